@@ -2,29 +2,62 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
-	"time"
 
+	"github.com/taisei-ito/cluster-status-monitor/internal/accounting"
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/anomaly"
 	"github.com/taisei-ito/cluster-status-monitor/internal/api"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/availability"
+	"github.com/taisei-ito/cluster-status-monitor/internal/certs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/chargeback"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
 	"github.com/taisei-ito/cluster-status-monitor/internal/config"
+	"github.com/taisei-ito/cluster-status-monitor/internal/digest"
+	"github.com/taisei-ito/cluster-status-monitor/internal/forecast"
+	"github.com/taisei-ito/cluster-status-monitor/internal/incident"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/jobs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/maintenance"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/notify"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/reload"
+	"github.com/taisei-ito/cluster-status-monitor/internal/report"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/statuspage"
 	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+	"github.com/taisei-ito/cluster-status-monitor/internal/usage"
+	"github.com/taisei-ito/cluster-status-monitor/internal/utilization"
 )
 
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
+	if err == nil {
+		err = cfg.Validate()
+	}
 	if err != nil {
+		var invalid config.ValidationError
+		if errors.As(err, &invalid) {
+			for _, fe := range invalid {
+				log.Printf("Invalid configuration: %v", fe)
+			}
+			log.Fatalf("Invalid configuration: %d errors", len(invalid))
+		}
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Invalid configuration: %v", err)
-	}
-
 	// Initialize storage
 	store, err := storage.Factory(cfg.Storage)
 	if err != nil {
@@ -34,22 +67,294 @@ func main() {
 
 	log.Printf("Storage initialized: %s", cfg.Storage.Type)
 
+	// Subcommands run against the storage and exit
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		if err := runReport(cfg, store, os.Args[2:]); err != nil {
+			log.Fatalf("Report failed: %v", err)
+		}
+		return
+	}
+
+	// Background jobs stop when the server shuts down. Their intervals
+	// change on configuration reloads.
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	intervals := newSchedules(cfg)
+
+	// Initialize cluster registry
+	reg := registry.New(store, registry.NewPBSDiscoverer(cfg.Registry.QstatPath), cfg.StaticClusters())
+	if clusters, err := reg.Sync(ctx); err != nil {
+		log.Printf("Cluster registry sync failed: %v", err)
+	} else {
+		log.Printf("Cluster registry synchronized: %d clusters", len(clusters))
+	}
+	go reg.Run(ctx, intervals.registrySync)
+
+	// Nodes under scheduled maintenance are reported in maintenance and
+	// their alerts are silenced
+	windows := maintenance.NewStore(store)
+
+	// Initialize node inventory
+	inv := inventory.New(store, inventory.NewPBSSource(cfg.Registry.PbsnodesPath))
+	inv.OnRefresh = windows.ApplyToNodes
+	if _, err := inv.Refresh(ctx); err != nil {
+		log.Printf("Node inventory refresh failed: %v", err)
+	}
+	go inv.Run(ctx, intervals.inventory)
+
+	// Start collectors
+	runner := collector.NewRemoteShell(cfg.Collector.RemoteShell)
+
+	diskUsers := collector.NewDiskUserStore(store, cfg.Collector.DiskUserRetention)
+	diskUserCollector := collector.NewDiskUserCollector(reg, runner, diskUsers, cfg.Collector.DucPath)
+	diskUserCollector.Index = cfg.Collector.DucIndex
+	go diskUserCollector.Run(ctx, intervals.diskUsers)
+
+	filesystems := collector.NewFilesystemStore(store, cfg.Collector.FilesystemRetention)
+	filesystemCollector := collector.NewFilesystemCollector(reg, inv, runner, filesystems)
+	filesystemCollector.Concurrency = cfg.Collector.FilesystemConcurrency
+	go filesystemCollector.Run(ctx, intervals.filesystems)
+
+	cpuAccounting := accounting.NewStore(store)
+	accountingCollector := accounting.NewCollector(inv, runner, cpuAccounting, cfg.Collector.HostsFile, cfg.Collector.SaPath)
+	accountingCollector.Exclude = cfg.Collector.AccountingExclude
+	go accountingCollector.Run(ctx, intervals.accounting)
+
+	nodeLoads := collector.NewNodeLoadStore(store)
+	nodeLoadCollector := collector.NewNodeLoadCollector(reg, inv, runner, nodeLoads)
+	go nodeLoadCollector.Run(ctx, intervals.nodeLoad)
+
+	userUsage, err := usage.NewStore(store, inv)
+	if err != nil {
+		log.Fatalf("Failed to initialize user usage: %v", err)
+	}
+	userUsage.Interval = cfg.Collector.JobInterval
+	userUsage.Retention = cfg.Usage.Retention
+	userUsage.FairShare = cfg.Usage.FairShare
+
+	jobTracker := jobs.NewTracker(store, cfg.Registry.QstatPath)
+	jobTracker.OnCollect = userUsage.RecordJobs
+	go jobTracker.Run(ctx, schedule.New(cfg.Collector.JobInterval))
+
+	utilizationRecorder := utilization.NewRecorder(store, reg, inv)
+	go utilizationRecorder.Run(ctx, intervals.utilization)
+
+	// Start anomaly detection
+	detector := anomaly.NewDetector(store, []anomaly.Source{
+		anomaly.NewMetricsSource(store, anomaly.MetricKeys),
+		anomaly.NewNodeLoadSource(reg, nodeLoads),
+	})
+	detector.Threshold = cfg.Alerts.AnomalyThreshold
+	go detector.Run(ctx, intervals.anomalies)
+
+	// Start alert evaluation
+	forecaster := forecast.New(filesystems)
+	alerts := alert.NewEngine(store, alertRules(cfg.Alerts, forecaster, reg, detector, inv))
+	alerts.Silence = windows.SilenceAlerts
+	go alerts.Run(ctx, intervals.alerts)
+
+	// Start incident tracking
+	incidents := incident.NewStore(store, alerts)
+	incidents.AutoOpenSeverity = autoOpenSeverity(cfg.Alerts)
+	go incidents.Run(ctx, intervals.alerts)
+
+	// Public status page
+	announcements := statuspage.NewAnnouncements(store)
+	statusPage := statuspage.NewPage(reg, inv, incidents, announcements, windows)
+	statusPage.Title = cfg.StatusPage.Title
+	statusPage.ResolvedWindow = cfg.StatusPage.ResolvedWindow
+	statusPage.FeedWindow = cfg.StatusPage.FeedWindow
+
+	reports := report.NewGenerator(reg, utilizationRecorder, jobTracker, cpuAccounting, filesystems)
+	configureReports(reports, cfg.Reports)
+
+	nodeAvailability := availability.NewCalculator(inv)
+
+	// Initialize research group chargeback
+	groups := chargeback.NewGroups(store)
+	groups.File = cfg.Chargeback.GroupFile
+	groups.MinGID = cfg.Chargeback.MinGID
+	if groups.File != "" {
+		if n, err := groups.ImportFile(); err != nil {
+			log.Printf("Group import failed: %v", err)
+		} else {
+			log.Printf("Groups imported from %s: %d groups", groups.File, n)
+		}
+	}
+	biller := chargeback.NewBiller(groups, jobTracker, cpuAccounting)
+	biller.Rates = chargebackRates(cfg.Chargeback)
+	biller.Currency = cfg.Chargeback.Currency
+
+	// Start weekly digest
+	mailer := notify.NewSMTP(cfg.Notify.SMTPHost, cfg.Notify.SMTPPort,
+		cfg.Notify.SMTPUsername, cfg.Notify.SMTPPassword, cfg.Notify.SMTPFrom)
+	subscriptions := digest.NewSubscriptions(store, reg)
+	digests := digest.NewScheduler(store, reg, inv, utilizationRecorder, diskUsers, alerts, subscriptions, mailer)
+	digests.Weekday, _ = cfg.Digest.ParseWeekday()
+	digests.Hour = cfg.Digest.Hour
+	digests.TopUsers = cfg.Digest.TopUsers
+	if cfg.Notify.SMTPHost != "" {
+		go digests.Run(ctx)
+	}
+
+	// Initialize authentication
+	users := auth.NewUsers(store)
+	sessions := newSessions(store, cfg.Auth)
+	bootstrapAdmin(users, cfg.Auth)
+	authenticators := []auth.Authenticator{users}
+	if cfg.LDAP.URL != "" {
+		authenticators = append(authenticators, newLDAP(cfg.LDAP))
+	}
+	login := auth.NewChain(users, authenticators...)
+	login.Roles, _ = auth.ParseRoleMap(cfg.Auth.GroupRoles)
+	login.DefaultRole = cfg.Auth.DefaultRole
+	var oidcLogin *auth.OIDC
+	if cfg.OIDC.Issuer != "" {
+		oidcLogin = newOIDC(cfg.OIDC)
+		oidcLogin.Secure = cfg.Auth.CookieSecure
+	}
+	tokens := auth.NewTokens(store)
+	tokens.DefaultTTL = cfg.Auth.TokenTTL
+	var authMiddleware *auth.Middleware
+	if cfg.Auth.Enabled {
+		authMiddleware = auth.NewMiddleware(sessions, users, tokens)
+	} else {
+		log.Println("Authentication is disabled; every /api route is open")
+	}
+
+	// Cross-origin policies, security headers and rate limits
+	privatePolicy, publicPolicy := corsPolicies(cfg.Security)
+	corsPolicy := api.NewCORS(privatePolicy)
+	publicCORSPolicy := api.NewCORS(publicPolicy)
+	headers := api.NewHeaders(securityHeaders(cfg.Security))
+	proxies := auth.NewTrustedProxies(trustedProxies(cfg.Security))
+	limits := api.RateLimiters{
+		IP:     newLimiter("ip", cfg.RateLimits.IP),
+		API:    newLimiter("api", cfg.RateLimits.API),
+		Legacy: newLimiter("legacy", cfg.RateLimits.Legacy),
+		Login:  newLimiter("login", cfg.RateLimits.Login),
+		Ingest: newLimiter("ingest", cfg.RateLimits.Ingest),
+		Public: newLimiter("public", cfg.RateLimits.Public),
+	}
+
+	// Configuration reloads swap the new values into the running subsystems
+	reloader := reload.New(cfg, func(next *config.Config, changed []string) {
+		intervals.set(next)
+		alerts.SetRules(alertRules(next.Alerts, forecaster, reg, detector, inv))
+		detector.SetThreshold(next.Alerts.AnomalyThreshold)
+		incidents.SetAutoOpenSeverity(autoOpenSeverity(next.Alerts))
+		reg.SetStatic(next.StaticClusters())
+		if slices.Contains(changed, "clusters") {
+			go func() {
+				if clusters, err := reg.Sync(ctx); err != nil {
+					log.Printf("Cluster registry sync failed: %v", err)
+				} else {
+					log.Printf("Cluster registry synchronized: %d clusters", len(clusters))
+				}
+			}()
+		}
+		privatePolicy, publicPolicy := corsPolicies(next.Security)
+		corsPolicy.Set(privatePolicy)
+		publicCORSPolicy.Set(publicPolicy)
+		headers.Set(securityHeaders(next.Security))
+		proxies.Set(trustedProxies(next.Security))
+		setLimits(limits, next.RateLimits)
+	}, reloadable...)
+
 	// Create router
-	router := api.NewRouter(store)
+	router := api.NewRouter(api.Dependencies{
+		Storage:       store,
+		Registry:      reg,
+		Inventory:     inv,
+		DiskUsers:     diskUsers,
+		Filesystems:   filesystems,
+		Accounting:    cpuAccounting,
+		Forecaster:    forecaster,
+		Alerts:        alerts,
+		NodeLoads:     nodeLoads,
+		Anomalies:     detector,
+		Jobs:          jobTracker,
+		Reports:       reports,
+		Digest:        digests,
+		Subscribers:   subscriptions,
+		Usage:         userUsage,
+		Groups:        groups,
+		Billing:       biller,
+		Availability:  nodeAvailability,
+		Incidents:     incidents,
+		StatusPage:    statusPage,
+		Announcements: announcements,
+		StatusURL:     cfg.StatusPage.URL,
+		Maintenance:   windows,
+		Users:         users,
+		Sessions:      sessions,
+		Login:         login,
+		OIDC:          oidcLogin,
+		Tokens:        tokens,
+		Auth:          authMiddleware,
+		CertsRequired: cfg.TLS.ClientCertRequired,
+		GlobalCerts:   cfg.TLS.GlobalIngestNames,
+		Audit:         audit.NewLog(store),
+		CORS:          corsPolicy,
+		PublicCORS:    publicCORSPolicy,
+		Headers:       headers,
+		Proxies:       proxies,
+		Limits:        limits,
+		Reloader:      reloader,
+	})
 
 	// Create server
 	srv := &http.Server{
-		Addr:         ":" + cfg.ServerPort,
+		Addr:         ":" + cfg.Server.Port,
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	// Serve HTTPS when a certificate is configured
+	var certificates *certs.Reloader
+	if cfg.TLS.CertFile != "" {
+		certificates, err = certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		srv.TLSConfig = certificates.TLSConfig()
+	}
+
+	// SIGHUP reloads the configuration and the TLS certificate
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloader.Reload(models.ConfigReloadSignal, "")
+			if certificates == nil {
+				continue
+			}
+			if err := certificates.Reload(); err != nil {
+				log.Printf("TLS certificate reload failed, keeping the current one: %v", err)
+				continue
+			}
+			log.Println("TLS certificate reloaded")
+		}
+	}()
+
+	// The configuration file is also reloaded when it changes
+	if cfg.File != "" && cfg.Server.WatchInterval > 0 {
+		go reloader.Watch(ctx, cfg.File, cfg.Server.WatchInterval)
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %s", cfg.ServerPort)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if srv.TLSConfig != nil {
+			log.Printf("Server starting on port %s with TLS", cfg.Server.Port)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Server starting on port %s", cfg.Server.Port)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
@@ -60,14 +365,115 @@ func main() {
 	<-quit
 
 	log.Println("Server shutting down...")
+	stop()
 
 	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
 	log.Println("Server exited")
 }
+
+// chargebackRates builds the rate table from the chargeback configuration,
+// which Validate has already checked
+func chargebackRates(cfg config.ChargebackConfig) chargeback.Rates {
+	rates, _ := cfg.ParseRates()
+	defaultRate := rates["default"]
+	delete(rates, "default")
+	return chargeback.Rates{
+		Default:    defaultRate,
+		Clusters:   rates,
+		Accounting: cfg.AccountingRate,
+	}
+}
+
+// newSessions creates the session manager, generating a signing secret when
+// none is configured
+func newSessions(store storage.Storage, cfg config.AuthConfig) *auth.Sessions {
+	secret := []byte(cfg.SessionSecret)
+	if len(secret) == 0 {
+		var err error
+		if secret, err = auth.RandomSecret(); err != nil {
+			log.Fatalf("Failed to generate session secret: %v", err)
+		}
+		log.Println("AUTH_SESSION_SECRET is not set; sessions end when the server restarts")
+	}
+	sessions := auth.NewSessions(store, secret)
+	sessions.TTL = cfg.SessionTTL
+	sessions.Secure = cfg.CookieSecure
+	return sessions
+}
+
+// bootstrapAdmin creates the configured admin user when it does not exist
+func bootstrapAdmin(users *auth.Users, cfg config.AuthConfig) {
+	if cfg.AdminPassword == "" {
+		return
+	}
+	if _, err := users.Get(cfg.AdminUser); err == nil {
+		return
+	} else if !errors.Is(err, auth.ErrUserNotFound) {
+		log.Printf("Failed to look up admin user: %v", err)
+		return
+	}
+	admin := models.User{
+		Username: cfg.AdminUser,
+		Name:     "Administrator",
+		Roles:    []models.RoleGrant{{Role: models.RoleAdmin}},
+	}
+	if _, err := users.Put(admin, cfg.AdminPassword); err != nil {
+		log.Printf("Failed to create admin user: %v", err)
+		return
+	}
+	log.Printf("Created admin user %s", cfg.AdminUser)
+}
+
+// newLDAP creates the LDAP authenticator from its configuration
+func newLDAP(cfg config.LDAPConfig) *auth.LDAP {
+	l := auth.NewLDAP(cfg.URL, cfg.BaseDN)
+	l.StartTLS = cfg.StartTLS
+	l.BindDN = cfg.BindDN
+	l.BindPassword = cfg.BindPassword
+	l.UserFilter = cfg.UserFilter
+	l.GroupBaseDN = cfg.GroupBaseDN
+	l.GroupFilter = cfg.GroupFilter
+	l.RequiredGroups = cfg.RequiredGroups
+	l.Attributes = auth.LDAPAttributes{
+		Username: cfg.UsernameAttr,
+		Name:     cfg.NameAttr,
+		Email:    cfg.EmailAttr,
+		Group:    cfg.GroupAttr,
+	}
+	l.Timeout = cfg.Timeout
+
+	l.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.SkipVerify}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			log.Fatalf("Failed to read LDAP CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			log.Fatalf("No certificates found in LDAP CA file %s", cfg.CAFile)
+		}
+		l.TLSConfig.RootCAs = pool
+	}
+	return l
+}
+
+// newOIDC creates the OIDC login from its configuration
+func newOIDC(cfg config.OIDCConfig) *auth.OIDC {
+	o := auth.NewOIDC(cfg.Issuer, cfg.ClientID, cfg.RedirectURL)
+	o.ClientSecret = cfg.ClientSecret
+	o.Scopes = cfg.Scopes
+	o.UsernameClaim = cfg.UsernameClaim
+	o.NameClaim = cfg.NameClaim
+	o.EmailClaim = cfg.EmailClaim
+	o.GroupsClaim = cfg.GroupsClaim
+	o.RequiredGroups = cfg.RequiredGroups
+	o.CookiePath = "/api/v1/auth/oidc"
+	return o
+}
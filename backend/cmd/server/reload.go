@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/netip"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/anomaly"
+	"github.com/taisei-ito/cluster-status-monitor/internal/api"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/config"
+	"github.com/taisei-ito/cluster-status-monitor/internal/forecast"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/ratelimit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+)
+
+// reloadable are the configuration keys a reload applies to the running
+// server. The job interval is not among them because usage samples are
+// weighted by it.
+var reloadable = []string{
+	"registry.sync_interval",
+	"registry.inventory_interval",
+	"clusters",
+	"collectors.disk_user_interval",
+	"collectors.filesystem_interval",
+	"collectors.accounting_interval",
+	"collectors.node_load_interval",
+	"collectors.utilization_interval",
+	"alerts.*",
+	"security.*",
+	"rate_limits.*",
+}
+
+// schedules are the intervals of the background jobs that a reload can
+// change
+type schedules struct {
+	registrySync *schedule.Interval
+	inventory    *schedule.Interval
+	diskUsers    *schedule.Interval
+	filesystems  *schedule.Interval
+	accounting   *schedule.Interval
+	nodeLoad     *schedule.Interval
+	utilization  *schedule.Interval
+	anomalies    *schedule.Interval
+	alerts       *schedule.Interval // Also syncs incidents
+}
+
+// newSchedules creates the intervals of a configuration
+func newSchedules(cfg *config.Config) *schedules {
+	return &schedules{
+		registrySync: schedule.New(cfg.Registry.SyncInterval),
+		inventory:    schedule.New(cfg.Registry.InventoryInterval),
+		diskUsers:    schedule.New(cfg.Collector.DiskUserInterval),
+		filesystems:  schedule.New(cfg.Collector.FilesystemInterval),
+		accounting:   schedule.New(cfg.Collector.AccountingInterval),
+		nodeLoad:     schedule.New(cfg.Collector.NodeLoadInterval),
+		utilization:  schedule.New(cfg.Collector.UtilizationInterval),
+		anomalies:    schedule.New(cfg.Alerts.AnomalyInterval),
+		alerts:       schedule.New(cfg.Alerts.Interval),
+	}
+}
+
+// set changes the intervals to those of a reloaded configuration
+func (s *schedules) set(cfg *config.Config) {
+	s.registrySync.Set(cfg.Registry.SyncInterval)
+	s.inventory.Set(cfg.Registry.InventoryInterval)
+	s.diskUsers.Set(cfg.Collector.DiskUserInterval)
+	s.filesystems.Set(cfg.Collector.FilesystemInterval)
+	s.accounting.Set(cfg.Collector.AccountingInterval)
+	s.nodeLoad.Set(cfg.Collector.NodeLoadInterval)
+	s.utilization.Set(cfg.Collector.UtilizationInterval)
+	s.anomalies.Set(cfg.Alerts.AnomalyInterval)
+	s.alerts.Set(cfg.Alerts.Interval)
+}
+
+// alertRules creates the alert rules with the thresholds of cfg
+func alertRules(cfg config.AlertConfig, forecaster *forecast.Forecaster, reg *registry.Registry,
+	detector *anomaly.Detector, inv *inventory.Inventory) []alert.Rule {
+	return []alert.Rule{
+		forecast.NewDiskFillRule(forecaster, reg, cfg.ForecastWindow,
+			cfg.DiskFillWarningDays, cfg.DiskFillCriticalDays),
+		anomaly.NewRule(detector, cfg.AnomalyWindow,
+			cfg.AnomalyThreshold, cfg.AnomalyCritical),
+		inventory.NewNodeDownRule(inv, cfg.NodeDownCritical),
+	}
+}
+
+// autoOpenSeverity returns the lowest severity opening incidents, empty
+// when automatic incidents are disabled
+func autoOpenSeverity(cfg config.AlertConfig) string {
+	if cfg.IncidentAutoOpen == "none" {
+		return ""
+	}
+	return cfg.IncidentAutoOpen
+}
+
+// corsPolicies returns the cross-origin policies of /api and of the public
+// routes
+func corsPolicies(cfg config.SecurityConfig) (api.CORSPolicy, api.CORSPolicy) {
+	private := api.CORSPolicy{
+		Origins:     cfg.CORSOrigins,
+		Methods:     cfg.CORSMethods,
+		Credentials: cfg.CORSCredentials,
+		MaxAge:      cfg.CORSMaxAge,
+	}
+	public := api.CORSPolicy{
+		Origins: cfg.PublicOrigins,
+		Methods: []string{"GET", "HEAD"},
+		MaxAge:  cfg.CORSMaxAge,
+	}
+	return private, public
+}
+
+// securityHeaders returns the security headers of cfg
+func securityHeaders(cfg config.SecurityConfig) api.SecurityHeaders {
+	return api.SecurityHeaders{
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		FrameOptions:          cfg.FrameOptions,
+		HSTSMaxAge:            cfg.HSTSMaxAge,
+	}
+}
+
+// trustedProxies returns the reverse proxies of cfg, which Validate has
+// already checked
+func trustedProxies(cfg config.SecurityConfig) []netip.Prefix {
+	proxies, _ := auth.ParseTrustedProxies(cfg.TrustedProxies)
+	return proxies
+}
+
+// newLimiter creates the rate limiter of a route group from a limit that
+// Validate has already checked
+func newLimiter(name, limit string) *ratelimit.Limiter {
+	l, _ := ratelimit.ParseLimit(limit)
+	return ratelimit.New(name, l)
+}
+
+// setLimits changes the rate limits to those of a reloaded configuration,
+// which Validate has already checked
+func setLimits(limits api.RateLimiters, cfg config.RateLimitConfig) {
+	groups := cfg.Groups()
+	for name, l := range map[string]*ratelimit.Limiter{
+		"ip":     limits.IP,
+		"api":    limits.API,
+		"legacy": limits.Legacy,
+		"login":  limits.Login,
+		"ingest": limits.Ingest,
+		"public": limits.Public,
+	} {
+		limit, _ := ratelimit.ParseLimit(groups[name])
+		l.SetLimit(limit)
+	}
+}
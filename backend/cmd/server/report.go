@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/accounting"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/config"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/jobs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/report"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+	"github.com/taisei-ito/cluster-status-monitor/internal/utilization"
+)
+
+// runReport implements `server report [-format markdown|html|json] [-o file] [YYYY-MM]`.
+// The report is generated from the stored data; the previous month is used
+// when no period is given.
+func runReport(cfg *config.Config, store storage.Storage, args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	format := flags.String("format", report.FormatMarkdown, "output format: markdown, html or json")
+	output := flags.String("o", "", "write the report to a file instead of stdout")
+	flags.Parse(args)
+
+	period := flags.Arg(0)
+	if period == "" {
+		period = time.Now().AddDate(0, -1, 0).Format("2006-01")
+	}
+
+	reg := registry.New(store, registry.NewPBSDiscoverer(cfg.Registry.QstatPath), registry.DefaultFileServers())
+	inv := inventory.New(store, inventory.NewPBSSource(cfg.Registry.PbsnodesPath))
+	generator := report.NewGenerator(reg,
+		utilization.NewRecorder(store, reg, inv),
+		jobs.NewTracker(store, cfg.Registry.QstatPath),
+		accounting.NewStore(store),
+		collector.NewFilesystemStore(store, cfg.Collector.FilesystemRetention))
+	configureReports(generator, cfg.Reports)
+
+	rep, err := generator.Generate(period)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch *format {
+	case report.FormatMarkdown:
+		err = report.RenderMarkdown(&buf, rep)
+	case report.FormatHTML:
+		err = report.RenderHTML(&buf, rep)
+	case report.FormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output != "" {
+		return os.WriteFile(*output, buf.Bytes(), 0o644)
+	}
+	_, err = os.Stdout.Write(buf.Bytes())
+	return err
+}
+
+// configureReports applies the report configuration to a generator
+func configureReports(g *report.Generator, cfg config.ReportConfig) {
+	g.TopUsers = cfg.TopUsers
+	g.LowEfficiency = cfg.LowEfficiency
+	g.LowEfficiencyMinTime = cfg.LowEfficiencyMinTime
+}
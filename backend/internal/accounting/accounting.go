@@ -0,0 +1,313 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// countersKey holds the last cumulative `sa -m` value per host and user
+const countersKey = "accounting_counters"
+
+// ErrInvalidPeriod is returned for periods that are not formatted as YYYY-MM
+var ErrInvalidPeriod = errors.New("invalid period, expected YYYY-MM")
+
+var periodPattern = regexp.MustCompile(`^\d{4}-\d{2}$`)
+
+// Collector gathers per-user CPU time from process accounting on every host
+// listed in the hosts file, replacing stat.sh
+type Collector struct {
+	inventory   *inventory.Inventory
+	runner      collector.Runner
+	store       *Store
+	HostsFile   string   // hosts.equiv style list of hosts to query
+	SaPath      string   // Path to sa on the hosts
+	Exclude     []string // Users that are not recorded, e.g. root
+	Concurrency int      // Hosts queried in parallel
+}
+
+// NewCollector creates a process accounting collector
+func NewCollector(inv *inventory.Inventory, runner collector.Runner, store *Store, hostsFile, saPath string) *Collector {
+	return &Collector{
+		inventory:   inv,
+		runner:      runner,
+		store:       store,
+		HostsFile:   hostsFile,
+		SaPath:      saPath,
+		Concurrency: 8,
+	}
+}
+
+// Collect reads `sa -m` from every reachable host and adds the CPU time
+// consumed since the previous collection to today's per-user totals.
+// `sa -m` reports cumulative totals, so only the increase is recorded and
+// the accounting files can be rotated on the hosts without losing history.
+func (c *Collector) Collect(ctx context.Context) error {
+	hosts, err := ReadHostsFile(c.HostsFile)
+	if err != nil {
+		return err
+	}
+	pbs, down, err := c.inventoryHosts()
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		current = make(map[string]map[string]float64)
+		errs    []error
+	)
+	sem := make(chan struct{}, max(c.Concurrency, 1))
+
+	for _, host := range hosts {
+		if down[host] {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			out, err := c.runner.Run(ctx, host, c.SaPath+" -m")
+			var usage map[string]float64
+			if err == nil {
+				usage, err = ParseSaM(out)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", host, err))
+				return
+			}
+			current[host] = c.filter(usage)
+		}(host)
+	}
+	wg.Wait()
+
+	if err := c.store.Record(time.Now(), current, pbs); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Run collects at the given interval until ctx is done
+func (c *Collector) Run(ctx context.Context, interval *schedule.Interval) {
+	collector.RunEvery(ctx, interval, "CPU accounting", c.Collect)
+}
+
+// inventoryHosts returns the names and addresses of the nodes in the
+// inventory, which PBS schedules, and of those not online, including their
+// short host names
+func (c *Collector) inventoryHosts() (pbs, down map[string]bool, err error) {
+	nodes, err := c.inventory.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pbs = make(map[string]bool)
+	down = make(map[string]bool)
+	for _, n := range nodes {
+		short, _, _ := strings.Cut(n.Host, ".")
+		for _, name := range []string{n.Name, n.Host, short} {
+			pbs[name] = true
+			if n.State != models.NodeStateOnline {
+				down[name] = true
+			}
+		}
+	}
+	return pbs, down, nil
+}
+
+// filter drops excluded users
+func (c *Collector) filter(usage map[string]float64) map[string]float64 {
+	for _, user := range c.Exclude {
+		delete(usage, user)
+	}
+	return usage
+}
+
+// Store keeps per-user daily CPU totals, partitioned by month
+type Store struct {
+	storage storage.Storage
+	mu      sync.Mutex
+}
+
+// NewStore creates an accounting store
+func NewStore(store storage.Storage) *Store {
+	return &Store{storage: store}
+}
+
+// cpuDelta is the CPU time a user consumed since the previous collection
+type cpuDelta struct {
+	minutes float64
+	pbs     float64 // Part of minutes on PBS nodes
+}
+
+// Record adds the increase of each host's cumulative counters since the last
+// call to the day of at. Hosts seen for the first time only set a baseline.
+// A counter lower than its previous value means the host's accounting was
+// reset, in which case the whole new value is counted. The increase on the
+// pbs hosts is also counted as PBS minutes, which chargeback leaves to the
+// jobs that ran there.
+func (s *Store) Record(at time.Time, cumulative map[string]map[string]float64, pbs map[string]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters := make(map[string]map[string]float64)
+	if err := storage.GetData(s.storage, countersKey, &counters); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to load accounting counters: %w", err)
+	}
+
+	deltas := make(map[string]cpuDelta)
+	for host, usage := range cumulative {
+		previous, known := counters[host]
+		for user, value := range usage {
+			if !known {
+				continue
+			}
+			delta := value - previous[user]
+			if delta < 0 {
+				delta = value
+			}
+			if delta > 0 {
+				d := deltas[user]
+				d.minutes += delta
+				if pbs[host] {
+					d.pbs += delta
+				}
+				deltas[user] = d
+			}
+		}
+		counters[host] = usage
+	}
+
+	if len(deltas) > 0 {
+		if err := s.addDay(at.Format("2006-01-02"), deltas); err != nil {
+			return err
+		}
+	}
+
+	return storage.SetData(s.storage, countersKey, counters)
+}
+
+// Period returns the per-user totals of a month, largest first
+func (s *Store) Period(period string) ([]models.UserCPUPeriod, error) {
+	days, err := s.days(period)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]*models.UserCPUPeriod)
+	for _, d := range days {
+		t, ok := totals[d.User]
+		if !ok {
+			t = &models.UserCPUPeriod{Period: period, User: d.User}
+			totals[d.User] = t
+		}
+		t.CPUMinutes += d.CPUMinutes
+		t.PBSMinutes += d.PBSMinutes
+		t.ActiveDays++
+	}
+
+	result := make([]models.UserCPUPeriod, 0, len(totals))
+	for _, t := range totals {
+		t.CPUHours = t.CPUMinutes / 60
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CPUMinutes != result[j].CPUMinutes {
+			return result[i].CPUMinutes > result[j].CPUMinutes
+		}
+		return result[i].User < result[j].User
+	})
+	return result, nil
+}
+
+// UserDays returns the daily totals of one user in a month
+func (s *Store) UserDays(period, user string) ([]models.UserCPUDay, error) {
+	days, err := s.days(period)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.UserCPUDay{}
+	for _, d := range days {
+		if d.User == user {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// days returns every daily record of a month
+func (s *Store) days(period string) ([]models.UserCPUDay, error) {
+	if !periodPattern.MatchString(period) {
+		return nil, ErrInvalidPeriod
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loadMonth(period)
+}
+
+// addDay adds CPU minutes per user to a day's totals
+func (s *Store) addDay(date string, deltas map[string]cpuDelta) error {
+	period := date[:7]
+	days, err := s.loadMonth(period)
+	if err != nil {
+		return err
+	}
+
+	for i := range days {
+		if days[i].Date != date {
+			continue
+		}
+		if delta, ok := deltas[days[i].User]; ok {
+			days[i].CPUMinutes += delta.minutes
+			days[i].PBSMinutes += delta.pbs
+			delete(deltas, days[i].User)
+		}
+	}
+	for user, delta := range deltas {
+		days = append(days, models.UserCPUDay{Date: date, User: user, CPUMinutes: delta.minutes, PBSMinutes: delta.pbs})
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].Date != days[j].Date {
+			return days[i].Date < days[j].Date
+		}
+		return days[i].User < days[j].User
+	})
+	return storage.SetData(s.storage, monthKey(period), days)
+}
+
+// loadMonth reads the daily records of a month
+func (s *Store) loadMonth(period string) ([]models.UserCPUDay, error) {
+	days := []models.UserCPUDay{}
+	err := storage.GetData(s.storage, monthKey(period), &days)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load accounting for %s: %w", period, err)
+	}
+	return days, nil
+}
+
+// monthKey returns the storage key for a month's daily records
+func monthKey(period string) string {
+	return "accounting_daily_" + period
+}
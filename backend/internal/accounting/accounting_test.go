@@ -0,0 +1,159 @@
+package accounting
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestParseSaM(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{
+			name: "users after the totals line",
+			out: `    1520  12345.67re     890.12cp     0avio     1024k
+root           1000   1000.00re     10.50cp     0avio      512k
+alice           500  11000.00re    850.00cp     0avio     2048k
+bob              20    345.67re     29.62cp     0avio      256k
+`,
+			want: map[string]float64{"root": 10.5, "alice": 850, "bob": 29.62},
+		},
+		{name: "empty", out: "", want: map[string]float64{}},
+		{name: "short lines skipped", out: "alice 5\n\n", want: map[string]float64{}},
+		{name: "invalid cpu", out: "alice 5 10.00re x.yzcp 0avio 0k\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSaM([]byte(tt.out))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseSaM = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSaM: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSaM = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHostsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.equiv")
+	content := `# compute nodes
+asuka01
+asuka02 guest
++@netgroup
+-badhost
+
+asuka01
+  naruko01.cluster.local
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	hosts, err := ReadHostsFile(path)
+	if err != nil {
+		t.Fatalf("ReadHostsFile: %v", err)
+	}
+	want := []string{"asuka01", "asuka02", "naruko01.cluster.local"}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("ReadHostsFile = %v, want %v", hosts, want)
+	}
+
+	if _, err := ReadHostsFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ReadHostsFile of a missing file succeeded")
+	}
+}
+
+func TestStoreRecord(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	s := NewStore(store)
+	day := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	pbs := map[string]bool{"asuka02": true}
+
+	steps := []struct {
+		name       string
+		at         time.Time
+		cumulative map[string]map[string]float64
+		want       []models.UserCPUDay // May records after the step
+	}{
+		{
+			name:       "first sighting only sets the baseline",
+			at:         day,
+			cumulative: map[string]map[string]float64{"asuka01": {"alice": 100, "bob": 10}},
+			want:       []models.UserCPUDay{},
+		},
+		{
+			name:       "increases are added",
+			at:         day.Add(time.Hour),
+			cumulative: map[string]map[string]float64{"asuka01": {"alice": 130, "bob": 10}, "asuka02": {"alice": 500}},
+			want:       []models.UserCPUDay{{Date: "2024-05-10", User: "alice", CPUMinutes: 30}},
+		},
+		{
+			name:       "hosts are summed, PBS nodes apart, and new users count in full",
+			at:         day.Add(2 * time.Hour),
+			cumulative: map[string]map[string]float64{"asuka01": {"alice": 140, "bob": 10, "carol": 5}, "asuka02": {"alice": 520}},
+			want: []models.UserCPUDay{
+				{Date: "2024-05-10", User: "alice", CPUMinutes: 60, PBSMinutes: 20},
+				{Date: "2024-05-10", User: "carol", CPUMinutes: 5},
+			},
+		},
+		{
+			name:       "a counter reset counts the new value",
+			at:         day.Add(24 * time.Hour),
+			cumulative: map[string]map[string]float64{"asuka01": {"alice": 15, "bob": 12, "carol": 5}},
+			want: []models.UserCPUDay{
+				{Date: "2024-05-10", User: "alice", CPUMinutes: 60, PBSMinutes: 20},
+				{Date: "2024-05-10", User: "carol", CPUMinutes: 5},
+				{Date: "2024-05-11", User: "alice", CPUMinutes: 15},
+				{Date: "2024-05-11", User: "bob", CPUMinutes: 2},
+			},
+		},
+	}
+	for _, step := range steps {
+		if err := s.Record(step.at, step.cumulative, pbs); err != nil {
+			t.Fatalf("%s: Record: %v", step.name, err)
+		}
+		days, err := s.days("2024-05")
+		if err != nil {
+			t.Fatalf("%s: days: %v", step.name, err)
+		}
+		if !reflect.DeepEqual(days, step.want) {
+			t.Errorf("%s: days = %+v, want %+v", step.name, days, step.want)
+		}
+	}
+
+	period, err := s.Period("2024-05")
+	if err != nil {
+		t.Fatalf("Period: %v", err)
+	}
+	want := []models.UserCPUPeriod{
+		{Period: "2024-05", User: "alice", CPUMinutes: 75, CPUHours: 1.25, PBSMinutes: 20, ActiveDays: 2},
+		{Period: "2024-05", User: "carol", CPUMinutes: 5, CPUHours: 5.0 / 60, ActiveDays: 1},
+		{Period: "2024-05", User: "bob", CPUMinutes: 2, CPUHours: 2.0 / 60, ActiveDays: 1},
+	}
+	if !reflect.DeepEqual(period, want) {
+		t.Errorf("Period = %+v, want %+v", period, want)
+	}
+
+	if _, err := s.Period("May 2024"); err != ErrInvalidPeriod {
+		t.Errorf("Period of an invalid month: error = %v, want ErrInvalidPeriod", err)
+	}
+}
@@ -0,0 +1,64 @@
+package accounting
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseSaM parses `sa -m` output into CPU minutes per user. Each line is
+// "<user> <calls> <real>re <cpu>cp <avio>avio <mem>k"; the unnamed first
+// line holds the totals and is skipped. This is the fourth column that
+// stat.sh summed with awk.
+func ParseSaM(out []byte) (map[string]float64, error) {
+	usage := make(map[string]float64)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		if _, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			continue // totals line
+		}
+
+		cpu, err := strconv.ParseFloat(strings.TrimSuffix(fields[3], "cp"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sa -m line %q: %w", scanner.Text(), err)
+		}
+		usage[fields[0]] += cpu
+	}
+
+	return usage, scanner.Err()
+}
+
+// ReadHostsFile reads host names from a hosts.equiv style file. Blank lines,
+// comments and "+"/"-" netgroup entries are ignored; only the first word of
+// each line is used.
+func ReadHostsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var hosts []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") ||
+			strings.HasPrefix(fields[0], "+") || strings.HasPrefix(fields[0], "-") {
+			continue
+		}
+		if !seen[fields[0]] {
+			seen[fields[0]] = true
+			hosts = append(hosts, fields[0])
+		}
+	}
+
+	return hosts, scanner.Err()
+}
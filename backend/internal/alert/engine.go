@@ -0,0 +1,237 @@
+package alert
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// storageKey is the storage key holding firing and recently resolved alerts
+const storageKey = "alerts"
+
+// resolvedLimit is the number of resolved alerts kept for the API
+const resolvedLimit = 200
+
+// ErrAlertNotFound is returned for unknown alert IDs
+var ErrAlertNotFound = errors.New("alert not found")
+
+// Rule evaluates one alert condition. Evaluate returns the alerts that are
+// currently firing; only Rule, Type, Severity, Cluster, Node, Target, Summary
+// and Value need to be set.
+type Rule interface {
+	Name() string
+	Evaluate(ctx context.Context) ([]models.Alert, error)
+}
+
+// state is the persisted alert state
+type state struct {
+	Firing   []models.Alert `json:"firing"`
+	Resolved []models.Alert `json:"resolved"`
+}
+
+// Engine evaluates alert rules periodically and tracks when alerts start and
+// resolve
+type Engine struct {
+	storage storage.Storage
+	rules   []Rule
+	// Silence, when set, marks the alerts to silence among those currently
+	// reported. Silenced alerts keep firing but open no incidents.
+	Silence func(alerts []models.Alert, at time.Time) error
+	mu      sync.Mutex
+}
+
+// NewEngine creates an alert engine evaluating the given rules
+func NewEngine(store storage.Storage, rules []Rule) *Engine {
+	return &Engine{storage: store, rules: rules}
+}
+
+// Evaluate runs every rule and updates the firing alerts. Alerts of a rule
+// that fails to evaluate are left unchanged.
+func (e *Engine) Evaluate(ctx context.Context) error {
+	e.mu.Lock()
+	rules := e.rules
+	e.mu.Unlock()
+
+	var reported []models.Alert
+	failed := make(map[string]bool)
+	var errs []error
+	for _, rule := range rules {
+		alerts, err := rule.Evaluate(ctx)
+		if err != nil {
+			failed[rule.Name()] = true
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name(), err))
+			continue
+		}
+		for _, a := range alerts {
+			a.Rule = rule.Name()
+			a.ID = alertID(a)
+			reported = append(reported, a)
+		}
+	}
+	if e.Silence != nil {
+		if err := e.Silence(reported, time.Now()); err != nil {
+			errs = append(errs, fmt.Errorf("silence: %w", err))
+		}
+	}
+	current := make(map[string]models.Alert, len(reported))
+	for _, a := range reported {
+		current[a.ID] = a
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st, err := e.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var firing []models.Alert
+	for _, a := range st.Firing {
+		if next, ok := current[a.ID]; ok {
+			next.StartsAt = a.StartsAt
+			next.State = models.AlertStateFiring
+			next.UpdatedAt = now
+			firing = append(firing, next)
+			delete(current, a.ID)
+			continue
+		}
+		if failed[a.Rule] {
+			firing = append(firing, a)
+			continue
+		}
+
+		a.State = models.AlertStateResolved
+		a.ResolvedAt = &now
+		a.UpdatedAt = now
+		st.Resolved = append([]models.Alert{a}, st.Resolved...)
+		log.Printf("Alert resolved: %s %s", a.Rule, a.Target)
+	}
+	for _, a := range current {
+		a.State = models.AlertStateFiring
+		a.StartsAt = now
+		a.UpdatedAt = now
+		firing = append(firing, a)
+		if a.Silenced {
+			log.Printf("Alert firing (silenced): %s %s: %s", a.Rule, a.Target, a.Summary)
+		} else {
+			log.Printf("Alert firing: %s %s: %s", a.Rule, a.Target, a.Summary)
+		}
+	}
+
+	sort.Slice(firing, func(i, j int) bool {
+		if !firing[i].StartsAt.Equal(firing[j].StartsAt) {
+			return firing[i].StartsAt.Before(firing[j].StartsAt)
+		}
+		return firing[i].ID < firing[j].ID
+	})
+	st.Firing = firing
+	if len(st.Resolved) > resolvedLimit {
+		st.Resolved = st.Resolved[:resolvedLimit]
+	}
+
+	if err := storage.SetData(e.storage, storageKey, st); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// SetRules replaces the rules. Evaluations already running finish with the
+// previous rules.
+func (e *Engine) SetRules(rules []Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules = rules
+}
+
+// Run evaluates the rules at the given interval until ctx is done
+func (e *Engine) Run(ctx context.Context, interval *schedule.Interval) {
+	for {
+		start := time.Now()
+		if err := e.Evaluate(ctx); err != nil {
+			log.Printf("Alert evaluation failed: %v", err)
+		}
+
+		if !interval.Wait(ctx, start) {
+			return
+		}
+	}
+}
+
+// Firing returns the currently firing alerts
+func (e *Engine) Firing() ([]models.Alert, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	return st.Firing, nil
+}
+
+// Resolved returns recently resolved alerts, newest first
+func (e *Engine) Resolved() ([]models.Alert, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	return st.Resolved, nil
+}
+
+// Get returns a firing alert, or the most recent resolved alert with the ID
+func (e *Engine) Get(id string) (*models.Alert, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, alerts := range [][]models.Alert{st.Firing, st.Resolved} {
+		for _, a := range alerts {
+			if a.ID == id {
+				return &a, nil
+			}
+		}
+	}
+	return nil, ErrAlertNotFound
+}
+
+// load reads the alert state from storage
+func (e *Engine) load() (*state, error) {
+	st := &state{}
+	err := storage.GetData(e.storage, storageKey, st)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load alerts: %w", err)
+	}
+	if st.Firing == nil {
+		st.Firing = []models.Alert{}
+	}
+	if st.Resolved == nil {
+		st.Resolved = []models.Alert{}
+	}
+	return st, nil
+}
+
+// alertID identifies an alert by its rule and target, so re-evaluations of
+// the same condition update one alert
+func alertID(a models.Alert) string {
+	sum := sha1.Sum([]byte(a.Rule + "|" + a.Cluster + "|" + a.Target))
+	return hex.EncodeToString(sum[:8])
+}
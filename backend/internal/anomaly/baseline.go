@@ -0,0 +1,65 @@
+package anomaly
+
+import (
+	"math"
+	"time"
+)
+
+// hoursPerWeek is the number of seasonal buckets of a baseline
+const hoursPerWeek = 7 * 24
+
+// stat is an exponentially weighted mean and variance. Until 1/alpha samples
+// have been seen it is the plain running mean and variance.
+type stat struct {
+	N    int     `json:"n"`
+	Mean float64 `json:"mean"`
+	Var  float64 `json:"var"`
+}
+
+// update adds a sample, weighting it by at least alpha
+func (s *stat) update(x, alpha float64) {
+	s.N++
+	w := math.Max(1/float64(s.N), alpha)
+	d := x - s.Mean
+	s.Mean += w * d
+	s.Var = (1 - w) * (s.Var + w*d*d)
+}
+
+// baseline holds the seasonal statistics of one series
+type baseline struct {
+	Overall  stat               `json:"overall"`
+	Hours    [hoursPerWeek]stat `json:"hours"`
+	LastTime time.Time          `json:"last_time"`
+}
+
+// hourOfWeek returns the seasonal bucket of t, starting Sunday 00:00
+func hourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// expected returns the statistics used to score a sample at t: the
+// hour-of-week bucket once it has minSamples samples, otherwise the overall
+// statistics. ok is false while the series is still learning.
+func (b *baseline) expected(t time.Time, minSamples int) (s stat, kind string, ok bool) {
+	if h := b.Hours[hourOfWeek(t)]; h.N >= minSamples {
+		return h, "hour_of_week", true
+	}
+	if b.Overall.N >= minSamples {
+		return b.Overall, "overall", true
+	}
+	return stat{}, "", false
+}
+
+// update adds a sample to the overall and seasonal statistics
+func (b *baseline) update(x float64, t time.Time, alpha float64) {
+	b.Overall.update(x, alpha)
+	b.Hours[hourOfWeek(t)].update(x, alpha)
+	b.LastTime = t
+}
+
+// stddev returns the standard deviation of s with a floor, so that flat
+// series (a node constantly at load 0) do not turn every change into an
+// infinite score
+func stddev(s stat) float64 {
+	return math.Max(math.Sqrt(s.Var), 0.1+0.05*math.Abs(s.Mean))
+}
@@ -0,0 +1,208 @@
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+const (
+	baselinesKey = "anomaly_baselines"
+	eventsKey    = "anomaly_events"
+
+	// eventRetention and eventLimit bound the stored anomaly events
+	eventRetention = 7 * 24 * time.Hour
+	eventLimit     = 1000
+)
+
+// Sample is one observation of a series
+type Sample struct {
+	Cluster string
+	Node    string
+	Metric  string
+	Value   float64
+	Time    time.Time
+}
+
+// SeriesID identifies the series a sample belongs to
+func (s Sample) SeriesID() string {
+	if s.Node != "" {
+		return s.Cluster + "/" + s.Node + "/" + s.Metric
+	}
+	return s.Cluster + "/" + s.Metric
+}
+
+// Source provides the latest samples of a set of series
+type Source interface {
+	Samples(ctx context.Context) ([]Sample, error)
+}
+
+// Detector keeps a baseline per series and records samples that deviate
+// from it as anomaly events
+type Detector struct {
+	storage    storage.Storage
+	sources    []Source
+	Threshold  float64 // Minimum absolute score recorded as an anomaly
+	MinSamples int     // Samples a baseline needs before it is used
+	Alpha      float64 // Minimum weight of a new sample; lower adapts slower
+	mu         sync.Mutex
+}
+
+// NewDetector creates an anomaly detector reading from the given sources
+func NewDetector(store storage.Storage, sources []Source) *Detector {
+	return &Detector{
+		storage:    store,
+		sources:    sources,
+		Threshold:  3,
+		MinSamples: 6,
+		Alpha:      0.1,
+	}
+}
+
+// Sample reads every source and observes the returned samples
+func (d *Detector) Sample(ctx context.Context) error {
+	var samples []Sample
+	var errs []error
+	for _, src := range d.sources {
+		s, err := src.Samples(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		samples = append(samples, s...)
+	}
+
+	if _, err := d.Observe(samples); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// SetThreshold changes Threshold while the detector runs
+func (d *Detector) SetThreshold(threshold float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Threshold = threshold
+}
+
+// Run samples at the given interval until ctx is done
+func (d *Detector) Run(ctx context.Context, interval *schedule.Interval) {
+	collector.RunEvery(ctx, interval, "Anomaly detection", d.Sample)
+}
+
+// Observe scores samples against their baselines, records anomalies and
+// then updates the baselines. Samples not newer than the last one seen for
+// their series are ignored.
+func (d *Detector) Observe(samples []Sample) ([]models.AnomalyEvent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	baselines := make(map[string]*baseline)
+	if err := storage.GetData(d.storage, baselinesKey, &baselines); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load anomaly baselines: %w", err)
+	}
+
+	var events []models.AnomalyEvent
+	for _, s := range samples {
+		id := s.SeriesID()
+		b, ok := baselines[id]
+		if !ok {
+			b = &baseline{}
+			baselines[id] = b
+		}
+		if !s.Time.After(b.LastTime) {
+			continue
+		}
+
+		if st, kind, ok := b.expected(s.Time, d.MinSamples); ok {
+			sd := stddev(st)
+			score := (s.Value - st.Mean) / sd
+			if math.Abs(score) >= d.Threshold {
+				events = append(events, models.AnomalyEvent{
+					Series:   id,
+					Cluster:  s.Cluster,
+					Node:     s.Node,
+					Metric:   s.Metric,
+					Value:    s.Value,
+					Expected: st.Mean,
+					StdDev:   sd,
+					Score:    score,
+					Baseline: kind,
+					Time:     s.Time,
+				})
+			}
+		}
+		b.update(s.Value, s.Time, d.Alpha)
+	}
+
+	if err := storage.SetData(d.storage, baselinesKey, baselines); err != nil {
+		return nil, err
+	}
+	if len(events) > 0 {
+		if err := d.appendEvents(events); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// Events returns anomalies recorded since the given time, newest first.
+// An empty cluster returns every cluster.
+func (d *Detector) Events(since time.Time, cluster string) ([]models.AnomalyEvent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	events, err := d.loadEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.AnomalyEvent{}
+	for _, e := range events {
+		if e.Time.Before(since) || (cluster != "" && e.Cluster != cluster) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// appendEvents stores new events and drops expired ones
+func (d *Detector) appendEvents(events []models.AnomalyEvent) error {
+	stored, err := d.loadEvents()
+	if err != nil {
+		return err
+	}
+
+	stored = append(stored, events...)
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Time.After(stored[j].Time) })
+
+	cutoff := time.Now().Add(-eventRetention)
+	kept := stored[:0]
+	for _, e := range stored {
+		if e.Time.After(cutoff) && len(kept) < eventLimit {
+			kept = append(kept, e)
+		}
+	}
+	return storage.SetData(d.storage, eventsKey, kept)
+}
+
+// loadEvents reads the stored events
+func (d *Detector) loadEvents() ([]models.AnomalyEvent, error) {
+	events := []models.AnomalyEvent{}
+	err := storage.GetData(d.storage, eventsKey, &events)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load anomaly events: %w", err)
+	}
+	return events, nil
+}
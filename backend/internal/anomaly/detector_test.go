@@ -0,0 +1,109 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestStatUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		alpha    float64
+		values   []float64
+		wantMean float64
+		wantVar  float64
+	}{
+		{"single sample", 0.1, []float64{5}, 5, 0},
+		{"running mean while learning", 0.1, []float64{2, 4, 6, 8}, 5, 5},
+		{"constant series", 0.5, []float64{3, 3, 3, 3, 3}, 3, 0},
+		// With alpha 0.5 the third sample already weighs 0.5 instead of 1/3
+		{"exponential weighting", 0.5, []float64{0, 0, 8}, 4, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s stat
+			for _, v := range tt.values {
+				s.update(v, tt.alpha)
+			}
+			if s.N != len(tt.values) || math.Abs(s.Mean-tt.wantMean) > 1e-9 || math.Abs(s.Var-tt.wantVar) > 1e-9 {
+				t.Errorf("stat = %+v, want mean %v and variance %v", s, tt.wantMean, tt.wantVar)
+			}
+		})
+	}
+}
+
+func TestStddevFloor(t *testing.T) {
+	if got := stddev(stat{Mean: 0, Var: 0}); got != 0.1 {
+		t.Errorf("stddev of a flat zero series = %v, want 0.1", got)
+	}
+	if got := stddev(stat{Mean: 100, Var: 0}); got != 5.1 {
+		t.Errorf("stddev of a flat series at 100 = %v, want 5.1", got)
+	}
+	if got := stddev(stat{Mean: 1, Var: 4}); got != 2 {
+		t.Errorf("stddev = %v, want 2", got)
+	}
+}
+
+func TestDetectorObserve(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	d := NewDetector(store, nil)
+	d.MinSamples = 3
+
+	// Every sample falls in the same hour-of-week bucket
+	start := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	sample := func(minutes int, value float64) Sample {
+		return Sample{Cluster: "asuka", Node: "asuka01", Metric: "load", Value: value, Time: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	// Learning: no score until MinSamples samples are in
+	events, err := d.Observe([]Sample{sample(0, 10), sample(1, 11), sample(2, 50)})
+	if err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("events while learning = %+v", events)
+	}
+
+	// Within the usual range after learning
+	if events, _ = d.Observe([]Sample{sample(3, 25)}); len(events) != 0 {
+		t.Errorf("events for a usual value = %+v", events)
+	}
+
+	events, err = d.Observe([]Sample{sample(4, 500), sample(4, 1000), sample(1, 1000)})
+	if err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want one for the spike; repeated and older samples are ignored", events)
+	}
+	e := events[0]
+	if e.Series != "asuka/asuka01/load" || e.Value != 500 || e.Score < d.Threshold || e.Baseline != "hour_of_week" {
+		t.Errorf("event = %+v", e)
+	}
+
+	stored, err := d.Events(start, "")
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Time != e.Time {
+		t.Errorf("stored events = %+v", stored)
+	}
+	if other, _ := d.Events(start, "naruko"); len(other) != 0 {
+		t.Errorf("events of another cluster = %+v", other)
+	}
+}
+
+func TestSeriesID(t *testing.T) {
+	if got := (Sample{Cluster: "asuka", Node: "asuka01", Metric: "load"}).SeriesID(); got != "asuka/asuka01/load" {
+		t.Errorf("node series = %q", got)
+	}
+	if got := (Sample{Cluster: "asuka", Metric: "utilization"}).SeriesID(); got != "asuka/utilization" {
+		t.Errorf("cluster series = %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// RuleType identifies anomaly alerts
+const RuleType = "anomaly"
+
+// Rule fires for series with a recent anomaly. The strongest event of each
+// series within Window is reported, so an alert resolves once the series has
+// been quiet for Window.
+type Rule struct {
+	detector          *Detector
+	Window            time.Duration // How long an anomaly keeps firing
+	Threshold         float64       // Minimum absolute score that fires
+	CriticalThreshold float64       // Minimum absolute score that is critical
+}
+
+// NewRule creates an anomaly alert rule
+func NewRule(detector *Detector, window time.Duration, threshold, criticalThreshold float64) *Rule {
+	return &Rule{
+		detector:          detector,
+		Window:            window,
+		Threshold:         threshold,
+		CriticalThreshold: criticalThreshold,
+	}
+}
+
+// Name returns the rule name
+func (r *Rule) Name() string {
+	return RuleType
+}
+
+// Evaluate returns an alert for every series with an anomaly within Window
+func (r *Rule) Evaluate(ctx context.Context) ([]models.Alert, error) {
+	events, err := r.detector.Events(time.Now().Add(-r.Window), "")
+	if err != nil {
+		return nil, err
+	}
+
+	strongest := make(map[string]models.AnomalyEvent)
+	var order []string
+	for _, e := range events {
+		prev, ok := strongest[e.Series]
+		if !ok {
+			order = append(order, e.Series)
+		}
+		if !ok || math.Abs(e.Score) > math.Abs(prev.Score) {
+			strongest[e.Series] = e
+		}
+	}
+
+	var alerts []models.Alert
+	for _, series := range order {
+		e := strongest[series]
+		score := math.Abs(e.Score)
+		if score < r.Threshold {
+			continue
+		}
+		severity := models.SeverityWarning
+		if score >= r.CriticalThreshold {
+			severity = models.SeverityCritical
+		}
+
+		target := e.Cluster
+		if e.Node != "" {
+			target = e.Node
+		}
+		alerts = append(alerts, models.Alert{
+			Type:     RuleType,
+			Severity: severity,
+			Cluster:  e.Cluster,
+			Node:     e.Node,
+			Target:   e.Series,
+			Summary: fmt.Sprintf("%s of %s is %.2f, expected %.2f ± %.2f (score %.1f)",
+				e.Metric, target, e.Value, e.Expected, e.StdDev, e.Score),
+			Value: e.Score,
+		})
+	}
+	return alerts, nil
+}
@@ -0,0 +1,116 @@
+package anomaly
+
+import (
+	"context"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// MetricKeys are the per-cluster metrics written by the collection scripts
+// that are checked for anomalies
+var MetricKeys = []string{"load_average", "pbs_usage"}
+
+// MetricsSource reads the per-cluster metrics written by the collection
+// scripts. Items without a numeric value, such as the "used/total" strings
+// of cpu_usage, and dummy items are skipped.
+type MetricsSource struct {
+	storage storage.Storage
+	keys    []string
+}
+
+// NewMetricsSource creates a source for the given metric keys
+func NewMetricsSource(store storage.Storage, keys []string) *MetricsSource {
+	return &MetricsSource{storage: store, keys: keys}
+}
+
+// Samples returns the latest value of every cluster and metric
+func (s *MetricsSource) Samples(ctx context.Context) ([]Sample, error) {
+	// Items without a timestamp are stamped with the time of the last script run
+	var fallback time.Time
+	if metadata, err := s.storage.Get("metadata"); err == nil {
+		if ts, ok := metadata["timestamp"].(float64); ok {
+			fallback = time.Unix(int64(ts), 0).UTC()
+		}
+	}
+
+	var samples []Sample
+	for _, key := range s.keys {
+		data, err := s.storage.Get(key)
+		if err != nil {
+			continue
+		}
+		items, ok := data["data"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if dummy, _ := m["is_dummy"].(bool); dummy {
+				continue
+			}
+			cluster, _ := m["cluster"].(string)
+			value, ok := m["value"].(float64)
+			if cluster == "" || !ok {
+				continue
+			}
+
+			t := fallback
+			if ts, ok := m["timestamp"].(string); ok {
+				if parsed, err := time.Parse(time.RFC3339, ts); err == nil {
+					t = parsed
+				}
+			}
+			if t.IsZero() {
+				continue
+			}
+
+			samples = append(samples, Sample{Cluster: cluster, Metric: key, Value: value, Time: t})
+		}
+	}
+	return samples, nil
+}
+
+// NodeLoadSource reads the per-node load averages collected over the
+// remote shell
+type NodeLoadSource struct {
+	registry *registry.Registry
+	loads    *collector.NodeLoadStore
+}
+
+// NewNodeLoadSource creates a source for per-node load averages
+func NewNodeLoadSource(reg *registry.Registry, loads *collector.NodeLoadStore) *NodeLoadSource {
+	return &NodeLoadSource{registry: reg, loads: loads}
+}
+
+// Samples returns the latest 5 minute load average of every node
+func (s *NodeLoadSource) Samples(ctx context.Context) ([]Sample, error) {
+	clusters, err := s.registry.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	for _, cluster := range clusters {
+		loads, err := s.loads.Get(cluster)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range loads {
+			samples = append(samples, Sample{
+				Cluster: cluster,
+				Node:    l.Node,
+				Metric:  "load5",
+				Value:   l.Load5,
+				Time:    l.CollectedAt,
+			})
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// authorize responds with 403 and returns false unless the user of the
+// request holds role on the cluster. An empty cluster asks for a grant
+// covering every cluster.
+func authorize(w http.ResponseWriter, r *http.Request, role, cluster string) bool {
+	if auth.Can(r.Context(), role, cluster) {
+		return true
+	}
+	if cluster == "" {
+		auth.Forbidden(w, fmt.Sprintf("The %s role is required on every cluster", role))
+	} else {
+		auth.Forbidden(w, fmt.Sprintf("The %s role is required on cluster %s", role, cluster))
+	}
+	return false
+}
+
+// authorizeClusters is authorize for every cluster of a list. An empty list
+// asks for a grant covering every cluster.
+func authorizeClusters(w http.ResponseWriter, r *http.Request, role string, clusters []string) bool {
+	if len(clusters) == 0 {
+		return authorize(w, r, role, "")
+	}
+	for _, cluster := range clusters {
+		if !authorize(w, r, role, cluster) {
+			return false
+		}
+	}
+	return true
+}
+
+// canView reports whether the user of the request may see the cluster
+func canView(r *http.Request, cluster string) bool {
+	return auth.Can(r.Context(), models.RoleViewer, cluster)
+}
+
+// canViewAffected reports whether the user of the request may see something
+// affecting the clusters, which it may when it can see one of them. An empty
+// list, or an empty cluster, affects every cluster and is seen by everyone.
+func canViewAffected(r *http.Request, clusters ...string) bool {
+	if len(clusters) == 0 {
+		return true
+	}
+	for _, cluster := range clusters {
+		if cluster == "" || canView(r, cluster) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeAffected is canViewAffected, responding with 403 and returning
+// false when the user of the request may not see any of the clusters
+func authorizeAffected(w http.ResponseWriter, r *http.Request, clusters ...string) bool {
+	if canViewAffected(r, clusters...) {
+		return true
+	}
+	return authorize(w, r, models.RoleViewer, clusters[0])
+}
+
+// clusterParam returns the "cluster" query parameter, responding with 403
+// and returning false when the user of the request may not see the cluster
+func clusterParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cluster := r.URL.Query().Get("cluster")
+	if cluster != "" && !authorize(w, r, models.RoleViewer, cluster) {
+		return "", false
+	}
+	return cluster, true
+}
+
+// seesAllUsers reports whether the user of the request may see the per-user
+// rows of other users on the cluster. Everyone else only sees their own.
+func seesAllUsers(r *http.Request, cluster string) bool {
+	return auth.Can(r.Context(), models.RoleClusterAdmin, cluster)
+}
+
+// currentUsername returns the login name of the user of the request
+func currentUsername(r *http.Request) string {
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return user.Username
+	}
+	return ""
+}
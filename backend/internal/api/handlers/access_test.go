@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// requestAs returns a request to target made by a user holding grants
+func requestAs(method, target string, grants ...models.RoleGrant) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	user := &models.User{Username: "alice", Source: models.UserSourceLocal, Roles: grants}
+	return r.WithContext(auth.WithUser(r.Context(), user))
+}
+
+func TestCanViewAffected(t *testing.T) {
+	viewer := models.RoleGrant{Role: models.RoleViewer, Cluster: "asuka"}
+	tests := []struct {
+		name     string
+		clusters []string
+		want     bool
+	}{
+		{"granted cluster", []string{"asuka"}, true},
+		{"other cluster", []string{"naruko"}, false},
+		{"one of several clusters", []string{"naruko", "asuka"}, true},
+		{"every cluster", nil, true},
+		{"every cluster as an empty name", []string{""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := requestAs(http.MethodGet, "/api/v1/incidents", viewer)
+			if got := canViewAffected(r, tt.clusters...); got != tt.want {
+				t.Errorf("canViewAffected(%v) = %v, want %v", tt.clusters, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterParam(t *testing.T) {
+	viewer := models.RoleGrant{Role: models.RoleViewer, Cluster: "asuka"}
+	tests := []struct {
+		name   string
+		target string
+		ok     bool
+	}{
+		{"no cluster", "/api/v1/alerts", true},
+		{"granted cluster", "/api/v1/alerts?cluster=asuka", true},
+		{"other cluster", "/api/v1/alerts?cluster=naruko", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			_, ok := clusterParam(rec, requestAs(http.MethodGet, tt.target, viewer))
+			if ok != tt.ok {
+				t.Fatalf("clusterParam ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok && rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+		})
+	}
+}
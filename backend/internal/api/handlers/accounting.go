@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/accounting"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// AccountingHandler handles per-user CPU accounting API requests
+type AccountingHandler struct {
+	store *accounting.Store
+}
+
+// NewAccountingHandler creates a new accounting handler
+func NewAccountingHandler(store *accounting.Store) *AccountingHandler {
+	return &AccountingHandler{store: store}
+}
+
+// GetUsers handles GET /api/v1/accounting/users
+func (h *AccountingHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	period := periodParam(r)
+
+	users, err := h.store.Period(period)
+	if err != nil {
+		h.respondAccountingError(w, err)
+		return
+	}
+
+	// The total covers every user, so the own row can be seen in proportion
+	var total float64
+	for _, u := range users {
+		total += u.CPUMinutes
+	}
+	if !seesAllUsers(r, "") {
+		own := []models.UserCPUPeriod{}
+		for _, u := range users {
+			if u.User == currentUsername(r) {
+				own = append(own, u)
+			}
+		}
+		users = own
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period":            period,
+		"users":             users,
+		"total_cpu_minutes": total,
+	})
+}
+
+// GetUser handles GET /api/v1/accounting/users/{user}
+func (h *AccountingHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	period := periodParam(r)
+	user := chi.URLParam(r, "user")
+	if user != currentUsername(r) && !authorize(w, r, models.RoleClusterAdmin, "") {
+		return
+	}
+
+	days, err := h.store.UserDays(period, user)
+	if err != nil {
+		h.respondAccountingError(w, err)
+		return
+	}
+
+	var total float64
+	for _, d := range days {
+		total += d.CPUMinutes
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period":            period,
+		"user":              user,
+		"days":              days,
+		"total_cpu_minutes": total,
+	})
+}
+
+// respondAccountingError maps accounting errors to HTTP responses
+func (h *AccountingHandler) respondAccountingError(w http.ResponseWriter, err error) {
+	if errors.Is(err, accounting.ErrInvalidPeriod) {
+		respondError(w, http.StatusBadRequest, "Invalid period", err)
+		return
+	}
+	respondError(w, http.StatusInternalServerError, "Internal server error", err)
+}
+
+// periodParam returns the "period" query parameter, defaulting to the
+// current month
+func periodParam(r *http.Request) string {
+	if period := r.URL.Query().Get("period"); period != "" {
+		return period
+	}
+	return time.Now().Format("2006-01")
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// AlertHandler handles alert API requests
+type AlertHandler struct {
+	engine *alert.Engine
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(engine *alert.Engine) *AlertHandler {
+	return &AlertHandler{engine: engine}
+}
+
+// GetAlerts handles GET /api/v1/alerts. Only alerts of clusters the user
+// may see are listed.
+func (h *AlertHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	cluster, ok := clusterParam(w, r)
+	if !ok {
+		return
+	}
+
+	var alerts []models.Alert
+	var err error
+	switch state {
+	case "", models.AlertStateFiring:
+		state = models.AlertStateFiring
+		alerts, err = h.engine.Firing()
+	case models.AlertStateResolved:
+		alerts, err = h.engine.Resolved()
+	default:
+		respondJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Invalid alert state",
+		})
+		return
+	}
+
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	filtered := []models.Alert{}
+	for _, a := range alerts {
+		if (cluster == "" || a.Cluster == cluster) && canView(r, a.Cluster) {
+			filtered = append(filtered, a)
+		}
+	}
+	alerts = filtered
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"state":  state,
+		"alerts": alerts,
+		"total":  len(alerts),
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/anomaly"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// AnomalyHandler handles anomaly API requests
+type AnomalyHandler struct {
+	detector *anomaly.Detector
+}
+
+// NewAnomalyHandler creates a new anomaly handler
+func NewAnomalyHandler(detector *anomaly.Detector) *AnomalyHandler {
+	return &AnomalyHandler{detector: detector}
+}
+
+// GetAnomalies handles GET /api/v1/anomalies. Only anomalies of clusters
+// the user may see are listed.
+func (h *AnomalyHandler) GetAnomalies(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := clusterParam(w, r)
+	if !ok {
+		return
+	}
+	window, err := parseWindow(r.URL.Query().Get("window"), 24*time.Hour)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid window parameter", err)
+		return
+	}
+
+	all, err := h.detector.Events(time.Now().Add(-window), cluster)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	events := []models.AnomalyEvent{}
+	for _, e := range all {
+		if canView(r, e.Cluster) {
+			events = append(events, e)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"window":    window.String(),
+		"anomalies": events,
+		"total":     len(events),
+	})
+}
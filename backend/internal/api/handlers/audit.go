@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+)
+
+// AuditHandler handles audit log API requests
+type AuditHandler struct {
+	log *audit.Log
+}
+
+// NewAuditHandler creates a new audit log handler
+func NewAuditHandler(log *audit.Log) *AuditHandler {
+	return &AuditHandler{log: log}
+}
+
+// GetAuditLog handles GET /api/v1/audit
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+	}
+
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			parsed, err := parseTime(value)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid "+name+" parameter", err)
+				return
+			}
+			*t = parsed
+		}
+	}
+	limit, err := parseIntParam(query.Get("limit"), 100)
+	if err != nil || limit <= 0 {
+		if err == nil {
+			err = errors.New("limit must be positive")
+		}
+		respondError(w, http.StatusBadRequest, "Invalid limit parameter", err)
+		return
+	}
+	filter.Limit = limit
+
+	entries, err := h.log.Query(filter)
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidFilter) {
+			respondError(w, http.StatusBadRequest, "Invalid audit filter", err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// changeAction names the audit action of a write that updates its target
+// when it existed and creates it otherwise
+func changeAction(kind string, existed bool) string {
+	if existed {
+		return kind + ".update"
+	}
+	return kind + ".create"
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// loginRequest is the body of POST /api/v1/auth/login
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// userRequest is the body of PUT /api/v1/auth/users/{username}
+type userRequest struct {
+	Name     string             `json:"name"`
+	Email    string             `json:"email"`
+	Password string             `json:"password"`
+	Roles    []models.RoleGrant `json:"roles"`
+}
+
+// auditedUser is a user as recorded in the audit log, which notes that the
+// password was set without recording it
+type auditedUser struct {
+	*models.User
+	Password bool `json:"password,omitempty"`
+}
+
+// AuthHandler handles login, logout and user management requests
+type AuthHandler struct {
+	users    *auth.Users
+	sessions *auth.Sessions
+	login    *auth.Chain
+	oidc     *auth.OIDC // nil when OIDC login is not configured
+}
+
+// NewAuthHandler creates a new authentication handler
+func NewAuthHandler(users *auth.Users, sessions *auth.Sessions, login *auth.Chain, oidc *auth.OIDC) *AuthHandler {
+	return &AuthHandler{users: users, sessions: sessions, login: login, oidc: oidc}
+}
+
+// Login handles POST /api/v1/auth/login
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	user, err := h.login.Login(r.Context(), req.Username, req.Password)
+	if err != nil {
+		respondLoginError(w, err)
+		return
+	}
+
+	session, err := h.sessions.Create(w, user.Username)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"user":       user,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+// StartOIDCLogin handles GET /api/v1/auth/oidc/login
+func (h *AuthHandler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		respondError(w, http.StatusNotFound, "OIDC login is not configured", nil)
+		return
+	}
+
+	target, err := h.oidc.Start(r.Context(), w, r.URL.Query().Get("return_to"))
+	if err != nil {
+		respondLoginError(w, err)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// FinishOIDCLogin handles GET /api/v1/auth/oidc/callback
+func (h *AuthHandler) FinishOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		respondError(w, http.StatusNotFound, "OIDC login is not configured", nil)
+		return
+	}
+
+	id, returnTo, err := h.oidc.Finish(r.Context(), w, r)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		respondLoginError(w, err)
+		return
+	}
+	user, err := h.login.Complete(id)
+	if err != nil {
+		log.Printf("OIDC login of %s failed: %v", id.Username, err)
+		respondLoginError(w, err)
+		return
+	}
+	if _, err := h.sessions.Create(w, user.Username); err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+// Logout handles POST /api/v1/auth/logout
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.sessions.Revoke(w, r); err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCurrentUser handles GET /api/v1/auth/me
+func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Authentication required", nil)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, user)
+}
+
+// ListUsers handles GET /api/v1/auth/users
+func (h *AuthHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.users.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"users": users,
+		"total": len(users),
+	})
+}
+
+// PutUser handles PUT /api/v1/auth/users/{username}
+func (h *AuthHandler) PutUser(w http.ResponseWriter, r *http.Request) {
+	var req userRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	before, _ := h.users.Get(chi.URLParam(r, "username"))
+	user, err := h.users.Put(models.User{
+		Username: chi.URLParam(r, "username"),
+		Name:     req.Name,
+		Email:    req.Email,
+		Roles:    req.Roles,
+	}, req.Password)
+	if err != nil {
+		h.respondUserError(w, err)
+		return
+	}
+	audit.Record(r, changeAction("user", before != nil), "user:"+user.Username,
+		auditedUser{User: before}, auditedUser{User: user, Password: req.Password != ""})
+
+	respondJSON(w, http.StatusOK, user)
+}
+
+// DeleteUser handles DELETE /api/v1/auth/users/{username}
+func (h *AuthHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	before, err := h.users.Get(username)
+	if err != nil {
+		h.respondUserError(w, err)
+		return
+	}
+	if err := h.users.Delete(username); err != nil {
+		h.respondUserError(w, err)
+		return
+	}
+	if err := h.sessions.RevokeUser(username); err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	audit.Record(r, "user.delete", "user:"+username, before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondUserError maps user store errors to HTTP responses
+func (h *AuthHandler) respondUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		respondError(w, http.StatusNotFound, "User not found", err)
+	case errors.Is(err, auth.ErrInvalidUser):
+		respondError(w, http.StatusBadRequest, "Invalid user", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
+
+// respondLoginError maps login errors to HTTP responses
+func respondLoginError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		respondError(w, http.StatusUnauthorized, "Invalid credentials", err)
+	case errors.Is(err, auth.ErrInvalidLoginState):
+		respondError(w, http.StatusBadRequest, "Invalid login state", err)
+	case errors.Is(err, auth.ErrUnavailable):
+		respondError(w, http.StatusServiceUnavailable, "Authentication unavailable", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/availability"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+)
+
+// AvailabilityHandler handles node availability API requests
+type AvailabilityHandler struct {
+	registry   *registry.Registry
+	calculator *availability.Calculator
+}
+
+// NewAvailabilityHandler creates a new availability handler
+func NewAvailabilityHandler(reg *registry.Registry, calculator *availability.Calculator) *AvailabilityHandler {
+	return &AvailabilityHandler{registry: reg, calculator: calculator}
+}
+
+// GetAvailability handles GET /api/v1/availability. Only clusters the user
+// may see are listed.
+func (h *AvailabilityHandler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := clusterParam(w, r)
+	if !ok {
+		return
+	}
+	if cluster != "" {
+		if _, ok := resolveCluster(w, h.registry, cluster); !ok {
+			return
+		}
+	}
+	start, end, ok := timeRangeParams(w, r, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	all, err := h.calculator.Clusters(cluster, start, end)
+	if err != nil {
+		h.respondAvailabilityError(w, err)
+		return
+	}
+	clusters := visibleAvailability(r, all)
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"start":    start,
+		"end":      end,
+		"clusters": clusters,
+		"total":    len(clusters),
+	})
+}
+
+// GetFlakyNodes handles GET /api/v1/availability/flaky. Only nodes of
+// clusters the user may see are listed.
+func (h *AvailabilityHandler) GetFlakyNodes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cluster, ok := clusterParam(w, r)
+	if !ok {
+		return
+	}
+	if cluster != "" {
+		if _, ok := resolveCluster(w, h.registry, cluster); !ok {
+			return
+		}
+	}
+	start, end, ok := timeRangeParams(w, r, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+	top, err := parseIntParam(query.Get("top"), 10)
+	if err != nil || top < 0 {
+		respondError(w, http.StatusBadRequest, "Invalid top parameter", err)
+		return
+	}
+
+	// Nodes are filtered before the top ones are taken
+	flaky, err := h.calculator.Flakiest(cluster, start, end, 0)
+	if err != nil {
+		h.respondAvailabilityError(w, err)
+		return
+	}
+	nodes := visibleAvailability(r, flaky)
+	if top > 0 && len(nodes) > top {
+		nodes = nodes[:top]
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"start": start,
+		"end":   end,
+		"nodes": nodes,
+		"total": len(nodes),
+	})
+}
+
+// GetClusterAvailability handles GET /api/v1/clusters/{name}/availability
+func (h *AvailabilityHandler) GetClusterAvailability(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := resolveCluster(w, h.registry, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+	start, end, ok := timeRangeParams(w, r, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	nodes, err := h.calculator.Nodes(cluster.Name, start, end)
+	if err != nil {
+		h.respondAvailabilityError(w, err)
+		return
+	}
+	summaries, err := h.calculator.Clusters(cluster.Name, start, end)
+	if err != nil {
+		h.respondAvailabilityError(w, err)
+		return
+	}
+
+	var summary interface{}
+	if len(summaries) > 0 {
+		summary = summaries[0]
+	}
+	if nodes == nil {
+		nodes = []models.Availability{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster": cluster.Name,
+		"start":   start,
+		"end":     end,
+		"summary": summary,
+		"nodes":   nodes,
+	})
+}
+
+// visibleAvailability returns the entries of clusters the user of the
+// request may see
+func visibleAvailability(r *http.Request, all []models.Availability) []models.Availability {
+	visible := []models.Availability{}
+	for _, a := range all {
+		if canView(r, a.Cluster) {
+			visible = append(visible, a)
+		}
+	}
+	return visible
+}
+
+// respondAvailabilityError maps availability errors to HTTP responses
+func (h *AvailabilityHandler) respondAvailabilityError(w http.ResponseWriter, err error) {
+	if errors.Is(err, availability.ErrInvalidWindow) {
+		respondError(w, http.StatusBadRequest, "Invalid window", err)
+		return
+	}
+	respondError(w, http.StatusInternalServerError, "Internal server error", err)
+}
+
+// timeRangeParams reads the "start" and "end" query parameters (RFC 3339 or
+// YYYY-MM-DD), or a "window" ending now when start is not given. It writes
+// a 400 response and returns false for invalid values.
+func timeRangeParams(w http.ResponseWriter, r *http.Request, defaultWindow time.Duration) (time.Time, time.Time, bool) {
+	query := r.URL.Query()
+
+	end := time.Now().UTC()
+	if value := query.Get("end"); value != "" {
+		t, err := parseTime(value)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid end parameter", err)
+			return time.Time{}, time.Time{}, false
+		}
+		end = t
+	}
+
+	if value := query.Get("start"); value != "" {
+		start, err := parseTime(value)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid start parameter", err)
+			return time.Time{}, time.Time{}, false
+		}
+		return start, end, true
+	}
+
+	window, err := parseWindow(query.Get("window"), defaultWindow)
+	if err != nil || window <= 0 {
+		if err == nil {
+			err = errors.New("window must be positive")
+		}
+		respondError(w, http.StatusBadRequest, "Invalid window parameter", err)
+		return time.Time{}, time.Time{}, false
+	}
+	return end.Add(-window), end, true
+}
+
+// parseTime parses an RFC 3339 time or a local YYYY-MM-DD date
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", value)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/chargeback"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// maxGroupFileSize limits the size of an uploaded group file
+const maxGroupFileSize = 4 << 20
+
+// ChargebackHandler handles research group and invoice API requests
+type ChargebackHandler struct {
+	groups *chargeback.Groups
+	biller *chargeback.Biller
+}
+
+// NewChargebackHandler creates a new chargeback handler
+func NewChargebackHandler(groups *chargeback.Groups, biller *chargeback.Biller) *ChargebackHandler {
+	return &ChargebackHandler{groups: groups, biller: biller}
+}
+
+// ListGroups handles GET /api/v1/chargeback/groups
+func (h *ChargebackHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.groups.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"groups": groups,
+		"total":  len(groups),
+	})
+}
+
+// GetGroup handles GET /api/v1/chargeback/groups/{group}
+func (h *ChargebackHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.groups.Get(chi.URLParam(r, "group"))
+	if err != nil {
+		h.respondChargebackError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, group)
+}
+
+// PutGroup handles PUT /api/v1/chargeback/groups/{group}
+func (h *ChargebackHandler) PutGroup(w http.ResponseWriter, r *http.Request) {
+	var group models.ResearchGroup
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	group.Name = chi.URLParam(r, "group")
+
+	before, _ := h.groups.Get(group.Name)
+	saved, err := h.groups.Put(group)
+	if err != nil {
+		h.respondChargebackError(w, err)
+		return
+	}
+	audit.Record(r, changeAction("group", before != nil), "group:"+saved.Name, before, saved)
+
+	respondJSON(w, http.StatusOK, saved)
+}
+
+// DeleteGroup handles DELETE /api/v1/chargeback/groups/{group}
+func (h *ChargebackHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	before, err := h.groups.Get(chi.URLParam(r, "group"))
+	if err != nil {
+		h.respondChargebackError(w, err)
+		return
+	}
+	if err := h.groups.Delete(before.Name); err != nil {
+		h.respondChargebackError(w, err)
+		return
+	}
+	audit.Record(r, "group.delete", "group:"+before.Name, before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportGroups handles POST /api/v1/chargeback/groups/import. The request
+// body is imported as an /etc/group format file; an empty body imports the
+// configured group file.
+func (h *ChargebackHandler) ImportGroups(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxGroupFileSize))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	var imported int
+	if len(bytes.TrimSpace(body)) > 0 {
+		imported, err = h.groups.Import(bytes.NewReader(body), h.groups.MinGID)
+	} else {
+		imported, err = h.groups.ImportFile()
+	}
+	if err != nil {
+		h.respondChargebackError(w, err)
+		return
+	}
+	audit.Record(r, "group.import", "groups", nil, map[string]interface{}{"imported": imported})
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"imported": imported,
+	})
+}
+
+// GetRates handles GET /api/v1/chargeback/rates
+func (h *ChargebackHandler) GetRates(w http.ResponseWriter, r *http.Request) {
+	clusters := h.biller.Rates.Clusters
+	if clusters == nil {
+		clusters = map[string]float64{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"currency":   h.biller.Currency,
+		"default":    h.biller.Rates.Default,
+		"clusters":   clusters,
+		"accounting": h.biller.Rates.Accounting,
+	})
+}
+
+// GetInvoices handles GET /api/v1/chargeback/invoices
+func (h *ChargebackHandler) GetInvoices(w http.ResponseWriter, r *http.Request) {
+	period := periodParam(r)
+
+	invoices, err := h.biller.Invoices(period)
+	if err != nil {
+		h.respondChargebackError(w, err)
+		return
+	}
+
+	h.respondInvoices(w, r, "chargeback-"+period+".csv", invoices, map[string]interface{}{
+		"period":   period,
+		"invoices": invoices,
+		"total":    len(invoices),
+	})
+}
+
+// GetInvoice handles GET /api/v1/chargeback/invoices/{group}
+func (h *ChargebackHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	period := periodParam(r)
+	group := chi.URLParam(r, "group")
+
+	invoice, err := h.biller.Invoice(period, group)
+	if err != nil {
+		h.respondChargebackError(w, err)
+		return
+	}
+
+	h.respondInvoices(w, r, "chargeback-"+group+"-"+period+".csv", []models.Invoice{*invoice}, invoice)
+}
+
+// respondInvoices writes invoices as CSV when format=csv, or v as JSON
+func (h *ChargebackHandler) respondInvoices(w http.ResponseWriter, r *http.Request, filename string, invoices []models.Invoice, v interface{}) {
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		respondJSON(w, http.StatusOK, v)
+	case "csv":
+		var buf bytes.Buffer
+		if err := chargeback.WriteCSV(&buf, invoices); err != nil {
+			respondError(w, http.StatusInternalServerError, "Internal server error", err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	default:
+		respondJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Invalid invoice format",
+		})
+	}
+}
+
+// respondChargebackError maps chargeback errors to HTTP responses
+func (h *ChargebackHandler) respondChargebackError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, chargeback.ErrGroupNotFound):
+		respondError(w, http.StatusNotFound, "Group not found", err)
+	case errors.Is(err, chargeback.ErrInvalidGroup):
+		respondError(w, http.StatusBadRequest, "Invalid group", err)
+	case errors.Is(err, chargeback.ErrInvalidPeriod):
+		respondError(w, http.StatusBadRequest, "Invalid period", err)
+	case errors.Is(err, chargeback.ErrNoGroupFile):
+		respondError(w, http.StatusBadRequest, "No group file configured", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
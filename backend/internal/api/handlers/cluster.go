@@ -3,17 +3,24 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
 	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+	"github.com/taisei-ito/cluster-status-monitor/internal/usage"
 )
 
 // ClusterHandler handles cluster API requests
 type ClusterHandler struct {
-	storage storage.Storage
+	storage     storage.Storage
+	registry    *registry.Registry
+	filesystems *collector.FilesystemStore
+	usage       *usage.Store
 }
 
 // NewClusterHandler creates a new cluster handler
-func NewClusterHandler(storage storage.Storage) *ClusterHandler {
-	return &ClusterHandler{storage: storage}
+func NewClusterHandler(storage storage.Storage, registry *registry.Registry, filesystems *collector.FilesystemStore, usage *usage.Store) *ClusterHandler {
+	return &ClusterHandler{storage: storage, registry: registry, filesystems: filesystems, usage: usage}
 }
 
 // GetClusterInfo handles GET /api/cluster
@@ -28,12 +35,19 @@ func (h *ClusterHandler) GetClusterInfo(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !authorize(w, r, models.RoleViewer, clusterName) {
+		return
+	}
+	if _, ok := resolveCluster(w, h.registry, clusterName); !ok {
+		return
+	}
+
 	var response interface{}
 	var err error
 
 	switch dataType {
 	case "users":
-		response, err = h.getClusterUsers(clusterName)
+		response, err = h.getClusterUsers(clusterName, r)
 	case "disk":
 		response, err = h.getClusterDisk(clusterName)
 	case "history":
@@ -43,10 +57,7 @@ func (h *ClusterHandler) GetClusterInfo(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, map[string]string{
-			"error":   "Internal server error",
-			"message": err.Error(),
-		})
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
 		return
 	}
 
@@ -82,8 +93,39 @@ func (h *ClusterHandler) getClusterSummary(clusterName string) (map[string]inter
 	return result, nil
 }
 
-// getClusterUsers returns user information for a cluster
-func (h *ClusterHandler) getClusterUsers(clusterName string) (map[string]interface{}, error) {
+// getClusterUsers returns user information for a cluster. Usage recorded
+// from the running jobs is preferred over the legacy script output. Users
+// without the cluster-admin role only get their own row.
+func (h *ClusterHandler) getClusterUsers(clusterName string, r *http.Request) (map[string]interface{}, error) {
+	latest, err := h.usage.Latest(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	seesAll := seesAllUsers(r, clusterName)
+	if len(latest) > 0 {
+		if !seesAll {
+			own := []models.UserUsage{}
+			for _, u := range latest {
+				if u.Username == currentUsername(r) {
+					own = append(own, u)
+				}
+			}
+			latest = own
+		}
+		return map[string]interface{}{
+			"cluster": clusterName,
+			"users":   latest,
+		}, nil
+	}
+
+	// The legacy output cannot be filtered per user
+	if !seesAll {
+		return map[string]interface{}{
+			"cluster": clusterName,
+			"users":   []interface{}{},
+		}, nil
+	}
+
 	key := "cluster_" + clusterName + "_users"
 	userData, err := h.storage.Get(key)
 
@@ -101,8 +143,20 @@ func (h *ClusterHandler) getClusterUsers(clusterName string) (map[string]interfa
 	}, nil
 }
 
-// getClusterDisk returns disk usage information for a cluster
+// getClusterDisk returns disk usage information for a cluster. Data from the
+// filesystem collector is preferred over the legacy script output.
 func (h *ClusterHandler) getClusterDisk(clusterName string) (map[string]interface{}, error) {
+	usage, err := h.filesystems.DiskUsage(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if len(usage) > 0 {
+		return map[string]interface{}{
+			"cluster": clusterName,
+			"disk":    usage,
+		}, nil
+	}
+
 	key := "cluster_" + clusterName + "_disk"
 	diskData, err := h.storage.Get(key)
 
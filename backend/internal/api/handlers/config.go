@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/reload"
+)
+
+// ConfigHandler handles configuration reload API requests
+type ConfigHandler struct {
+	reloader *reload.Reloader
+}
+
+// NewConfigHandler creates a new configuration handler
+func NewConfigHandler(reloader *reload.Reloader) *ConfigHandler {
+	return &ConfigHandler{reloader: reloader}
+}
+
+// ReloadConfig handles POST /api/v1/config/reload
+func (h *ConfigHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	var actor string
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		actor = user.Username
+	}
+
+	result := h.reloader.Reload(models.ConfigReloadAPI, actor)
+	if !result.Success {
+		respondJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	audit.Record(r, "config.reload", "config", nil, map[string][]string{"applied": result.Applied})
+	respondJSON(w, http.StatusOK, result)
+}
+
+// ListReloads handles GET /api/v1/config/reloads
+func (h *ConfigHandler) ListReloads(w http.ResponseWriter, r *http.Request) {
+	reloads := h.reloader.Results()
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"reloads": reloads,
+		"total":   len(reloads),
+	})
+}
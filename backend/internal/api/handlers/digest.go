@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/digest"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/notify"
+)
+
+// DigestHandler handles weekly digest API requests
+type DigestHandler struct {
+	scheduler     *digest.Scheduler
+	subscriptions *digest.Subscriptions
+}
+
+// NewDigestHandler creates a new digest handler
+func NewDigestHandler(scheduler *digest.Scheduler, subscriptions *digest.Subscriptions) *DigestHandler {
+	return &DigestHandler{scheduler: scheduler, subscriptions: subscriptions}
+}
+
+// ListSubscriptions handles GET /api/v1/digest/subscriptions
+func (h *DigestHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.subscriptions.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"subscriptions": subs,
+		"total":         len(subs),
+	})
+}
+
+// PutSubscription handles PUT /api/v1/digest/subscriptions/{recipient}
+func (h *DigestHandler) PutSubscription(w http.ResponseWriter, r *http.Request) {
+	var sub models.DigestSubscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	sub.Recipient = chi.URLParam(r, "recipient")
+
+	before, _ := h.subscriptions.Get(sub.Recipient)
+	saved, err := h.subscriptions.Put(sub)
+	if err != nil {
+		h.respondDigestError(w, err)
+		return
+	}
+	audit.Record(r, changeAction("digest_subscription", before != nil), "digest_subscription:"+saved.Recipient, before, saved)
+
+	respondJSON(w, http.StatusOK, saved)
+}
+
+// DeleteSubscription handles DELETE /api/v1/digest/subscriptions/{recipient}
+func (h *DigestHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	before, err := h.subscriptions.Get(chi.URLParam(r, "recipient"))
+	if err != nil {
+		h.respondDigestError(w, err)
+		return
+	}
+	if err := h.subscriptions.Delete(before.Recipient); err != nil {
+		h.respondDigestError(w, err)
+		return
+	}
+	audit.Record(r, "digest_subscription.delete", "digest_subscription:"+before.Recipient, before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPreview handles GET /api/v1/digest/preview
+func (h *DigestHandler) GetPreview(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var clusters []string
+	if list := query.Get("clusters"); list != "" {
+		clusters = strings.Split(list, ",")
+	}
+
+	d, err := h.scheduler.Build(clusters, time.Now().UTC())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	switch query.Get("format") {
+	case "", "json":
+		respondJSON(w, http.StatusOK, d)
+		return
+	case "html":
+		err = digest.RenderHTML(&buf, d)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	case "text":
+		err = digest.RenderText(&buf, d)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	default:
+		respondJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Invalid digest format",
+		})
+		return
+	}
+
+	if err != nil {
+		w.Header().Del("Content-Type")
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// SendDigest handles POST /api/v1/digest/send
+func (h *DigestHandler) SendDigest(w http.ResponseWriter, r *http.Request) {
+	sent, err := h.scheduler.SendAll(r.Context())
+	if errors.Is(err, notify.ErrNotConfigured) {
+		respondError(w, http.StatusServiceUnavailable, "Mail is not configured", err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadGateway, "Digest delivery failed", err)
+		return
+	}
+
+	audit.Record(r, "digest.send", "digest", nil, map[string]interface{}{"sent": sent})
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"sent": sent,
+	})
+}
+
+// respondDigestError maps digest errors to HTTP responses
+func (h *DigestHandler) respondDigestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, digest.ErrSubscriptionNotFound):
+		respondError(w, http.StatusNotFound, "Subscription not found", err)
+	case errors.Is(err, digest.ErrInvalidSubscription):
+		respondError(w, http.StatusBadRequest, "Invalid subscription", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/forecast"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+)
+
+// DiskHandler handles disk usage API requests
+type DiskHandler struct {
+	registry    *registry.Registry
+	users       *collector.DiskUserStore
+	filesystems *collector.FilesystemStore
+	forecaster  *forecast.Forecaster
+}
+
+// NewDiskHandler creates a new disk handler
+func NewDiskHandler(registry *registry.Registry, users *collector.DiskUserStore, filesystems *collector.FilesystemStore, forecaster *forecast.Forecaster) *DiskHandler {
+	return &DiskHandler{registry: registry, users: users, filesystems: filesystems, forecaster: forecaster}
+}
+
+// GetDisk handles GET /api/v1/clusters/{name}/disk
+func (h *DiskHandler) GetDisk(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := resolveCluster(w, h.registry, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	usage, err := h.filesystems.DiskUsage(cluster.Name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster":  cluster.Name,
+		"disk":     usage,
+		"has_data": len(usage) > 0,
+	})
+}
+
+// GetFilesystems handles GET /api/v1/clusters/{name}/filesystems
+func (h *DiskHandler) GetFilesystems(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := resolveCluster(w, h.registry, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	filesystems, err := h.filesystems.Get(cluster.Name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster":     cluster.Name,
+		"filesystems": filesystems,
+		"has_data":    len(filesystems) > 0,
+	})
+}
+
+// GetForecast handles GET /api/v1/clusters/{name}/filesystems/forecast
+func (h *DiskHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := resolveCluster(w, h.registry, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	window, err := parseWindow(query.Get("window"), 7*24*time.Hour)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid window parameter", err)
+		return
+	}
+
+	forecasts, err := h.forecaster.Cluster(cluster.Name, window)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	host, mount := query.Get("host"), query.Get("mount")
+	filtered := make([]models.FilesystemForecast, 0, len(forecasts))
+	for _, fc := range forecasts {
+		if (host == "" || fc.Host == host) && (mount == "" || fc.MountPoint == mount) {
+			filtered = append(filtered, fc)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster":   cluster.Name,
+		"window":    window.String(),
+		"forecasts": filtered,
+	})
+}
+
+// GetDiskUsers handles GET /api/v1/clusters/{name}/disk/users
+func (h *DiskHandler) GetDiskUsers(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := resolveCluster(w, h.registry, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	top, err := parseIntParam(query.Get("top"), 10)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid top parameter", err)
+		return
+	}
+	window, err := parseWindow(query.Get("window"), 24*time.Hour)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid window parameter", err)
+		return
+	}
+
+	// Users without the cluster-admin role only see their own row, wherever
+	// it ranks
+	seesAll := seesAllUsers(r, cluster.Name)
+	if !seesAll {
+		top = 0
+	}
+	report, err := h.users.Report(cluster.Name, query.Get("filesystem"), top, window)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	if !seesAll {
+		own := []models.UserDiskUsage{}
+		for _, u := range report.Users {
+			if u.User == currentUsername(r) {
+				own = append(own, u)
+			}
+		}
+		report.Users = own
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// parseIntParam parses an integer query parameter or returns a default value
+func parseIntParam(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseWindow parses a time window such as "24h" or "7d"
+func parseWindow(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/incident"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// incidentRequest is the body of POST /api/v1/incidents. With an alert ID
+// the incident is opened from that alert and the other fields are optional.
+type incidentRequest struct {
+	AlertID  string   `json:"alert_id"`
+	Title    string   `json:"title"`
+	Status   string   `json:"status"`
+	Severity string   `json:"severity"`
+	Clusters []string `json:"clusters"`
+	Nodes    []string `json:"nodes"`
+	Alerts   []string `json:"alerts"`
+	Message  string   `json:"message"`
+}
+
+// incidentUpdateRequest is the body of POST /api/v1/incidents/{id}/updates.
+// The author is always the user of the request.
+type incidentUpdateRequest struct {
+	models.IncidentUpdate
+	Resolution string `json:"resolution"`
+}
+
+// IncidentHandler handles incident API requests
+type IncidentHandler struct {
+	store  *incident.Store
+	alerts *alert.Engine
+}
+
+// NewIncidentHandler creates a new incident handler
+func NewIncidentHandler(store *incident.Store, alerts *alert.Engine) *IncidentHandler {
+	return &IncidentHandler{store: store, alerts: alerts}
+}
+
+// ListIncidents handles GET /api/v1/incidents. Only incidents affecting a
+// cluster the user may see are listed.
+func (h *IncidentHandler) ListIncidents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	open, _ := strconv.ParseBool(query.Get("open"))
+	cluster, ok := clusterParam(w, r)
+	if !ok {
+		return
+	}
+
+	all, err := h.store.List(incident.Filter{
+		Status:  query.Get("status"),
+		Cluster: cluster,
+		Open:    open,
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	incidents := []models.Incident{}
+	for _, inc := range all {
+		if canViewAffected(r, inc.Clusters...) {
+			incidents = append(incidents, inc)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"incidents": incidents,
+		"total":     len(incidents),
+	})
+}
+
+// CreateIncident handles POST /api/v1/incidents
+func (h *IncidentHandler) CreateIncident(w http.ResponseWriter, r *http.Request) {
+	var req incidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// An incident opened from an alert affects the alert's cluster
+	clusters := req.Clusters
+	if req.AlertID != "" {
+		a, err := h.alerts.Get(req.AlertID)
+		if err != nil {
+			h.respondIncidentError(w, err)
+			return
+		}
+		clusters = []string{a.Cluster}
+	}
+	if !authorizeClusters(w, r, models.RoleClusterAdmin, clusters) {
+		return
+	}
+	author := currentUsername(r)
+
+	var inc *models.Incident
+	var err error
+	if req.AlertID != "" {
+		inc, err = h.store.CreateFromAlert(req.AlertID, req.Title, req.Message, author)
+	} else {
+		inc, err = h.store.Create(models.Incident{
+			Title:    req.Title,
+			Status:   req.Status,
+			Severity: req.Severity,
+			Clusters: req.Clusters,
+			Nodes:    req.Nodes,
+			Alerts:   req.Alerts,
+		}, req.Message, author)
+	}
+	if err != nil {
+		h.respondIncidentError(w, err)
+		return
+	}
+	audit.Record(r, "incident.create", "incident:"+strconv.Itoa(inc.ID), nil, inc)
+
+	respondJSON(w, http.StatusCreated, inc)
+}
+
+// GetIncident handles GET /api/v1/incidents/{id}
+func (h *IncidentHandler) GetIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+
+	inc, err := h.store.Get(id)
+	if err != nil {
+		h.respondIncidentError(w, err)
+		return
+	}
+	if !authorizeAffected(w, r, inc.Clusters...) {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, inc)
+}
+
+// PutIncident handles PUT /api/v1/incidents/{id}
+func (h *IncidentHandler) PutIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	before, ok := h.authorizeIncident(w, r, id)
+	if !ok {
+		return
+	}
+
+	var edit models.Incident
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !authorizeClusters(w, r, models.RoleClusterAdmin, edit.Clusters) {
+		return
+	}
+
+	inc, err := h.store.Edit(id, edit)
+	if err != nil {
+		h.respondIncidentError(w, err)
+		return
+	}
+	audit.Record(r, "incident.update", "incident:"+strconv.Itoa(id), before, inc)
+
+	respondJSON(w, http.StatusOK, inc)
+}
+
+// AddIncidentUpdate handles POST /api/v1/incidents/{id}/updates
+func (h *IncidentHandler) AddIncidentUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	before, ok := h.authorizeIncident(w, r, id)
+	if !ok {
+		return
+	}
+
+	var req incidentUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	req.Author = currentUsername(r)
+	inc, err := h.store.AddUpdate(id, req.IncidentUpdate, req.Resolution)
+	if err != nil {
+		h.respondIncidentError(w, err)
+		return
+	}
+	audit.Record(r, "incident.add_update", "incident:"+strconv.Itoa(id), before, inc)
+
+	respondJSON(w, http.StatusOK, inc)
+}
+
+// DeleteIncident handles DELETE /api/v1/incidents/{id}
+func (h *IncidentHandler) DeleteIncident(w http.ResponseWriter, r *http.Request) {
+	id, ok := incidentID(w, r)
+	if !ok {
+		return
+	}
+	before, ok := h.authorizeIncident(w, r, id)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Delete(id); err != nil {
+		h.respondIncidentError(w, err)
+		return
+	}
+	audit.Record(r, "incident.delete", "incident:"+strconv.Itoa(id), before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeIncident returns an existing incident, responding with 403 and
+// returning false unless the user of the request is a cluster admin of
+// every cluster of it
+func (h *IncidentHandler) authorizeIncident(w http.ResponseWriter, r *http.Request, id int) (*models.Incident, bool) {
+	inc, err := h.store.Get(id)
+	if err != nil {
+		h.respondIncidentError(w, err)
+		return nil, false
+	}
+	return inc, authorizeClusters(w, r, models.RoleClusterAdmin, inc.Clusters)
+}
+
+// respondIncidentError maps incident errors to HTTP responses
+func (h *IncidentHandler) respondIncidentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, incident.ErrIncidentNotFound):
+		respondError(w, http.StatusNotFound, "Incident not found", err)
+	case errors.Is(err, incident.ErrInvalidIncident):
+		respondError(w, http.StatusBadRequest, "Invalid incident", err)
+	case errors.Is(err, alert.ErrAlertNotFound):
+		respondError(w, http.StatusNotFound, "Alert not found", err)
+	case errors.Is(err, incident.ErrAlertNotFiring):
+		respondError(w, http.StatusConflict, "Alert is not firing", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
+
+// incidentID parses the {id} URL parameter, writing a 400 response when it
+// is not a number
+func incidentID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid incident ID", err)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// maxIngestBytes caps the size of pushed documents
+const maxIngestBytes = 16 << 20
+
+// ingestKeys are the global keys written by the legacy collector scripts
+var ingestKeys = map[string]bool{
+	"load_average": true,
+	"pbs_usage":    true,
+	"cpu_usage":    true,
+	"nodes_alive":  true,
+	"nodes_down":   true,
+	"metadata":     true,
+}
+
+// clusterIngestKey matches the per-cluster keys of the legacy collector
+// scripts
+var clusterIngestKey = regexp.MustCompile(`^cluster_(.+)_(load|pbs|cpu|users|disk|history)$`)
+
+// IngestHandler handles data pushed by collectors running on the clusters
+type IngestHandler struct {
+	storage     storage.Storage
+	registry    *registry.Registry
+	globalNames []string // Client certificate names allowed to push the global keys
+}
+
+// NewIngestHandler creates a new ingest handler. Client certificates issued
+// to one of globalNames may push the global keys; other certificates only
+// push the keys of their own cluster.
+func NewIngestHandler(storage storage.Storage, registry *registry.Registry, globalNames []string) *IngestHandler {
+	return &IngestHandler{storage: storage, registry: registry, globalNames: globalNames}
+}
+
+// PutData handles PUT /api/v1/ingest/{key}. The body becomes the data of
+// the key, as if the legacy scripts had written it. Only the keys of those
+// scripts are accepted, so tokens cannot overwrite other stored data. Global
+// keys need the admin role and cluster keys the cluster-admin role on their
+// cluster; certificates need to be issued to the cluster or its master
+// host, or to one of the global names for the global keys.
+func (h *IngestHandler) PutData(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+	if m := clusterIngestKey.FindStringSubmatch(key); m != nil {
+		cluster, ok := resolveCluster(w, h.registry, m[1])
+		if !ok {
+			return
+		}
+		_, isCert := auth.CertificateFromContext(r.Context())
+		switch {
+		case isCert && !auth.CanCertificate(r.Context(), cluster.Name, cluster.MasterHost):
+			// A cluster master's certificate only covers its own cluster
+			auth.Forbidden(w, fmt.Sprintf("The client certificate is not issued to cluster %s or its master host", cluster.Name))
+			return
+		case !isCert && !auth.CanScope(r.Context(), models.RoleClusterAdmin, cluster.Name):
+			auth.Forbidden(w, fmt.Sprintf("The %s role is required on cluster %s", models.RoleClusterAdmin, cluster.Name))
+			return
+		}
+	} else if !ingestKeys[key] {
+		respondError(w, http.StatusBadRequest, "Invalid key", fmt.Errorf("%q is not a collector key", key))
+		return
+	} else if _, isCert := auth.CertificateFromContext(r.Context()); isCert {
+		// Global keys hold data of every cluster
+		if !auth.CanCertificate(r.Context(), h.globalNames...) {
+			auth.Forbidden(w, "The client certificate is not allowed to push the global keys")
+			return
+		}
+	} else if !auth.CanScope(r.Context(), models.RoleAdmin, "") {
+		auth.Forbidden(w, fmt.Sprintf("The %s role is required on every cluster", models.RoleAdmin))
+		return
+	}
+
+	var data interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestBytes)).Decode(&data); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := storage.SetData(h.storage, key, data); err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	// The data is replaced wholesale and can be large, so it is not diffed
+	audit.Record(r, "ingest.put", "key:"+key, nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestIngestPutData(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	reg := registry.New(store, nil, nil)
+	for _, name := range []string{"asuka", "naruko"} {
+		if _, err := reg.Put(models.ClusterInfo{Name: name, Type: models.ClusterTypeCompute, MasterHost: name + "00"}); err != nil {
+			t.Fatalf("Put %s: %v", name, err)
+		}
+	}
+	router := chi.NewRouter()
+	router.Put("/api/v1/ingest/{key}", NewIngestHandler(store, reg, []string{"collector"}).PutData)
+
+	admin := models.RoleGrant{Role: models.RoleAdmin}
+	clusterAdmin := models.RoleGrant{Role: models.RoleClusterAdmin, Cluster: "asuka"}
+	viewer := models.RoleGrant{Role: models.RoleViewer}
+
+	tests := []struct {
+		name   string
+		key    string
+		grants []models.RoleGrant
+		cert   string
+		want   int
+	}{
+		{"global key as admin", "load_average", []models.RoleGrant{admin}, "", http.StatusNoContent},
+		{"global key as cluster-admin", "pbs_usage", []models.RoleGrant{clusterAdmin}, "", http.StatusForbidden},
+		{"cluster key on own cluster", "cluster_asuka_load", []models.RoleGrant{clusterAdmin}, "", http.StatusNoContent},
+		{"cluster key on other cluster", "cluster_naruko_load", []models.RoleGrant{clusterAdmin}, "", http.StatusForbidden},
+		{"cluster key as viewer", "cluster_asuka_users", []models.RoleGrant{viewer}, "", http.StatusForbidden},
+		{"cluster key as admin", "cluster_naruko_disk", []models.RoleGrant{admin}, "", http.StatusNoContent},
+		{"cluster key of an unknown cluster", "cluster_kirin_load", []models.RoleGrant{admin}, "", http.StatusNotFound},
+		{"key outside the collector keys", "users", []models.RoleGrant{admin}, "", http.StatusBadRequest},
+		{"certificate of the master host", "cluster_asuka_pbs", nil, "asuka00", http.StatusNoContent},
+		{"certificate of another master host", "cluster_asuka_cpu", nil, "naruko00", http.StatusForbidden},
+		// Only the configured certificate names may push the global keys
+		{"certificate on a global key", "metadata", nil, "asuka00", http.StatusForbidden},
+		{"allowed certificate on a global key", "nodes_down", nil, "collector", http.StatusNoContent},
+	}
+	// Every case uses its own key, so that refused writes can be told apart
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/ingest/"+tt.key, strings.NewReader(`{"value": 1}`))
+			user := &models.User{Username: "alice", Source: models.UserSourceLocal, Roles: tt.grants}
+			ctx := auth.WithUser(r.Context(), user)
+			if tt.cert != "" {
+				ctx = auth.WithCertificate(ctx, &x509.Certificate{Subject: pkix.Name{CommonName: tt.cert}})
+			}
+			r = r.WithContext(ctx)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+
+			var stored interface{}
+			err := storage.GetData(store, tt.key, &stored)
+			if written := err == nil && stored != nil; written != (tt.want == http.StatusNoContent) {
+				t.Errorf("key written = %v after status %d", written, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/jobs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// JobHandler handles PBS job API requests
+type JobHandler struct {
+	tracker *jobs.Tracker
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(tracker *jobs.Tracker) *JobHandler {
+	return &JobHandler{tracker: tracker}
+}
+
+// GetJobs handles GET /api/v1/jobs
+func (h *JobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	period := periodParam(r)
+
+	all, err := h.tracker.Month(period)
+	if err != nil {
+		if errors.Is(err, jobs.ErrInvalidPeriod) {
+			respondError(w, http.StatusBadRequest, "Invalid period", err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	query := r.URL.Query()
+	cluster, user, state := query.Get("cluster"), query.Get("user"), query.Get("state")
+	result := []models.Job{}
+	for _, job := range all {
+		if (cluster == "" || job.Cluster == cluster) && (user == "" || job.User == user) && (state == "" || job.State == state) {
+			// Users without the cluster-admin role only see their own jobs
+			if canView(r, job.Cluster) && (job.User == currentUsername(r) || seesAllUsers(r, job.Cluster)) {
+				result = append(result, job)
+			}
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period": period,
+		"jobs":   result,
+		"total":  len(result),
+	})
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/maintenance"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+)
+
+// MaintenanceHandler handles maintenance window API requests
+type MaintenanceHandler struct {
+	registry     *registry.Registry
+	store        *maintenance.Store
+	calendarName string
+}
+
+// NewMaintenanceHandler creates a new maintenance window handler
+func NewMaintenanceHandler(reg *registry.Registry, store *maintenance.Store, calendarName string) *MaintenanceHandler {
+	return &MaintenanceHandler{registry: reg, store: store, calendarName: calendarName}
+}
+
+// ListWindows handles GET /api/v1/maintenance. Only windows of clusters the
+// user may see, and those of every cluster, are listed.
+func (h *MaintenanceHandler) ListWindows(w http.ResponseWriter, r *http.Request) {
+	if _, ok := clusterParam(w, r); !ok {
+		return
+	}
+	all, err := h.store.List(maintenanceFilter(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	windows := []models.MaintenanceWindow{}
+	for _, win := range all {
+		if canViewAffected(r, win.Cluster) {
+			windows = append(windows, win)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"windows": windows,
+		"total":   len(windows),
+	})
+}
+
+// GetCalendar handles GET /status/maintenance.ics
+func (h *MaintenanceHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	windows, err := h.store.List(maintenanceFilter(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := maintenance.WriteICS(&buf, h.calendarName, windows); err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="maintenance.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// CreateWindow handles POST /api/v1/maintenance
+func (h *MaintenanceHandler) CreateWindow(w http.ResponseWriter, r *http.Request) {
+	var win models.MaintenanceWindow
+	if err := json.NewDecoder(r.Body).Decode(&win); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !authorize(w, r, models.RoleClusterAdmin, win.Cluster) {
+		return
+	}
+	if win.Cluster != "" {
+		if _, ok := resolveCluster(w, h.registry, win.Cluster); !ok {
+			return
+		}
+	}
+
+	created, err := h.store.Create(win)
+	if err != nil {
+		h.respondMaintenanceError(w, err)
+		return
+	}
+	audit.Record(r, "maintenance.create", "maintenance:"+strconv.Itoa(created.ID), nil, created)
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+// GetWindow handles GET /api/v1/maintenance/{id}
+func (h *MaintenanceHandler) GetWindow(w http.ResponseWriter, r *http.Request) {
+	id, ok := windowID(w, r)
+	if !ok {
+		return
+	}
+
+	win, err := h.store.Get(id)
+	if err != nil {
+		h.respondMaintenanceError(w, err)
+		return
+	}
+	if !authorizeAffected(w, r, win.Cluster) {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, win)
+}
+
+// PutWindow handles PUT /api/v1/maintenance/{id}
+func (h *MaintenanceHandler) PutWindow(w http.ResponseWriter, r *http.Request) {
+	id, ok := windowID(w, r)
+	if !ok {
+		return
+	}
+	before, ok := h.authorizeWindow(w, r, id)
+	if !ok {
+		return
+	}
+
+	var win models.MaintenanceWindow
+	if err := json.NewDecoder(r.Body).Decode(&win); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !authorize(w, r, models.RoleClusterAdmin, win.Cluster) {
+		return
+	}
+	if win.Cluster != "" {
+		if _, ok := resolveCluster(w, h.registry, win.Cluster); !ok {
+			return
+		}
+	}
+
+	updated, err := h.store.Put(id, win)
+	if err != nil {
+		h.respondMaintenanceError(w, err)
+		return
+	}
+	audit.Record(r, "maintenance.update", "maintenance:"+strconv.Itoa(id), before, updated)
+
+	respondJSON(w, http.StatusOK, updated)
+}
+
+// DeleteWindow handles DELETE /api/v1/maintenance/{id}
+func (h *MaintenanceHandler) DeleteWindow(w http.ResponseWriter, r *http.Request) {
+	id, ok := windowID(w, r)
+	if !ok {
+		return
+	}
+	before, ok := h.authorizeWindow(w, r, id)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Delete(id); err != nil {
+		h.respondMaintenanceError(w, err)
+		return
+	}
+	audit.Record(r, "maintenance.delete", "maintenance:"+strconv.Itoa(id), before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeWindow returns an existing window, responding with 403 and
+// returning false unless the user of the request is a cluster admin of its
+// cluster
+func (h *MaintenanceHandler) authorizeWindow(w http.ResponseWriter, r *http.Request, id int) (*models.MaintenanceWindow, bool) {
+	win, err := h.store.Get(id)
+	if err != nil {
+		h.respondMaintenanceError(w, err)
+		return nil, false
+	}
+	return win, authorize(w, r, models.RoleClusterAdmin, win.Cluster)
+}
+
+// respondMaintenanceError maps maintenance window errors to HTTP responses
+func (h *MaintenanceHandler) respondMaintenanceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, maintenance.ErrWindowNotFound):
+		respondError(w, http.StatusNotFound, "Maintenance window not found", err)
+	case errors.Is(err, maintenance.ErrInvalidWindow):
+		respondError(w, http.StatusBadRequest, "Invalid maintenance window", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
+
+// maintenanceFilter reads the cluster and upcoming query parameters
+func maintenanceFilter(r *http.Request) maintenance.Filter {
+	upcoming, _ := strconv.ParseBool(r.URL.Query().Get("upcoming"))
+	return maintenance.Filter{
+		Cluster:  r.URL.Query().Get("cluster"),
+		Upcoming: upcoming,
+	}
+}
+
+// windowID parses the {id} URL parameter, writing a 400 response when it is
+// not a number
+func windowID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid maintenance window ID", err)
+		return 0, false
+	}
+	return id, true
+}
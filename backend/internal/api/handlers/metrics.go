@@ -3,20 +3,23 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
 	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
 )
 
 // MetricsHandler handles metrics API requests
 type MetricsHandler struct {
-	storage storage.Storage
+	storage  storage.Storage
+	registry *registry.Registry
 }
 
 // NewMetricsHandler creates a new metrics handler
-func NewMetricsHandler(storage storage.Storage) *MetricsHandler {
-	return &MetricsHandler{storage: storage}
+func NewMetricsHandler(storage storage.Storage, registry *registry.Registry) *MetricsHandler {
+	return &MetricsHandler{storage: storage, registry: registry}
 }
 
 // GetMetrics handles GET /api/metrics
@@ -31,16 +34,23 @@ func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 
 	switch metricType {
 	case "current":
-		response, err = h.getCurrentMetrics()
+		response, err = h.getCurrentMetrics(r)
 	case "load":
-		response, err = h.getMetricsByKey("load_average")
+		response, err = h.getMetricsByKey("load_average", r)
 	case "pbs":
-		response, err = h.getMetricsByKey("pbs_usage")
+		response, err = h.getMetricsByKey("pbs_usage", r)
 	case "cpu":
-		response, err = h.getMetricsByKey("cpu_usage")
+		response, err = h.getMetricsByKey("cpu_usage", r)
 	case "nodes":
+		// Node names are not tied to a cluster in the legacy data
+		if !authorize(w, r, models.RoleViewer, "") {
+			return
+		}
 		response, err = h.getNodeStatus()
 	case "all":
+		if !authorize(w, r, models.RoleAdmin, "") {
+			return
+		}
 		response, err = h.getAllMetrics()
 	default:
 		respondJSON(w, http.StatusBadRequest, map[string]string{
@@ -50,18 +60,15 @@ func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, map[string]string{
-			"error":   "Internal server error",
-			"message": err.Error(),
-		})
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
 		return
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
-// getCurrentMetrics returns current metrics
-func (h *MetricsHandler) getCurrentMetrics() (*models.CurrentMetrics, error) {
+// getCurrentMetrics returns current metrics of the clusters the user may see
+func (h *MetricsHandler) getCurrentMetrics(r *http.Request) (*models.CurrentMetrics, error) {
 	metadata, _ := h.storage.Get("metadata")
 	timestamp := time.Now().Unix()
 	if metadata != nil {
@@ -70,9 +77,9 @@ func (h *MetricsHandler) getCurrentMetrics() (*models.CurrentMetrics, error) {
 		}
 	}
 
-	loadAvg, _ := h.parseMetrics("load_average")
-	pbsUsage, _ := h.parseMetrics("pbs_usage")
-	cpuUsage, _ := h.parseMetrics("cpu_usage")
+	loadAvg, _ := h.parseMetrics("load_average", r)
+	pbsUsage, _ := h.parseMetrics("pbs_usage", r)
+	cpuUsage, _ := h.parseMetrics("cpu_usage", r)
 
 	hasData := len(loadAvg) > 0
 
@@ -85,8 +92,9 @@ func (h *MetricsHandler) getCurrentMetrics() (*models.CurrentMetrics, error) {
 	}, nil
 }
 
-// getMetricsByKey returns metrics for a specific key
-func (h *MetricsHandler) getMetricsByKey(key string) (interface{}, error) {
+// getMetricsByKey returns metrics for a specific key. Entries of clusters
+// the user may not see are left out.
+func (h *MetricsHandler) getMetricsByKey(key string, r *http.Request) (interface{}, error) {
 	data, err := h.storage.Get(key)
 	if err != nil || len(data) == 0 {
 		return map[string]interface{}{
@@ -96,7 +104,23 @@ func (h *MetricsHandler) getMetricsByKey(key string) (interface{}, error) {
 		}, nil
 	}
 
-	return data, nil
+	if canView(r, "") {
+		return data, nil
+	}
+	visible := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		visible[k] = v
+	}
+	entries := []interface{}{}
+	if arr, ok := data["data"].([]interface{}); ok {
+		for _, item := range arr {
+			if m, ok := item.(map[string]interface{}); ok && canView(r, getStringValue(m, "cluster")) {
+				entries = append(entries, item)
+			}
+		}
+	}
+	visible["data"] = entries
+	return visible, nil
 }
 
 // getNodeStatus returns node status information
@@ -125,6 +149,21 @@ func (h *MetricsHandler) getNodeStatus() (*models.NodeStatus, error) {
 	}, nil
 }
 
+// privateKeyPrefixes are the storage keys left out of type=all. They hold
+// password hashes, sessions, token hashes, the audit log and the digest
+// recipients, none of which is a metric.
+var privateKeyPrefixes = []string{"auth_", "audit_log_", "digest_subscriptions"}
+
+// isPrivateKey reports whether key is kept out of type=all
+func isPrivateKey(key string) bool {
+	for _, prefix := range privateKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // getAllMetrics returns all available metrics
 func (h *MetricsHandler) getAllMetrics() (map[string]interface{}, error) {
 	keys, err := h.storage.List()
@@ -134,6 +173,9 @@ func (h *MetricsHandler) getAllMetrics() (map[string]interface{}, error) {
 
 	result := make(map[string]interface{})
 	for _, key := range keys {
+		if isPrivateKey(key) {
+			continue
+		}
 		if data, err := h.storage.Get(key); err == nil {
 			result[key] = data
 		}
@@ -142,8 +184,9 @@ func (h *MetricsHandler) getAllMetrics() (map[string]interface{}, error) {
 	return result, nil
 }
 
-// parseMetrics converts storage data to ClusterMetric array
-func (h *MetricsHandler) parseMetrics(key string) ([]models.ClusterMetric, error) {
+// parseMetrics converts storage data to ClusterMetric array, keeping the
+// clusters the user may see
+func (h *MetricsHandler) parseMetrics(key string, r *http.Request) ([]models.ClusterMetric, error) {
 	data, err := h.storage.Get(key)
 	if err != nil {
 		return []models.ClusterMetric{}, nil
@@ -153,7 +196,7 @@ func (h *MetricsHandler) parseMetrics(key string) ([]models.ClusterMetric, error
 	if arr, ok := data["data"].([]interface{}); ok {
 		metrics := make([]models.ClusterMetric, 0, len(arr))
 		for _, item := range arr {
-			if m, ok := item.(map[string]interface{}); ok {
+			if m, ok := item.(map[string]interface{}); ok && canView(r, getStringValue(m, "cluster")) {
 				metric := models.ClusterMetric{
 					Cluster: getStringValue(m, "cluster"),
 					Value:   getFloatValue(m, "value"),
@@ -177,7 +220,10 @@ func (h *MetricsHandler) parseMetrics(key string) ([]models.ClusterMetric, error
 
 // generateDummyData creates dummy data for testing
 func (h *MetricsHandler) generateDummyData(key string) []map[string]interface{} {
-	clusters := []string{"cluster1", "cluster2", "cluster3"}
+	clusters, err := h.registry.Names()
+	if err != nil || len(clusters) == 0 {
+		clusters = []string{"cluster1", "cluster2", "cluster3"}
+	}
 	result := make([]map[string]interface{}, 0, len(clusters))
 
 	for _, cluster := range clusters {
@@ -212,6 +258,14 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
+func respondError(w http.ResponseWriter, status int, message string, err error) {
+	body := map[string]string{"error": message}
+	if err != nil {
+		body["message"] = err.Error()
+	}
+	respondJSON(w, status, body)
+}
+
 func extractStringArray(data map[string]interface{}) []string {
 	if data == nil {
 		return []string{}
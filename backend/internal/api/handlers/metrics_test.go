@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestGetAllMetricsLeavesOutPrivateKeys(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	keys := []string{
+		"load_average", "pbs_usage",
+		"auth_users", "auth_sessions", "auth_tokens",
+		"audit_log_2024-05-01", "digest_subscriptions",
+	}
+	for _, key := range keys {
+		if err := storage.SetData(store, key, []string{"x"}); err != nil {
+			t.Fatalf("SetData %s: %v", key, err)
+		}
+	}
+	h := NewMetricsHandler(store, registry.New(store, nil, nil))
+
+	rec := httptest.NewRecorder()
+	h.GetMetrics(rec, requestAs(http.MethodGet, "/api/metrics?type=all", models.RoleGrant{Role: models.RoleAdmin}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, key := range keys {
+		_, returned := got[key]
+		if want := !isPrivateKey(key); returned != want {
+			t.Errorf("key %s returned = %v, want %v", key, returned, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+)
+
+// NodeHandler handles node inventory API requests
+type NodeHandler struct {
+	registry  *registry.Registry
+	inventory *inventory.Inventory
+	loads     *collector.NodeLoadStore
+}
+
+// NewNodeHandler creates a new node handler
+func NewNodeHandler(registry *registry.Registry, inventory *inventory.Inventory, loads *collector.NodeLoadStore) *NodeHandler {
+	return &NodeHandler{registry: registry, inventory: inventory, loads: loads}
+}
+
+// GetClusterNodes handles GET /api/v1/clusters/{name}/nodes
+func (h *NodeHandler) GetClusterNodes(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := resolveCluster(w, h.registry, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	nodes, err := h.inventory.ByCluster(cluster.Name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster": cluster.Name,
+		"nodes":   nodes,
+		"total":   len(nodes),
+	})
+}
+
+// GetClusterNodeLoad handles GET /api/v1/clusters/{name}/nodes/load
+func (h *NodeHandler) GetClusterNodeLoad(w http.ResponseWriter, r *http.Request) {
+	cluster, ok := resolveCluster(w, h.registry, chi.URLParam(r, "name"))
+	if !ok {
+		return
+	}
+
+	loads, err := h.loads.Get(cluster.Name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster": cluster.Name,
+		"loads":   loads,
+		"total":   len(loads),
+	})
+}
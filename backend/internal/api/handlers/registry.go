@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+)
+
+// RegistryHandler handles cluster registry API requests
+type RegistryHandler struct {
+	registry *registry.Registry
+}
+
+// NewRegistryHandler creates a new registry handler
+func NewRegistryHandler(registry *registry.Registry) *RegistryHandler {
+	return &RegistryHandler{registry: registry}
+}
+
+// ListClusters handles GET /api/v1/clusters, listing the clusters the user
+// may see
+func (h *RegistryHandler) ListClusters(w http.ResponseWriter, r *http.Request) {
+	all, err := h.registry.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	clusters := make([]models.ClusterInfo, 0, len(all))
+	for _, c := range all {
+		if canView(r, c.Name) {
+			clusters = append(clusters, c)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"clusters": clusters,
+		"total":    len(clusters),
+	})
+}
+
+// GetCluster handles GET /api/v1/clusters/{name}
+func (h *RegistryHandler) GetCluster(w http.ResponseWriter, r *http.Request) {
+	cluster, err := h.registry.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		h.respondRegistryError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cluster)
+}
+
+// PutCluster handles PUT /api/v1/clusters/{name}
+func (h *RegistryHandler) PutCluster(w http.ResponseWriter, r *http.Request) {
+	var cluster models.ClusterInfo
+	if err := json.NewDecoder(r.Body).Decode(&cluster); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	cluster.Name = chi.URLParam(r, "name")
+
+	before, _ := h.registry.Get(cluster.Name)
+	saved, err := h.registry.Put(cluster)
+	if err != nil {
+		h.respondRegistryError(w, err)
+		return
+	}
+	audit.Record(r, changeAction("cluster", before != nil), "cluster:"+saved.Name, before, saved)
+
+	respondJSON(w, http.StatusOK, saved)
+}
+
+// DeleteCluster handles DELETE /api/v1/clusters/{name}
+func (h *RegistryHandler) DeleteCluster(w http.ResponseWriter, r *http.Request) {
+	before, err := h.registry.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		h.respondRegistryError(w, err)
+		return
+	}
+	if err := h.registry.Delete(before.Name); err != nil {
+		h.respondRegistryError(w, err)
+		return
+	}
+	audit.Record(r, "cluster.delete", "cluster:"+before.Name, before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SyncClusters handles POST /api/v1/clusters/sync
+func (h *RegistryHandler) SyncClusters(w http.ResponseWriter, r *http.Request) {
+	before, _ := h.registry.List()
+	clusters, err := h.registry.Sync(r.Context())
+	if err != nil && clusters == nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadGateway, "Cluster discovery failed", err)
+		return
+	}
+	audit.Record(r, "cluster.sync", "clusters", clusterNames(before), clusterNames(clusters))
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"clusters": clusters,
+		"total":    len(clusters),
+	})
+}
+
+// respondRegistryError maps registry errors to HTTP responses
+func (h *RegistryHandler) respondRegistryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, registry.ErrClusterNotFound):
+		respondError(w, http.StatusNotFound, "Cluster not found", err)
+	case errors.Is(err, registry.ErrInvalidCluster):
+		respondError(w, http.StatusBadRequest, "Invalid cluster", err)
+	case errors.Is(err, registry.ErrClusterNotManual):
+		respondError(w, http.StatusConflict, "Cluster cannot be deleted", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
+
+// clusterNames returns the names of clusters, for recording which clusters
+// a sync added or removed
+func clusterNames(clusters []models.ClusterInfo) map[string]interface{} {
+	names := make([]string, 0, len(clusters))
+	for _, c := range clusters {
+		names = append(names, c.Name)
+	}
+	return map[string]interface{}{"clusters": names}
+}
+
+// resolveCluster looks up a cluster in the registry and writes a 404 response
+// when the name is unknown
+func resolveCluster(w http.ResponseWriter, reg *registry.Registry, name string) (*models.ClusterInfo, bool) {
+	cluster, err := reg.Get(name)
+	if errors.Is(err, registry.ErrClusterNotFound) {
+		respondError(w, http.StatusNotFound, "Cluster not found", err)
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return nil, false
+	}
+	return cluster, true
+}
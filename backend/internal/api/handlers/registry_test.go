@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestListClustersVisibility(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	reg := registry.New(store, nil, nil)
+	for _, name := range []string{"asuka", "naruko"} {
+		if _, err := reg.Put(models.ClusterInfo{Name: name, Type: models.ClusterTypeCompute, MasterHost: name + "00"}); err != nil {
+			t.Fatalf("Put %s: %v", name, err)
+		}
+	}
+	h := NewRegistryHandler(reg)
+
+	tests := []struct {
+		name  string
+		grant models.RoleGrant
+		want  []string
+	}{
+		{"viewer of one cluster", models.RoleGrant{Role: models.RoleViewer, Cluster: "asuka"}, []string{"asuka"}},
+		{"viewer of every cluster", models.RoleGrant{Role: models.RoleViewer}, []string{"asuka", "naruko"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ListClusters(rec, requestAs(http.MethodGet, "/api/v1/clusters", tt.grant))
+			var body struct {
+				Clusters []models.ClusterInfo `json:"clusters"`
+				Total    int                  `json:"total"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			var got []string
+			for _, c := range body.Clusters {
+				got = append(got, c.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) || body.Total != len(tt.want) {
+				t.Errorf("clusters = %v (total %d), want %v", got, body.Total, tt.want)
+			}
+		})
+	}
+}
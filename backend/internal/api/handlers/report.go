@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/report"
+)
+
+// ReportHandler handles monthly report API requests
+type ReportHandler struct {
+	generator *report.Generator
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(generator *report.Generator) *ReportHandler {
+	return &ReportHandler{generator: generator}
+}
+
+// GetReport handles GET /api/v1/reports/{period}
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = report.FormatJSON
+	}
+
+	rep, err := h.generator.Generate(chi.URLParam(r, "period"))
+	if err != nil {
+		if errors.Is(err, report.ErrInvalidPeriod) {
+			respondError(w, http.StatusBadRequest, "Invalid period", err)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case report.FormatJSON:
+		respondJSON(w, http.StatusOK, rep)
+		return
+	case report.FormatHTML:
+		err = report.RenderHTML(&buf, rep)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	case report.FormatMarkdown:
+		err = report.RenderMarkdown(&buf, rep)
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	default:
+		respondJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Invalid report format",
+		})
+		return
+	}
+
+	if err != nil {
+		w.Header().Del("Content-Type")
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
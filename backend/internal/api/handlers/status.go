@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/statuspage"
+)
+
+// StatusHandler serves the public status page and its announcements
+type StatusHandler struct {
+	page          *statuspage.Page
+	announcements *statuspage.Announcements
+	baseURL       string
+}
+
+// NewStatusHandler creates a new status page handler. baseURL is the public
+// URL used in feed links; when empty it is taken from the request.
+func NewStatusHandler(page *statuspage.Page, announcements *statuspage.Announcements, baseURL string) *StatusHandler {
+	return &StatusHandler{page: page, announcements: announcements, baseURL: baseURL}
+}
+
+// GetStatusPage handles GET /status
+func (h *StatusHandler) GetStatusPage(w http.ResponseWriter, r *http.Request) {
+	page, err := h.page.Build()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := statuspage.RenderHTML(&buf, page); err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// GetStatus handles GET /status.json
+func (h *StatusHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	page, err := h.page.Build()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, page)
+}
+
+// GetStatusFeed handles GET /status/feed.atom
+func (h *StatusHandler) GetStatusFeed(w http.ResponseWriter, r *http.Request) {
+	baseURL := h.baseURL
+	if baseURL == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		} else if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		baseURL = scheme + "://" + r.Host
+	}
+
+	var buf bytes.Buffer
+	if err := h.page.WriteAtom(&buf, baseURL); err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// ListAnnouncements handles GET /api/v1/announcements
+func (h *StatusHandler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	announcements, err := h.announcements.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"announcements": announcements,
+		"total":         len(announcements),
+	})
+}
+
+// CreateAnnouncement handles POST /api/v1/announcements
+func (h *StatusHandler) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var ann models.Announcement
+	if err := json.NewDecoder(r.Body).Decode(&ann); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	created, err := h.announcements.Create(ann)
+	if err != nil {
+		h.respondAnnouncementError(w, err)
+		return
+	}
+	audit.Record(r, "announcement.create", "announcement:"+strconv.Itoa(created.ID), nil, created)
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+// GetAnnouncement handles GET /api/v1/announcements/{id}
+func (h *StatusHandler) GetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id, ok := announcementID(w, r)
+	if !ok {
+		return
+	}
+
+	ann, err := h.announcements.Get(id)
+	if err != nil {
+		h.respondAnnouncementError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ann)
+}
+
+// PutAnnouncement handles PUT /api/v1/announcements/{id}
+func (h *StatusHandler) PutAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id, ok := announcementID(w, r)
+	if !ok {
+		return
+	}
+
+	var ann models.Announcement
+	if err := json.NewDecoder(r.Body).Decode(&ann); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	before, err := h.announcements.Get(id)
+	if err != nil {
+		h.respondAnnouncementError(w, err)
+		return
+	}
+	updated, err := h.announcements.Put(id, ann)
+	if err != nil {
+		h.respondAnnouncementError(w, err)
+		return
+	}
+	audit.Record(r, "announcement.update", "announcement:"+strconv.Itoa(id), before, updated)
+
+	respondJSON(w, http.StatusOK, updated)
+}
+
+// DeleteAnnouncement handles DELETE /api/v1/announcements/{id}
+func (h *StatusHandler) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id, ok := announcementID(w, r)
+	if !ok {
+		return
+	}
+
+	before, err := h.announcements.Get(id)
+	if err != nil {
+		h.respondAnnouncementError(w, err)
+		return
+	}
+	if err := h.announcements.Delete(id); err != nil {
+		h.respondAnnouncementError(w, err)
+		return
+	}
+	audit.Record(r, "announcement.delete", "announcement:"+strconv.Itoa(id), before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondAnnouncementError maps announcement errors to HTTP responses
+func (h *StatusHandler) respondAnnouncementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, statuspage.ErrAnnouncementNotFound):
+		respondError(w, http.StatusNotFound, "Announcement not found", err)
+	case errors.Is(err, statuspage.ErrInvalidAnnouncement):
+		respondError(w, http.StatusBadRequest, "Invalid announcement", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
+
+// announcementID parses the {id} URL parameter, writing a 400 response when
+// it is not a number
+func announcementID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid announcement ID", err)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// tokenRequest is the body of POST /api/v1/auth/tokens
+type tokenRequest struct {
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// createdToken is a new token with its secret, which is only shown once
+type createdToken struct {
+	models.APIToken
+	Token string `json:"token"`
+}
+
+// TokenHandler handles API token requests
+type TokenHandler struct {
+	tokens *auth.Tokens
+}
+
+// NewTokenHandler creates a new API token handler
+func NewTokenHandler(tokens *auth.Tokens) *TokenHandler {
+	return &TokenHandler{tokens: tokens}
+}
+
+// ListTokens handles GET /api/v1/auth/tokens. Admins see every token with
+// ?all=true; everyone else sees their own.
+func (h *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	owner := currentUsername(r)
+	if all, _ := strconv.ParseBool(r.URL.Query().Get("all")); all {
+		if !authorize(w, r, models.RoleAdmin, "") {
+			return
+		}
+		owner = ""
+	}
+
+	tokens, err := h.tokens.List(owner)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"tokens": tokens,
+		"total":  len(tokens),
+	})
+}
+
+// CreateToken handles POST /api/v1/auth/tokens
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req tokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = models.TokenKindPersonal
+	}
+	// Service tokens do not act as anyone, so only admins may create them
+	if req.Kind == models.TokenKindService && !authorize(w, r, models.RoleAdmin, "") {
+		return
+	}
+	// Personal tokens act as their owner, so they cannot hold scopes beyond
+	// the owner's roles
+	if req.Kind == models.TokenKindPersonal {
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			if allowed, message := auth.TokenScopesAllowed(user, req.Scopes); !allowed {
+				auth.Forbidden(w, message)
+				return
+			}
+		}
+	}
+
+	token, raw, err := h.tokens.Create(models.APIToken{
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Owner:     currentUsername(r),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		h.respondTokenError(w, err)
+		return
+	}
+	audit.Record(r, "token.create", "token:"+token.ID, nil, token)
+
+	respondJSON(w, http.StatusCreated, createdToken{APIToken: *token, Token: raw})
+}
+
+// RevokeToken handles DELETE /api/v1/auth/tokens/{id}
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.tokens.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondTokenError(w, err)
+		return
+	}
+	if token.Owner != currentUsername(r) && !authorize(w, r, models.RoleAdmin, "") {
+		return
+	}
+
+	revoked, err := h.tokens.Revoke(token.ID)
+	if err != nil {
+		h.respondTokenError(w, err)
+		return
+	}
+	audit.Record(r, "token.revoke", "token:"+token.ID, token, revoked)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondTokenError maps API token errors to HTTP responses
+func (h *TokenHandler) respondTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrTokenNotFound):
+		respondError(w, http.StatusNotFound, "API token not found", err)
+	case errors.Is(err, auth.ErrInvalidToken):
+		respondError(w, http.StatusBadRequest, "Invalid API token", err)
+	default:
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
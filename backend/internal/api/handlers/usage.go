@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/usage"
+)
+
+// UsageHandler handles per-user usage API requests
+type UsageHandler struct {
+	registry *registry.Registry
+	usage    *usage.Store
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(reg *registry.Registry, usage *usage.Store) *UsageHandler {
+	return &UsageHandler{registry: reg, usage: usage}
+}
+
+// GetUserLeaderboard handles GET /api/v1/usage/users
+func (h *UsageHandler) GetUserLeaderboard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cluster := query.Get("cluster")
+	if cluster != "" {
+		if !authorize(w, r, models.RoleViewer, cluster) {
+			return
+		}
+		if _, ok := resolveCluster(w, h.registry, cluster); !ok {
+			return
+		}
+	}
+
+	window, err := parseWindow(query.Get("window"), 7*24*time.Hour)
+	if err != nil || window <= 0 {
+		if err == nil {
+			err = errors.New("window must be positive")
+		}
+		respondError(w, http.StatusBadRequest, "Invalid window parameter", err)
+		return
+	}
+	top, err := parseIntParam(query.Get("top"), 0)
+	if err != nil || top < 0 {
+		respondError(w, http.StatusBadRequest, "Invalid top parameter", err)
+		return
+	}
+
+	users, err := h.usage.Leaderboard(cluster, window)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	if !seesAllUsers(r, cluster) {
+		own := []models.UserUsageSummary{}
+		for _, u := range users {
+			if u.Username == currentUsername(r) {
+				own = append(own, u)
+			}
+		}
+		users = own
+	}
+	total := len(users)
+	if top > 0 && top < total {
+		users = users[:top]
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster": cluster,
+		"window":  window.String(),
+		"users":   users,
+		"total":   total,
+	})
+}
@@ -6,50 +6,288 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
+	"github.com/taisei-ito/cluster-status-monitor/internal/accounting"
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/anomaly"
 	"github.com/taisei-ito/cluster-status-monitor/internal/api/handlers"
+	"github.com/taisei-ito/cluster-status-monitor/internal/audit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/availability"
+	"github.com/taisei-ito/cluster-status-monitor/internal/chargeback"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/digest"
+	"github.com/taisei-ito/cluster-status-monitor/internal/forecast"
+	"github.com/taisei-ito/cluster-status-monitor/internal/incident"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/jobs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/maintenance"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/ratelimit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/reload"
+	"github.com/taisei-ito/cluster-status-monitor/internal/report"
+	"github.com/taisei-ito/cluster-status-monitor/internal/statuspage"
 	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+	"github.com/taisei-ito/cluster-status-monitor/internal/usage"
 )
 
+// Dependencies holds the services used by the API handlers
+type Dependencies struct {
+	Storage       storage.Storage
+	Registry      *registry.Registry
+	Inventory     *inventory.Inventory
+	DiskUsers     *collector.DiskUserStore
+	Filesystems   *collector.FilesystemStore
+	Accounting    *accounting.Store
+	Forecaster    *forecast.Forecaster
+	Alerts        *alert.Engine
+	NodeLoads     *collector.NodeLoadStore
+	Anomalies     *anomaly.Detector
+	Jobs          *jobs.Tracker
+	Reports       *report.Generator
+	Digest        *digest.Scheduler
+	Subscribers   *digest.Subscriptions
+	Usage         *usage.Store
+	Groups        *chargeback.Groups
+	Billing       *chargeback.Biller
+	Availability  *availability.Calculator
+	Incidents     *incident.Store
+	StatusPage    *statuspage.Page
+	Announcements *statuspage.Announcements
+	StatusURL     string
+	Maintenance   *maintenance.Store
+	Users         *auth.Users
+	Sessions      *auth.Sessions
+	Login         *auth.Chain
+	OIDC          *auth.OIDC // nil disables OIDC login
+	Tokens        *auth.Tokens
+	Auth          *auth.Middleware // nil disables authentication
+	CertsRequired bool             // Ingest requests need a verified TLS client certificate
+	GlobalCerts   []string         // Client certificate names allowed to push the global ingest keys
+	Audit         *audit.Log
+	CORS          *CORS // Cross-origin access to /api
+	PublicCORS    *CORS // Cross-origin access to the health check and status page
+	Headers       *Headers
+	Proxies       *auth.TrustedProxies // Reverse proxies whose forwarded client address is used
+	Limits        RateLimiters
+	Reloader      *reload.Reloader
+}
+
+// RateLimiters limit the request rate of each route group
+type RateLimiters struct {
+	IP     *ratelimit.Limiter // Every /api route, before authentication
+	API    *ratelimit.Limiter // Authenticated /api routes
+	Legacy *ratelimit.Limiter // The legacy metrics and cluster dumps, on top of API
+	Login  *ratelimit.Limiter
+	Ingest *ratelimit.Limiter
+	Public *ratelimit.Limiter // Health check and status page
+}
+
 // NewRouter creates and configures the API router
-func NewRouter(storage storage.Storage) *chi.Mux {
+func NewRouter(deps Dependencies) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(deps.Proxies.Handler)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(deps.Audit.Handler)
+	r.Use(deps.Headers.handler)
+
+	statusHandler := handlers.NewStatusHandler(deps.StatusPage, deps.Announcements, deps.StatusURL)
+	maintenanceHandler := handlers.NewMaintenanceHandler(deps.Registry, deps.Maintenance, deps.StatusPage.Title+" maintenance")
+	r.Group(func(r chi.Router) {
+		// Public data may be read from other sites, such as a department
+		// portal embedding the status page
+		r.Use(deps.PublicCORS.handler)
+		r.Use(deps.Limits.Public.Handler)
 
-	// CORS configuration
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300,
-	}))
-
-	// Health check
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
+		// Health check
+		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status":"ok"}`))
+		})
+
+		// Public status page
+		r.Get("/status", statusHandler.GetStatusPage)
+		r.Get("/status.json", statusHandler.GetStatus)
+		r.Get("/status/feed.atom", statusHandler.GetStatusFeed)
+		r.Get("/status/maintenance.ics", maintenanceHandler.GetCalendar)
 	})
 
 	// API routes
 	r.Route("/api", func(r chi.Router) {
-		// Metrics endpoints
-		metricsHandler := handlers.NewMetricsHandler(storage)
-		r.Get("/metrics", metricsHandler.GetMetrics)
-		r.Get("/metrics.php", metricsHandler.GetMetrics) // PHP compatibility
-
-		// Cluster endpoints
-		clusterHandler := handlers.NewClusterHandler(storage)
-		r.Get("/cluster", clusterHandler.GetClusterInfo)
-		r.Get("/cluster.php", clusterHandler.GetClusterInfo) // PHP compatibility
+		r.Use(deps.CORS.handler)
+		r.Use(deps.CORS.checkOrigin)
+		// Requests failing authentication count against their IP too
+		r.Use(deps.Limits.IP.Handler)
+
+		// Login and logout are open; every other API route needs a session
+		authHandler := handlers.NewAuthHandler(deps.Users, deps.Sessions, deps.Login, deps.OIDC)
+		loginLimit := deps.Limits.Login.Handler
+		r.With(loginLimit).Post("/v1/auth/login", authHandler.Login)
+		r.With(loginLimit).Post("/v1/auth/logout", authHandler.Logout)
+		r.With(loginLimit).Get("/v1/auth/oidc/login", authHandler.StartOIDCLogin)
+		r.With(loginLimit).Get("/v1/auth/oidc/callback", authHandler.FinishOIDCLogin)
+
+		// Without authentication every request runs as the anonymous admin
+		authenticate := auth.AllowAnonymous
+		if deps.Auth != nil {
+			authenticate = deps.Auth.Handler
+		}
+
+		// Signed-in users may look themselves up without holding a role
+		r.With(authenticate, deps.Limits.API.Handler).Get("/v1/auth/me", authHandler.GetCurrentUser)
+
+		// Collectors push data with tokens holding the ingest scope or with
+		// client certificates
+		ingestHandler := handlers.NewIngestHandler(deps.Storage, deps.Registry, deps.GlobalCerts)
+		ingestAuth := auth.ClientCertificate(deps.CertsRequired, authenticate)
+		r.With(ingestAuth, deps.Limits.Ingest.Handler, auth.RequireScope(models.ScopeIngest, models.RoleClusterAdmin)).Put("/v1/ingest/{key}", ingestHandler.PutData)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate)
+			r.Use(deps.Limits.API.Handler)
+			r.Use(auth.EnforceScopes)
+			r.Use(auth.RequireAnyRole(models.RoleViewer))
+			admin := auth.RequireRole(models.RoleAdmin)
+			globalClusterAdmin := auth.RequireRole(models.RoleClusterAdmin)
+			clusterAdmin := auth.RequireAnyRole(models.RoleClusterAdmin)
+			clusterViewer := auth.RequireClusterRole(models.RoleViewer, "name")
+
+			// Metrics endpoints
+			// These read whole storage documents, so they get a lower limit
+			legacyLimit := deps.Limits.Legacy.Handler
+			metricsHandler := handlers.NewMetricsHandler(deps.Storage, deps.Registry)
+			r.With(legacyLimit).Get("/metrics", metricsHandler.GetMetrics)
+			r.With(legacyLimit).Get("/metrics.php", metricsHandler.GetMetrics) // PHP compatibility
+
+			// Cluster endpoints
+			clusterHandler := handlers.NewClusterHandler(deps.Storage, deps.Registry, deps.Filesystems, deps.Usage)
+			r.With(legacyLimit).Get("/cluster", clusterHandler.GetClusterInfo)
+			r.With(legacyLimit).Get("/cluster.php", clusterHandler.GetClusterInfo) // PHP compatibility
+
+			r.Route("/v1", func(r chi.Router) {
+				// Authentication endpoints
+				r.With(admin).Get("/auth/users", authHandler.ListUsers)
+				r.With(admin).Put("/auth/users/{username}", authHandler.PutUser)
+				r.With(admin).Delete("/auth/users/{username}", authHandler.DeleteUser)
+
+				// Audit log endpoints
+				auditHandler := handlers.NewAuditHandler(deps.Audit)
+				r.With(admin).Get("/audit", auditHandler.GetAuditLog)
+
+				// Rate limiter counters
+				r.With(admin).Get("/debug/vars", ratelimit.Counters)
+
+				// Configuration reload endpoints
+				configHandler := handlers.NewConfigHandler(deps.Reloader)
+				r.With(admin).Post("/config/reload", configHandler.ReloadConfig)
+				r.With(admin).Get("/config/reloads", configHandler.ListReloads)
+
+				// API token endpoints
+				tokenHandler := handlers.NewTokenHandler(deps.Tokens)
+				r.Get("/auth/tokens", tokenHandler.ListTokens)
+				r.Post("/auth/tokens", tokenHandler.CreateToken)
+				r.Delete("/auth/tokens/{id}", tokenHandler.RevokeToken)
+
+				// Cluster registry endpoints
+				registryHandler := handlers.NewRegistryHandler(deps.Registry)
+				r.Get("/clusters", registryHandler.ListClusters)
+				r.With(admin).Post("/clusters/sync", registryHandler.SyncClusters)
+				r.With(clusterViewer).Get("/clusters/{name}", registryHandler.GetCluster)
+				r.With(admin).Put("/clusters/{name}", registryHandler.PutCluster)
+				r.With(admin).Delete("/clusters/{name}", registryHandler.DeleteCluster)
+
+				// Node inventory endpoints
+				nodeHandler := handlers.NewNodeHandler(deps.Registry, deps.Inventory, deps.NodeLoads)
+				r.With(clusterViewer).Get("/clusters/{name}/nodes", nodeHandler.GetClusterNodes)
+				r.With(clusterViewer).Get("/clusters/{name}/nodes/load", nodeHandler.GetClusterNodeLoad)
+
+				// Node availability endpoints
+				availabilityHandler := handlers.NewAvailabilityHandler(deps.Registry, deps.Availability)
+				r.With(clusterViewer).Get("/clusters/{name}/availability", availabilityHandler.GetClusterAvailability)
+				r.Get("/availability", availabilityHandler.GetAvailability)
+				r.Get("/availability/flaky", availabilityHandler.GetFlakyNodes)
+
+				// Disk usage endpoints
+				diskHandler := handlers.NewDiskHandler(deps.Registry, deps.DiskUsers, deps.Filesystems, deps.Forecaster)
+				r.With(clusterViewer).Get("/clusters/{name}/disk", diskHandler.GetDisk)
+				r.With(clusterViewer).Get("/clusters/{name}/disk/users", diskHandler.GetDiskUsers)
+				r.With(clusterViewer).Get("/clusters/{name}/filesystems", diskHandler.GetFilesystems)
+				r.With(clusterViewer).Get("/clusters/{name}/filesystems/forecast", diskHandler.GetForecast)
+
+				// CPU accounting endpoints
+				accountingHandler := handlers.NewAccountingHandler(deps.Accounting)
+				r.Get("/accounting/users", accountingHandler.GetUsers)
+				r.Get("/accounting/users/{user}", accountingHandler.GetUser)
+
+				// PBS job endpoints
+				jobHandler := handlers.NewJobHandler(deps.Jobs)
+				r.Get("/jobs", jobHandler.GetJobs)
+
+				// Per-user usage endpoints
+				usageHandler := handlers.NewUsageHandler(deps.Registry, deps.Usage)
+				r.Get("/usage/users", usageHandler.GetUserLeaderboard)
+
+				// Chargeback endpoints
+				chargebackHandler := handlers.NewChargebackHandler(deps.Groups, deps.Billing)
+				r.With(globalClusterAdmin).Get("/chargeback/groups", chargebackHandler.ListGroups)
+				r.With(admin).Post("/chargeback/groups/import", chargebackHandler.ImportGroups)
+				r.With(globalClusterAdmin).Get("/chargeback/groups/{group}", chargebackHandler.GetGroup)
+				r.With(admin).Put("/chargeback/groups/{group}", chargebackHandler.PutGroup)
+				r.With(admin).Delete("/chargeback/groups/{group}", chargebackHandler.DeleteGroup)
+				r.Get("/chargeback/rates", chargebackHandler.GetRates)
+				r.With(globalClusterAdmin).Get("/chargeback/invoices", chargebackHandler.GetInvoices)
+				r.With(globalClusterAdmin).Get("/chargeback/invoices/{group}", chargebackHandler.GetInvoice)
+
+				// Monthly report endpoints
+				reportHandler := handlers.NewReportHandler(deps.Reports)
+				r.With(globalClusterAdmin).Get("/reports/{period}", reportHandler.GetReport)
+
+				// Weekly digest endpoints
+				digestHandler := handlers.NewDigestHandler(deps.Digest, deps.Subscribers)
+				r.With(admin).Get("/digest/subscriptions", digestHandler.ListSubscriptions)
+				r.With(admin).Put("/digest/subscriptions/{recipient}", digestHandler.PutSubscription)
+				r.With(admin).Delete("/digest/subscriptions/{recipient}", digestHandler.DeleteSubscription)
+				r.With(admin).Get("/digest/preview", digestHandler.GetPreview)
+				r.With(admin).Post("/digest/send", digestHandler.SendDigest)
+
+				// Alert endpoints
+				alertHandler := handlers.NewAlertHandler(deps.Alerts)
+				r.Get("/alerts", alertHandler.GetAlerts)
+
+				// Incident endpoints
+				incidentHandler := handlers.NewIncidentHandler(deps.Incidents, deps.Alerts)
+				r.Get("/incidents", incidentHandler.ListIncidents)
+				r.With(clusterAdmin).Post("/incidents", incidentHandler.CreateIncident)
+				r.Get("/incidents/{id}", incidentHandler.GetIncident)
+				r.With(clusterAdmin).Put("/incidents/{id}", incidentHandler.PutIncident)
+				r.With(clusterAdmin).Delete("/incidents/{id}", incidentHandler.DeleteIncident)
+				r.With(clusterAdmin).Post("/incidents/{id}/updates", incidentHandler.AddIncidentUpdate)
+
+				// Status page announcement endpoints
+				r.Get("/announcements", statusHandler.ListAnnouncements)
+				r.With(admin).Post("/announcements", statusHandler.CreateAnnouncement)
+				r.Get("/announcements/{id}", statusHandler.GetAnnouncement)
+				r.With(admin).Put("/announcements/{id}", statusHandler.PutAnnouncement)
+				r.With(admin).Delete("/announcements/{id}", statusHandler.DeleteAnnouncement)
+
+				// Maintenance window endpoints
+				r.Get("/maintenance", maintenanceHandler.ListWindows)
+				r.With(clusterAdmin).Post("/maintenance", maintenanceHandler.CreateWindow)
+				r.Get("/maintenance/{id}", maintenanceHandler.GetWindow)
+				r.With(clusterAdmin).Put("/maintenance/{id}", maintenanceHandler.PutWindow)
+				r.With(clusterAdmin).Delete("/maintenance/{id}", maintenanceHandler.DeleteWindow)
+
+				// Anomaly endpoints
+				anomalyHandler := handlers.NewAnomalyHandler(deps.Anomalies)
+				r.Get("/anomalies", anomalyHandler.GetAnomalies)
+			})
+		})
 	})
 
 	return r
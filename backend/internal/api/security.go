@@ -0,0 +1,181 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/cors"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+)
+
+// CORSPolicy is the cross-origin access allowed to a group of routes
+type CORSPolicy struct {
+	Origins     []string // "*", scheme://host[:port] or a pattern with one "*"; empty allows no other origin
+	Methods     []string
+	Credentials bool // Let browsers send cookies with cross-origin requests
+	MaxAge      time.Duration
+}
+
+// CORS applies a CORS policy to a group of routes. The policy can be
+// replaced while the server runs; requests already being served finish
+// under the previous one.
+type CORS struct {
+	current atomic.Pointer[corsState]
+}
+
+// corsState is a policy and the cors handler built from it
+type corsState struct {
+	policy CORSPolicy
+	cors   *cors.Cors // nil when the policy allows no other origin
+}
+
+// NewCORS creates the CORS handling of a group of routes
+func NewCORS(policy CORSPolicy) *CORS {
+	c := &CORS{}
+	c.Set(policy)
+	return c
+}
+
+// Set replaces the policy
+func (c *CORS) Set(p CORSPolicy) {
+	state := &corsState{policy: p}
+	// The cors package allows every origin when none are given
+	if len(p.Origins) > 0 {
+		state.cors = cors.New(cors.Options{
+			AllowedOrigins:   p.Origins,
+			AllowedMethods:   p.Methods,
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Request-Id"},
+			ExposedHeaders:   []string{"Link", "Retry-After"},
+			AllowCredentials: p.Credentials,
+			MaxAge:           int(p.MaxAge.Seconds()),
+		})
+	}
+	c.current.Store(state)
+}
+
+// Policy returns the current policy
+func (c *CORS) Policy() CORSPolicy {
+	return c.current.Load().policy
+}
+
+// handler answers preflight requests and adds the CORS headers of the policy
+func (c *CORS) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := c.current.Load()
+		if state.cors == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		state.cors.Handler(next).ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin rejects state-changing requests sent by browsers from origins
+// other than the server's own and those of the policy. CORS only keeps other
+// sites from reading responses; without this check a page elsewhere could
+// still make a signed-in browser submit forms to the API.
+func (c *CORS) checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Clients other than browsers do not send an Origin header
+		origin := r.Header.Get("Origin")
+		if origin == "" || c.Policy().allows(origin, r.Host) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "Forbidden",
+			"message": "origin " + origin + " is not allowed",
+		})
+	})
+}
+
+// allows reports whether a state-changing request from origin to host is
+// allowed. "*" lets any site read responses but is not taken to allow
+// changes, which would let every site submit forms with the session cookie.
+func (p CORSPolicy) allows(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.Origins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" {
+			continue
+		}
+		if allowed == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+			len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecurityHeaders are the security headers added to every response, so the
+// server is safe to expose without a proxy adding them
+type SecurityHeaders struct {
+	ContentSecurityPolicy string        // Empty omits the header
+	FrameOptions          string        // X-Frame-Options; empty omits the header
+	HSTSMaxAge            time.Duration // Strict-Transport-Security, sent over HTTPS only, directly or through a trusted proxy; 0 omits it
+}
+
+// Headers adds security headers to every response. Like CORS, the headers
+// can be replaced while the server runs.
+type Headers struct {
+	current atomic.Pointer[SecurityHeaders]
+}
+
+// NewHeaders creates the security header handling
+func NewHeaders(headers SecurityHeaders) *Headers {
+	h := &Headers{}
+	h.Set(headers)
+	return h
+}
+
+// Set replaces the headers
+func (h *Headers) Set(headers SecurityHeaders) {
+	h.current.Store(&headers)
+}
+
+// handler adds the headers to responses
+func (h *Headers) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := h.current.Load()
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "no-referrer")
+		if s.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", s.ContentSecurityPolicy)
+		}
+		if s.FrameOptions != "" {
+			header.Set("X-Frame-Options", s.FrameOptions)
+		}
+		// Browsers ignore HSTS received over plain HTTP. The scheme a proxy
+		// forwarded is only believed from a trusted proxy.
+		https := r.TLS != nil || (auth.ViaTrustedProxy(r) && r.Header.Get("X-Forwarded-Proto") == "https")
+		if s.HSTSMaxAge > 0 && https {
+			header.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(s.HSTSMaxAge.Seconds())))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+)
+
+func TestHeadersHSTS(t *testing.T) {
+	proxies := auth.NewTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	headers := NewHeaders(SecurityHeaders{HSTSMaxAge: 24 * time.Hour})
+	handler := proxies.Handler(headers.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		name   string
+		remote string
+		tls    bool
+		proto  string
+		want   string
+	}{
+		{"plain HTTP", "192.0.2.7:5000", false, "", ""},
+		{"native HTTPS", "192.0.2.7:5000", true, "", "max-age=86400"},
+		{"HTTPS through a trusted proxy", "10.0.0.2:5000", false, "https", "max-age=86400"},
+		{"HTTP through a trusted proxy", "10.0.0.2:5000", false, "http", ""},
+		{"forwarded scheme from a client", "192.0.2.7:5000", false, "https", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/health", nil)
+			r.RemoteAddr = tt.remote
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		method  string
+		origin  string
+		want    int
+	}{
+		{"no Origin header", nil, http.MethodPost, "", http.StatusOK},
+		{"own origin", nil, http.MethodPost, "http://monitor.example.org", http.StatusOK},
+		{"other origin", nil, http.MethodPost, "https://evil.example.com", http.StatusForbidden},
+		{"listed origin", []string{"https://portal.example.org"}, http.MethodPut, "https://portal.example.org", http.StatusOK},
+		{"pattern", []string{"https://*.example.org"}, http.MethodDelete, "https://portal.example.org", http.StatusOK},
+		{"pattern of another site", []string{"https://*.example.org"}, http.MethodDelete, "https://example.com", http.StatusForbidden},
+		{"wildcard does not allow changes", []string{"*"}, http.MethodPost, "https://evil.example.com", http.StatusForbidden},
+		{"wildcard reads", []string{"*"}, http.MethodGet, "https://evil.example.com", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCORS(CORSPolicy{Origins: tt.origins})
+			r := httptest.NewRequest(tt.method, "http://monitor.example.org/api/v1/incidents", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			c.checkOrigin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// keyPrefix starts the storage keys of the log, which is kept in one
+// document per UTC day so that appending does not rewrite the whole history
+const keyPrefix = "audit_log_"
+
+// redacted replaces the values of secret fields in diffs
+const redacted = "[redacted]"
+
+// ErrInvalidFilter is returned for queries that fail validation
+var ErrInvalidFilter = errors.New("invalid audit filter")
+
+// day is the persisted log of one UTC day
+type day struct {
+	NextID  int                 `json:"next_id"`
+	Entries []models.AuditEntry `json:"entries"`
+}
+
+// Filter selects audit entries; empty fields match every entry
+type Filter struct {
+	Actor  string
+	Action string // Matches the action or, without a dot, every action on that kind of target
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// contextKey is the type of the request context keys of this package
+type contextKey int
+
+const logKey contextKey = iota
+
+// Log is the append-only audit log of administrative and data-changing API
+// requests. Entries are never modified or deleted through the API.
+type Log struct {
+	storage      storage.Storage
+	DefaultRange time.Duration // Queried when a filter has no start
+	MaxRange     time.Duration // Longest time range a query may cover
+	MaxLimit     int
+	Redact       []string // Fields whose values are never written to diffs
+	mu           sync.Mutex
+}
+
+// NewLog creates an audit log
+func NewLog(store storage.Storage) *Log {
+	return &Log{
+		storage:      store,
+		DefaultRange: 7 * 24 * time.Hour,
+		MaxRange:     366 * 24 * time.Hour,
+		MaxLimit:     1000,
+		Redact:       []string{"password", "password_hash", "hash", "secret", "client_secret", "token"},
+	}
+}
+
+// Append adds an entry to the log, setting its ID and, when zero, its time
+func (l *Log) Append(e models.AuditEntry) (*models.AuditEntry, error) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	e.At = e.At.UTC()
+	key := dayKey(e.At)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var d day
+	if err := storage.GetData(l.storage, key, &d); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	d.NextID++
+	e.ID = fmt.Sprintf("%s-%d", e.At.Format("20060102"), d.NextID)
+	d.Entries = append(d.Entries, e)
+
+	if err := storage.SetData(l.storage, key, d); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Query returns the entries matching the filter, newest first
+func (l *Log) Query(f Filter) ([]models.AuditEntry, error) {
+	if f.Until.IsZero() {
+		f.Until = time.Now()
+	}
+	if f.Since.IsZero() {
+		f.Since = f.Until.Add(-l.DefaultRange)
+	}
+	if f.Until.Before(f.Since) {
+		return nil, fmt.Errorf("%w: until is before since", ErrInvalidFilter)
+	}
+	if f.Until.Sub(f.Since) > l.MaxRange {
+		return nil, fmt.Errorf("%w: time range is longer than %s", ErrInvalidFilter, l.MaxRange)
+	}
+	if f.Limit <= 0 || f.Limit > l.MaxLimit {
+		f.Limit = l.MaxLimit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := []models.AuditEntry{}
+	first := truncateDay(f.Since.UTC())
+	for t := truncateDay(f.Until.UTC()); !t.Before(first); t = t.AddDate(0, 0, -1) {
+		var d day
+		if err := storage.GetData(l.storage, dayKey(t), &d); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		for i := len(d.Entries) - 1; i >= 0; i-- {
+			e := d.Entries[i]
+			if e.At.Before(f.Since) || e.At.After(f.Until) {
+				continue
+			}
+			if f.Actor != "" && e.Actor != f.Actor {
+				continue
+			}
+			if f.Action != "" && e.Action != f.Action && !strings.HasPrefix(e.Action, f.Action+".") {
+				continue
+			}
+			result = append(result, e)
+			if len(result) == f.Limit {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// Handler makes the log available to Record for the requests it serves
+func (l *Log) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), logKey, l)))
+	})
+}
+
+// Record logs a change made by a request. before and after are the target
+// as it was and as it is now; before is nil for created targets and after
+// for deleted ones. The change has already been made, so a failure to write
+// the log is only logged.
+func Record(r *http.Request, action, target string, before, after interface{}) {
+	l, ok := r.Context().Value(logKey).(*Log)
+	if !ok {
+		return
+	}
+
+	e := models.AuditEntry{
+		Action:    action,
+		Target:    target,
+		RequestID: middleware.GetReqID(r.Context()),
+		IP:        auth.ClientIP(r),
+		Diff:      l.Diff(before, after),
+	}
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		e.Actor = user.Username
+		e.Source = user.Source
+	}
+	if token, ok := auth.TokenFromContext(r.Context()); ok {
+		e.TokenID = token.ID
+	}
+	if _, err := l.Append(e); err != nil {
+		log.Printf("Failed to write audit log entry %s %s: %v", action, target, err)
+	}
+}
+
+// Diff compares the JSON fields of two versions of an object. Values that
+// do not encode to JSON objects are compared as a single "value" field.
+func (l *Log) Diff(before, after interface{}) map[string]models.AuditChange {
+	from, to := fields(before), fields(after)
+	diff := map[string]models.AuditChange{}
+	for name := range from {
+		if _, ok := to[name]; !ok {
+			to[name] = nil
+		}
+	}
+	for name, v := range to {
+		old := from[name]
+		if name == "updated_at" || reflect.DeepEqual(old, v) {
+			continue
+		}
+		if l.redacted(name) {
+			if old != nil {
+				old = redacted
+			}
+			if v != nil {
+				v = redacted
+			}
+		}
+		diff[name] = models.AuditChange{From: old, To: v}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// redacted reports whether the values of a field are kept out of the log
+func (l *Log) redacted(name string) bool {
+	for _, r := range l.Redact {
+		if strings.EqualFold(name, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// fields decodes the JSON encoding of v into its top-level fields
+func fields(v interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return result
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return result
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return result
+	}
+	if obj, ok := decoded.(map[string]interface{}); ok {
+		return obj
+	}
+	result["value"] = decoded
+	return result
+}
+
+// dayKey returns the storage key of the day of t
+func dayKey(t time.Time) string {
+	return keyPrefix + t.UTC().Format("20060102")
+}
+
+// truncateDay returns the start of the UTC day of t
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func newTestLog(t *testing.T) *Log {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	return NewLog(store)
+}
+
+func TestAppendAndQuery(t *testing.T) {
+	l := newTestLog(t)
+	now := time.Now().UTC()
+
+	entries := []models.AuditEntry{
+		{At: now.Add(-400 * 24 * time.Hour), Actor: "ada", Action: "cluster.create", Target: "cluster:old"},
+		{At: now.Add(-10 * 24 * time.Hour), Actor: "ada", Action: "cluster.update", Target: "cluster:asuka"},
+		{At: now.Add(-2 * time.Hour), Actor: "bob", Action: "token.create", Target: "token:1"},
+		{At: now.Add(-time.Hour), Actor: "ada", Action: "cluster.delete", Target: "cluster:naruko"},
+		{At: now.Add(-time.Hour), Actor: "ada", Action: "clusters.sync", Target: "registry"},
+	}
+	for _, e := range entries {
+		appended, err := l.Append(e)
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if appended.ID == "" {
+			t.Errorf("entry %s has no ID", e.Action)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		actions []string
+	}{
+		{"default range is the last week, newest first", Filter{}, []string{"clusters.sync", "cluster.delete", "token.create"}},
+		{"actor", Filter{Actor: "bob"}, []string{"token.create"}},
+		{"action kind", Filter{Action: "cluster", Since: now.Add(-30 * 24 * time.Hour)}, []string{"cluster.delete", "cluster.update"}},
+		{"exact action", Filter{Action: "cluster.update", Since: now.Add(-30 * 24 * time.Hour)}, []string{"cluster.update"}},
+		{"until", Filter{Since: now.Add(-30 * 24 * time.Hour), Until: now.Add(-90 * time.Minute)}, []string{"token.create", "cluster.update"}},
+		{"limit", Filter{Limit: 1}, []string{"clusters.sync"}},
+		// Entries are kept for good; older days are read with an explicit range
+		{"old entries", Filter{Since: now.Add(-401 * 24 * time.Hour), Until: now.Add(-300 * 24 * time.Hour)}, []string{"cluster.create"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			actions := []string{}
+			for _, e := range result {
+				actions = append(actions, e.Action)
+			}
+			if !reflect.DeepEqual(actions, tt.actions) {
+				t.Errorf("actions = %v, want %v", actions, tt.actions)
+			}
+		})
+	}
+
+	for _, f := range []Filter{
+		{Since: now, Until: now.Add(-time.Hour)},
+		{Since: now.Add(-400 * 24 * time.Hour)},
+	} {
+		if _, err := l.Query(f); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Query(%+v): error = %v, want ErrInvalidFilter", f, err)
+		}
+	}
+}
+
+func TestAppendIDs(t *testing.T) {
+	l := newTestLog(t)
+	day := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for _, at := range []time.Time{day, day.Add(time.Hour), day.Add(24 * time.Hour)} {
+		e, err := l.Append(models.AuditEntry{At: at, Action: "cluster.update"})
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		ids = append(ids, e.ID)
+	}
+	// IDs are numbered per day
+	if want := []string{"20240510-1", "20240510-2", "20240511-1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	l := newTestLog(t)
+	type user struct {
+		Name      string    `json:"name"`
+		Role      string    `json:"role"`
+		Password  string    `json:"password,omitempty"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	before := &user{Name: "vic", Role: "viewer", Password: "old", UpdatedAt: time.Now()}
+	after := &user{Name: "vic", Role: "admin", Password: "new"}
+	want := map[string]models.AuditChange{
+		"role":     {From: "viewer", To: "admin"},
+		"password": {From: redacted, To: redacted},
+	}
+	if diff := l.Diff(before, after); !reflect.DeepEqual(diff, want) {
+		t.Errorf("Diff = %v, want %v", diff, want)
+	}
+
+	// Created and deleted objects
+	if diff := l.Diff(nil, &user{Name: "vic"}); diff["name"].From != nil || diff["name"].To != "vic" {
+		t.Errorf("Diff of a created object = %v", diff)
+	}
+	if diff := l.Diff((*user)(nil), nil); diff != nil {
+		t.Errorf("Diff of nothing = %v", diff)
+	}
+	if diff := l.Diff([]string{"a"}, []string{"b"}); len(diff) != 1 || diff["value"].To == nil {
+		t.Errorf("Diff of a list = %v", diff)
+	}
+	if diff := l.Diff(before, before); diff != nil {
+		t.Errorf("Diff of the same object = %v", diff)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	l := newTestLog(t)
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Record(r, "cluster.update", "cluster:asuka", map[string]string{"queue": "a"}, map[string]string{"queue": "b"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h = l.Handler(h)
+	h = middleware.RequestID(h)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/clusters/asuka", nil)
+	r.RemoteAddr = "192.0.2.10:4321"
+	r = r.WithContext(auth.WithUser(r.Context(), &models.User{Username: "ada", Source: "local"}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	entries, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	e := entries[0]
+	if e.Actor != "ada" || e.Source != "local" || e.Action != "cluster.update" || e.Target != "cluster:asuka" || e.IP != "192.0.2.10" || e.RequestID == "" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Diff["queue"].From != "a" || e.Diff["queue"].To != "b" {
+		t.Errorf("diff = %v", e.Diff)
+	}
+
+	// Requests outside the handler are not logged
+	Record(httptest.NewRequest(http.MethodPut, "/", nil), "cluster.update", "cluster:asuka", nil, nil)
+	if entries, _ = l.Query(Filter{}); len(entries) != 1 {
+		t.Errorf("entries = %+v", entries)
+	}
+}
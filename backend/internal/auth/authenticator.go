@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// ErrInvalidRole is returned for role grants that fail validation
+var ErrInvalidRole = errors.New("invalid role")
+
+// ErrUnavailable is returned when no authenticator accepted the login and at
+// least one of them could not be reached
+var ErrUnavailable = errors.New("authentication service unavailable")
+
+// Identity is a user as reported by an authenticator
+type Identity struct {
+	Username string
+	Name     string
+	Email    string
+	Groups   []string
+	Source   string // One of the models.UserSource constants
+}
+
+// Authenticator checks a username and password against a user directory.
+// It returns ErrInvalidCredentials when the directory rejects them, and
+// other errors when the directory cannot be reached.
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+// RoleMap maps directory groups to the roles their members get
+type RoleMap map[string][]models.RoleGrant
+
+// ParseRoleMap parses "group=role" and "group=role@cluster" entries. A group
+// may appear in several entries.
+func ParseRoleMap(entries []string) (RoleMap, error) {
+	m := make(RoleMap)
+	for _, entry := range entries {
+		group, grant, ok := strings.Cut(entry, "=")
+		group = strings.TrimSpace(group)
+		if !ok || group == "" {
+			return nil, fmt.Errorf("%w: expected group=role[@cluster], got %q", ErrInvalidRole, entry)
+		}
+		g, err := ParseGrant(grant)
+		if err != nil {
+			return nil, err
+		}
+		m[group] = append(m[group], g)
+	}
+	return m, nil
+}
+
+// ParseGrant parses "role" or "role@cluster"
+func ParseGrant(s string) (models.RoleGrant, error) {
+	role, cluster, _ := strings.Cut(strings.TrimSpace(s), "@")
+	g := models.RoleGrant{Role: role, Cluster: cluster}
+	return g, ValidateGrant(g)
+}
+
+// ValidateGrant checks that a grant names a known role
+func ValidateGrant(g models.RoleGrant) error {
+	switch g.Role {
+	case models.RoleViewer, models.RoleClusterAdmin, models.RoleAdmin:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown role %q", ErrInvalidRole, g.Role)
+}
+
+// Grants returns the deduplicated grants of the given groups
+func (m RoleMap) Grants(groups []string) []models.RoleGrant {
+	seen := make(map[models.RoleGrant]bool)
+	grants := []models.RoleGrant{}
+	for _, group := range groups {
+		for _, g := range m[group] {
+			if !seen[g] {
+				seen[g] = true
+				grants = append(grants, g)
+			}
+		}
+	}
+	return grants
+}
+
+// Chain logs users in with the first authenticator that accepts their
+// credentials. Users of directories other than the local user store are
+// recorded in it on login, with roles mapped from their groups.
+type Chain struct {
+	users          *Users
+	authenticators []Authenticator
+	Roles          RoleMap // Roles of directory groups
+	DefaultRole    string  // Role of directory users in no mapped group; empty for none
+}
+
+// NewChain creates a login chain trying the authenticators in order
+func NewChain(users *Users, authenticators ...Authenticator) *Chain {
+	return &Chain{users: users, authenticators: authenticators, Roles: RoleMap{}}
+}
+
+// Login authenticates a username and password and returns the user
+func (c *Chain) Login(ctx context.Context, username, password string) (*models.User, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	var unavailable error
+	for _, a := range c.authenticators {
+		id, err := a.Authenticate(ctx, username, password)
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Authentication against %s failed: %v", a.Name(), err)
+			unavailable = fmt.Errorf("%w: %s", ErrUnavailable, a.Name())
+			continue
+		}
+
+		return c.Complete(id)
+	}
+
+	if unavailable != nil {
+		return nil, unavailable
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// Complete records the login of an authenticated identity and returns the
+// user. Identities from directories get the roles of their groups.
+func (c *Chain) Complete(id *Identity) (*models.User, error) {
+	var roles []models.RoleGrant
+	if id.Source != models.UserSourceLocal {
+		roles = c.Roles.Grants(id.Groups)
+		if len(roles) == 0 && c.DefaultRole != "" {
+			roles = []models.RoleGrant{{Role: c.DefaultRole}}
+		}
+	}
+	return c.users.Login(id, roles)
+}
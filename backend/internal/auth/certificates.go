@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"strings"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// WithCertificate returns a copy of ctx carrying the verified client
+// certificate a request was authenticated with
+func WithCertificate(ctx context.Context, cert *x509.Certificate) context.Context {
+	return context.WithValue(ctx, certKey, cert)
+}
+
+// CertificateFromContext returns the client certificate of a request
+// authenticated with one
+func CertificateFromContext(ctx context.Context) (*x509.Certificate, bool) {
+	cert, ok := ctx.Value(certKey).(*x509.Certificate)
+	return cert, ok && cert != nil
+}
+
+// ClientCertificate returns middleware authenticating requests that present
+// a client certificate verified during the TLS handshake. The certificate
+// acts as "cert:<common name>" and, like a token, only holds the ingest
+// scope. Other requests are rejected with 401 when required is set and
+// passed to fallback otherwise.
+func ClientCertificate(required bool, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		other := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				if required {
+					unauthorized(w, "A verified client certificate is required")
+					return
+				}
+				other.ServeHTTP(w, r)
+				return
+			}
+
+			cert := r.TLS.VerifiedChains[0][0]
+			name := cert.Subject.CommonName
+			if name == "" && len(cert.DNSNames) > 0 {
+				name = cert.DNSNames[0]
+			}
+			user := &models.User{Username: "cert:" + name, Source: models.UserSourceCert, Roles: []models.RoleGrant{}}
+			ctx := WithCertificate(WithUser(r.Context(), user), cert)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// CanCertificate reports whether the request was authenticated with a
+// client certificate issued to one of names. Requests without a certificate
+// and an empty list of names are refused.
+func CanCertificate(ctx context.Context, names ...string) bool {
+	cert, ok := CertificateFromContext(ctx)
+	return ok && CertificateNames(cert, names...)
+}
+
+// CertificateNames reports whether the certificate was issued to one of
+// names, by its common name or DNS names
+func CertificateNames(cert *x509.Certificate, names ...string) bool {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if strings.EqualFold(cert.Subject.CommonName, name) {
+			return true
+		}
+		for _, dns := range cert.DNSNames {
+			if strings.EqualFold(dns, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
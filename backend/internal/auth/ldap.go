@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// LDAPAttributes names the directory attributes mapped onto a user
+type LDAPAttributes struct {
+	Username string // Login name, e.g. uid
+	Name     string // Display name, e.g. cn
+	Email    string // e.g. mail
+	Group    string // Name of a group entry, e.g. cn
+}
+
+// LDAP authenticates users by searching the directory for the user entry
+// with a service account, then binding as that entry with the password.
+// Groups are the entries matching GroupFilter. Filters may contain
+// {username} and {dn}, which are replaced by the escaped login name and
+// user DN.
+type LDAP struct {
+	URL            string      // ldap:// or ldaps:// URL of the server
+	StartTLS       bool        // Upgrade ldap:// connections with StartTLS
+	TLSConfig      *tls.Config // TLS settings of ldaps:// and StartTLS
+	BindDN         string      // Service account searching the directory; empty binds anonymously
+	BindPassword   string
+	BaseDN         string // Where users are searched
+	UserFilter     string
+	GroupBaseDN    string // Where groups are searched; empty uses BaseDN
+	GroupFilter    string
+	RequiredGroups []string // Only members of one of these groups may log in; empty allows everyone
+	Attributes     LDAPAttributes
+	Timeout        time.Duration
+}
+
+// NewLDAP creates an LDAP authenticator for an RFC 2307 (posixAccount and
+// posixGroup) directory
+func NewLDAP(serverURL, baseDN string) *LDAP {
+	return &LDAP{
+		URL:         serverURL,
+		StartTLS:    true,
+		BaseDN:      baseDN,
+		UserFilter:  "(&(objectClass=posixAccount)(uid={username}))",
+		GroupFilter: "(&(objectClass=posixGroup)(memberUid={username}))",
+		Attributes: LDAPAttributes{
+			Username: "uid",
+			Name:     "cn",
+			Email:    "mail",
+			Group:    "cn",
+		},
+		Timeout: 10 * time.Second,
+	}
+}
+
+// Name returns the source of LDAP users
+func (l *LDAP) Name() string {
+	return models.UserSourceLDAP
+}
+
+// Authenticate binds as the user with the password and reads their details
+// and groups. The whole exchange must finish within Timeout and before ctx
+// is done.
+func (l *LDAP) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	// An empty password would be an unauthenticated bind, which servers
+	// accept without checking anything
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// Closing the connection fails the request waiting on it
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := l.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	attrs := []string{"dn", l.Attributes.Username, l.Attributes.Name, l.Attributes.Email}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, l.timeLimit(), false,
+		l.filter(l.UserFilter, username, ""), attrs, nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		// More than one entry matches, so binding would be a guess
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("user search failed: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		// Unknown, or ambiguous enough that binding would be a guess
+		return nil, ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("user bind failed: %w", err)
+	}
+
+	id := &Identity{
+		Username: entry.GetAttributeValue(l.Attributes.Username),
+		Name:     entry.GetAttributeValue(l.Attributes.Name),
+		Email:    entry.GetAttributeValue(l.Attributes.Email),
+		Source:   models.UserSourceLDAP,
+	}
+	if id.Username == "" {
+		id.Username = username
+	}
+
+	// Search groups as the service account, which may see more than the user
+	if err := l.bindService(conn); err != nil {
+		return nil, err
+	}
+	if id.Groups, err = l.groups(conn, id.Username, entry.DN); err != nil {
+		return nil, err
+	}
+
+	if len(l.RequiredGroups) > 0 && !intersects(id.Groups, l.RequiredGroups) {
+		return nil, ErrInvalidCredentials
+	}
+	return id, nil
+}
+
+// dial connects to the server, upgrading with StartTLS when configured. The
+// connection gets a deadline of Timeout, or of ctx when that is sooner.
+func (l *LDAP) dial(ctx context.Context) (*ldap.Conn, error) {
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if l.TLSConfig != nil {
+		tlsConfig = l.TLSConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	deadline := time.Now().Add(l.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+
+	var netConn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		netConn, err = dialer.DialContext(ctx, "tcp", hostPort(u, "389"))
+	case "ldaps":
+		netConn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", hostPort(u, "636"))
+	default:
+		return nil, fmt.Errorf("invalid LDAP URL: unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", l.URL, err)
+	}
+	if err := netConn.SetDeadline(deadline); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", l.URL, err)
+	}
+
+	conn := ldap.NewConn(netConn, u.Scheme == "ldaps")
+	conn.Start()
+	conn.SetTimeout(time.Until(deadline))
+
+	if l.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// hostPort returns the host and port of a URL, with the default port when
+// it has none
+func hostPort(u *url.URL, defaultPort string) string {
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(u.Hostname(), port)
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// bindService binds as the service account, or anonymously without one
+func (l *LDAP) bindService(conn *ldap.Conn) error {
+	var err error
+	if l.BindDN == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(l.BindDN, l.BindPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("service bind failed: %w", err)
+	}
+	return nil
+}
+
+// groups returns the names of the groups of a user
+func (l *LDAP) groups(conn *ldap.Conn, username, dn string) ([]string, error) {
+	if l.GroupFilter == "" {
+		return []string{}, nil
+	}
+	base := l.GroupBaseDN
+	if base == "" {
+		base = l.BaseDN
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, l.timeLimit(), false,
+		l.filter(l.GroupFilter, username, dn), []string{l.Attributes.Group}, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("group search failed: %w", err)
+	}
+	if result == nil {
+		return nil, errors.New("group search returned no result")
+	}
+
+	groups := []string{}
+	for _, e := range result.Entries {
+		if name := e.GetAttributeValue(l.Attributes.Group); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// filter substitutes the escaped username and DN into a filter template
+func (l *LDAP) filter(template, username, dn string) string {
+	return strings.NewReplacer(
+		"{username}", ldap.EscapeFilter(username),
+		"{dn}", ldap.EscapeFilter(dn),
+	).Replace(template)
+}
+
+// timeLimit is the server-side search time limit in seconds
+func (l *LDAP) timeLimit() int {
+	return int(l.Timeout / time.Second)
+}
+
+// intersects reports whether a and b share an element
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+//go:build integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// glauth returns an authenticator for the sample directory in docker/glauth.
+// Start it with "docker compose --profile ldap up -d glauth" and point
+// LDAP_TEST_URL at it when it is not on localhost.
+func glauth() *LDAP {
+	url := os.Getenv("LDAP_TEST_URL")
+	if url == "" {
+		url = "ldap://localhost:3893"
+	}
+	l := NewLDAP(url, "dc=cluster,dc=local")
+	l.StartTLS = false
+	l.BindDN = "cn=search,ou=svc,ou=users,dc=cluster,dc=local"
+	l.BindPassword = "search123"
+	// glauth names groups by ou
+	l.Attributes.Group = "ou"
+	return l
+}
+
+func TestLDAPGlauth(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		required []string
+		group    string // Expected group; empty expects the login to fail
+	}{
+		{"admin", "alice", "dirpass123", nil, "hpc-admins"},
+		{"operator", "bob", "dirpass123", nil, "ops"},
+		{"in a required group", "bob", "dirpass123", []string{"ops", "hpc-admins"}, "ops"},
+		{"not in a required group", "carol", "dirpass123", []string{"ops", "hpc-admins"}, ""},
+		{"wrong password", "alice", "search123", nil, ""},
+		{"unknown user", "mallory", "dirpass123", nil, ""},
+		{"wildcard username", "*", "dirpass123", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := glauth()
+			l.RequiredGroups = tt.required
+
+			id, err := l.Authenticate(context.Background(), tt.username, tt.password)
+			if tt.group == "" {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Authenticate error = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if id.Username != tt.username || id.Email != tt.username+"@cluster.local" {
+				t.Errorf("identity = %+v", id)
+			}
+			if !intersects(id.Groups, []string{tt.group}) {
+				t.Errorf("groups = %v, want %s among them", id.Groups, tt.group)
+			}
+		})
+	}
+}
+
+func TestLDAPGlauthServiceBind(t *testing.T) {
+	l := glauth()
+	l.BindPassword = "wrong"
+	if _, err := l.Authenticate(context.Background(), "alice", "dirpass123"); err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate error = %v, want a service bind error", err)
+	}
+}
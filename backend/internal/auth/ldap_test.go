@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+const (
+	testBaseDN    = "dc=cluster,dc=local"
+	testServiceDN = "cn=search,ou=svc,dc=cluster,dc=local"
+)
+
+// testDirectory is an LDAP server answering simple binds, and searches
+// from a fixed result per filter. It speaks just enough of the protocol
+// for the client in ldap.go.
+type testDirectory struct {
+	listener  net.Listener
+	passwords map[string]string        // Password of each DN
+	results   map[string][]*ldap.Entry // Entries found by each filter
+	hang      bool                     // Never answer
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+// newTestDirectory serves a directory holding the service account, alice
+// (hpc-admins and ops), bob (ops) and three entries named dup
+func newTestDirectory(t *testing.T) *testDirectory {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	alice := "uid=alice,ou=users," + testBaseDN
+	bob := "uid=bob,ou=users," + testBaseDN
+	d := &testDirectory{
+		listener: listener,
+		passwords: map[string]string{
+			testServiceDN: "search123",
+			alice:         "dirpass123",
+			bob:           "dirpass123",
+		},
+		results: map[string][]*ldap.Entry{
+			"(&(objectClass=posixAccount)(uid=alice))": {
+				ldap.NewEntry(alice, map[string][]string{"uid": {"alice"}, "cn": {"Alice Admin"}, "mail": {"alice@cluster.local"}}),
+			},
+			"(&(objectClass=posixAccount)(uid=bob))": {
+				ldap.NewEntry(bob, map[string][]string{"uid": {"bob"}, "cn": {"Bob Operator"}}),
+			},
+			"(&(objectClass=posixAccount)(uid=dup))": {
+				ldap.NewEntry("uid=dup,ou=a,"+testBaseDN, map[string][]string{"uid": {"dup"}}),
+				ldap.NewEntry("uid=dup,ou=b,"+testBaseDN, map[string][]string{"uid": {"dup"}}),
+				ldap.NewEntry("uid=dup,ou=c,"+testBaseDN, map[string][]string{"uid": {"dup"}}),
+			},
+			"(&(objectClass=posixGroup)(memberUid=alice))": {
+				ldap.NewEntry("cn=hpc-admins,ou=groups,"+testBaseDN, map[string][]string{"cn": {"hpc-admins"}}),
+				ldap.NewEntry("cn=ops,ou=groups,"+testBaseDN, map[string][]string{"cn": {"ops"}}),
+			},
+			"(&(objectClass=posixGroup)(memberUid=bob))": {
+				ldap.NewEntry("cn=ops,ou=groups,"+testBaseDN, map[string][]string{"cn": {"ops"}}),
+			},
+		},
+	}
+	go d.serve()
+	t.Cleanup(d.close)
+	return d
+}
+
+// authenticator returns an LDAP authenticator using the directory
+func (d *testDirectory) authenticator() *LDAP {
+	l := NewLDAP("ldap://"+d.listener.Addr().String(), testBaseDN)
+	l.StartTLS = false
+	l.BindDN = testServiceDN
+	l.BindPassword = "search123"
+	return l
+}
+
+func (d *testDirectory) serve() {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		d.mu.Lock()
+		d.conns = append(d.conns, conn)
+		d.mu.Unlock()
+		go d.handle(conn)
+	}
+}
+
+func (d *testDirectory) close() {
+	d.listener.Close()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range d.conns {
+		c.Close()
+	}
+}
+
+// handle answers the requests of a connection until it is closed
+func (d *testDirectory) handle(conn net.Conn) {
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		if d.hang {
+			continue
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, _ := op.Children[1].Value.(string)
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if want, ok := d.passwords[dn]; ok && want == op.Children[2].Data.String() {
+				code = ldap.LDAPResultSuccess
+			}
+			conn.Write(message(id, result(ldap.ApplicationBindResponse, code)).Bytes())
+		case ldap.ApplicationSearchRequest:
+			sizeLimit, _ := op.Children[3].Value.(int64)
+			filter, err := ldap.DecompileFilter(op.Children[6])
+			if err != nil {
+				conn.Write(message(id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError)).Bytes())
+				continue
+			}
+			code := uint16(ldap.LDAPResultSuccess)
+			for i, e := range d.results[filter] {
+				if sizeLimit > 0 && int64(i) >= sizeLimit {
+					code = ldap.LDAPResultSizeLimitExceeded
+					break
+				}
+				conn.Write(message(id, entry(e)).Bytes())
+			}
+			conn.Write(message(id, result(ldap.ApplicationSearchResultDone, code)).Bytes())
+		case ldap.ApplicationUnbindRequest:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// message wraps a protocol operation in an LDAP message
+func message(id int64, op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	p.AppendChild(op)
+	return p
+}
+
+// result returns an LDAP result operation
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return p
+}
+
+// entry returns a search result entry operation
+func entry(e *ldap.Entry) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "DN"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, a := range e.Attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a.Name, "Type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range a.Values {
+			values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(values)
+		attrs.AppendChild(attr)
+	}
+	p.AppendChild(attrs)
+	return p
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	d := newTestDirectory(t)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		required []string
+		want     *Identity
+	}{
+		{
+			name:     "user in two groups",
+			username: "alice",
+			password: "dirpass123",
+			want:     &Identity{Username: "alice", Name: "Alice Admin", Email: "alice@cluster.local", Groups: []string{"hpc-admins", "ops"}},
+		},
+		{
+			name:     "in a required group",
+			username: "bob",
+			password: "dirpass123",
+			required: []string{"ops"},
+			want:     &Identity{Username: "bob", Name: "Bob Operator", Groups: []string{"ops"}},
+		},
+		{name: "not in a required group", username: "bob", password: "dirpass123", required: []string{"hpc-admins"}},
+		{name: "wrong password", username: "alice", password: "search123"},
+		{name: "empty password", username: "alice", password: ""},
+		{name: "unknown user", username: "mallory", password: "dirpass123"},
+		{name: "ambiguous user", username: "dup", password: "dirpass123"},
+		{name: "filter injection", username: "alice)(uid=*", password: "dirpass123"},
+		{name: "wildcard username", username: "*", password: "dirpass123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := d.authenticator()
+			l.RequiredGroups = tt.required
+
+			id, err := l.Authenticate(context.Background(), tt.username, tt.password)
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Authenticate error = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			tt.want.Source = models.UserSourceLDAP
+			if !reflect.DeepEqual(id, tt.want) {
+				t.Errorf("identity = %+v, want %+v", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPServiceBindFailure(t *testing.T) {
+	d := newTestDirectory(t)
+	l := d.authenticator()
+	l.BindPassword = "wrong"
+
+	// A broken service account is an outage, not a wrong user password
+	_, err := l.Authenticate(context.Background(), "alice", "dirpass123")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate error = %v, want a service bind error", err)
+	}
+}
+
+func TestLDAPContextDeadline(t *testing.T) {
+	d := newTestDirectory(t)
+	d.hang = true
+	l := d.authenticator()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := l.Authenticate(ctx, "alice", "dirpass123")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Authenticate took %v with a 200ms deadline", elapsed)
+	}
+}
+
+func TestLDAPFilter(t *testing.T) {
+	l := NewLDAP("ldap://localhost", testBaseDN)
+	tests := []struct {
+		name     string
+		template string
+		username string
+		dn       string
+		want     string
+	}{
+		{"username", "(uid={username})", "alice", "", "(uid=alice)"},
+		{"special characters", "(uid={username})", "a*(b)\\", "", `(uid=a\2a\28b\29\5c)`},
+		{"dn", "(member={dn})", "alice", "uid=alice,ou=users,dc=x", "(member=uid=alice,ou=users,dc=x)"},
+		{"both", "(|(memberUid={username})(member={dn}))", "bob", "uid=bob", "(|(memberUid=bob)(member=uid=bob))"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.filter(tt.template, tt.username, tt.dn); got != tt.want {
+				t.Errorf("filter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
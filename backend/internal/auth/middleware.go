@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// contextKey is the type of the request context keys of this package
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	tokenKey
+	ownerKey
+	certKey
+	proxyKey
+)
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// UserFromContext returns the authenticated user of a request
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userKey).(*models.User)
+	return user, ok && user != nil
+}
+
+// WithToken returns a copy of ctx carrying the API token a request was
+// authenticated with
+func WithToken(ctx context.Context, token *models.APIToken) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// TokenFromContext returns the API token of a request authenticated with one
+func TokenFromContext(ctx context.Context) (*models.APIToken, bool) {
+	token, ok := ctx.Value(tokenKey).(*models.APIToken)
+	return token, ok && token != nil
+}
+
+// withTokenOwner returns a copy of ctx carrying the roles behind an API
+// token before they are capped to its scopes
+func withTokenOwner(ctx context.Context, owner *models.User) context.Context {
+	return context.WithValue(ctx, ownerKey, owner)
+}
+
+// tokenOwnerFromContext returns the roles behind the API token of a request
+func tokenOwnerFromContext(ctx context.Context) (*models.User, bool) {
+	owner, ok := ctx.Value(ownerKey).(*models.User)
+	return owner, ok && owner != nil
+}
+
+// Middleware rejects requests that are not authenticated with 401 and puts
+// the user of authenticated requests in the request context. Requests are
+// authenticated with an "Authorization: Bearer" API token or a session
+// cookie.
+type Middleware struct {
+	sessions *Sessions
+	users    *Users
+	tokens   *Tokens
+}
+
+// NewMiddleware creates the authentication middleware
+func NewMiddleware(sessions *Sessions, users *Users, tokens *Tokens) *Middleware {
+	return &Middleware{sessions: sessions, users: users, tokens: tokens}
+}
+
+// Handler wraps next with authentication
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if raw, ok := bearerToken(r); ok {
+			user, owner, token, err := m.tokenUser(r, raw)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					log.Printf("API token validation failed: %v", err)
+				}
+				unauthorized(w, "A valid API token is required")
+				return
+			}
+			ctx := withTokenOwner(WithToken(WithUser(r.Context(), user), token), owner)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		session, err := m.sessions.Validate(r)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidSession) {
+				log.Printf("Session validation failed: %v", err)
+			}
+			unauthorized(w, "A valid session is required")
+			return
+		}
+
+		user, err := m.users.Get(session.Username)
+		if err != nil {
+			// The user was deleted after signing in
+			unauthorized(w, "A valid session is required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// tokenUser returns the user an API token acts as and the owner whose roles
+// back it. Personal tokens act as their owner and service tokens, which only
+// admins create, get roles from their scopes; without the admin scope, roles
+// are capped at viewer.
+func (m *Middleware) tokenUser(r *http.Request, raw string) (*models.User, *models.User, *models.APIToken, error) {
+	token, err := m.tokens.Authenticate(raw, ClientIP(r))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var user models.User
+	var owner *models.User
+	if token.Kind == models.TokenKindPersonal {
+		o, err := m.users.Get(token.Owner)
+		if err != nil {
+			// The owner was deleted after creating the token
+			return nil, nil, nil, ErrInvalidCredentials
+		}
+		user, owner = *o, o
+	} else {
+		user = models.User{Username: "service:" + token.Name, Source: models.UserSourceToken, Roles: []models.RoleGrant{}}
+		switch {
+		case token.HasScope(models.ScopeAdmin):
+			user.Roles = []models.RoleGrant{{Role: models.RoleAdmin}}
+		case token.HasScope(models.ScopeRead):
+			user.Roles = []models.RoleGrant{{Role: models.RoleViewer}}
+		}
+		owner = &models.User{Username: user.Username, Source: user.Source, Roles: []models.RoleGrant{{Role: models.RoleAdmin}}}
+	}
+
+	if !token.HasScope(models.ScopeAdmin) {
+		capped := []models.RoleGrant{}
+		if token.HasScope(models.ScopeRead) {
+			for _, g := range user.Roles {
+				capped = append(capped, models.RoleGrant{Role: models.RoleViewer, Cluster: g.Cluster})
+			}
+		}
+		user.Roles = capped
+	}
+	return &user, owner, token, nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized writes a 401 response in the error envelope of the API
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   "Authentication required",
+		"message": message,
+	})
+}
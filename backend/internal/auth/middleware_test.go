@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// tokenFixture holds users and a way to issue their tokens
+type tokenFixture struct {
+	users      *Users
+	tokens     *Tokens
+	middleware *Middleware
+}
+
+// newTokenFixture creates the users vic (viewer of asuka), carl
+// (cluster-admin of asuka) and ada (admin)
+func newTokenFixture(t *testing.T) *tokenFixture {
+	t.Helper()
+	store := newTestStorage(t)
+	f := &tokenFixture{users: NewUsers(store), tokens: NewTokens(store)}
+	f.users.Cost = bcrypt.MinCost
+	f.middleware = NewMiddleware(NewSessions(store, []byte("secret")), f.users, f.tokens)
+
+	for _, u := range []models.User{
+		{Username: "vic", Roles: []models.RoleGrant{{Role: models.RoleViewer, Cluster: "asuka"}}},
+		{Username: "carl", Roles: []models.RoleGrant{{Role: models.RoleClusterAdmin, Cluster: "asuka"}}},
+		{Username: "ada", Roles: []models.RoleGrant{{Role: models.RoleAdmin}}},
+	} {
+		u.Source = models.UserSourceLocal
+		if _, err := f.users.Put(u, "long enough"); err != nil {
+			t.Fatalf("Put %s: %v", u.Username, err)
+		}
+	}
+	return f
+}
+
+// issue creates a token and returns its secret
+func (f *tokenFixture) issue(t *testing.T, kind, owner string, scopes ...string) string {
+	t.Helper()
+	_, raw, err := f.tokens.Create(models.APIToken{Name: "test", Kind: kind, Owner: owner, Scopes: scopes})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return raw
+}
+
+// authenticate runs a bearer token through the middleware and then through
+// next, returning the status code
+func (f *tokenFixture) authenticate(raw string, next http.Handler) int {
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/ingest/load_average", nil)
+	r.Header.Set("Authorization", "Bearer "+raw)
+	rec := httptest.NewRecorder()
+	f.middleware.Handler(next).ServeHTTP(rec, r)
+	return rec.Code
+}
+
+func TestTokenUserScopeCapping(t *testing.T) {
+	f := newTokenFixture(t)
+
+	tests := []struct {
+		name   string
+		kind   string
+		owner  string
+		scopes []string
+		want   []models.RoleGrant
+	}{
+		{"read caps roles at viewer", models.TokenKindPersonal, "carl", []string{models.ScopeRead},
+			[]models.RoleGrant{{Role: models.RoleViewer, Cluster: "asuka"}}},
+		{"read keeps the cluster of global grants", models.TokenKindPersonal, "ada", []string{models.ScopeRead},
+			[]models.RoleGrant{{Role: models.RoleViewer}}},
+		{"admin keeps the owner's roles", models.TokenKindPersonal, "carl", []string{models.ScopeAdmin},
+			[]models.RoleGrant{{Role: models.RoleClusterAdmin, Cluster: "asuka"}}},
+		{"ingest alone holds no role", models.TokenKindPersonal, "ada", []string{models.ScopeIngest},
+			[]models.RoleGrant{}},
+		{"read service token", models.TokenKindService, "ada", []string{models.ScopeRead},
+			[]models.RoleGrant{{Role: models.RoleViewer}}},
+		{"admin service token", models.TokenKindService, "ada", []string{models.ScopeAdmin},
+			[]models.RoleGrant{{Role: models.RoleAdmin}}},
+		{"ingest service token", models.TokenKindService, "ada", []string{models.ScopeIngest},
+			[]models.RoleGrant{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := f.issue(t, tt.kind, tt.owner, tt.scopes...)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			user, _, token, err := f.middleware.tokenUser(r, raw)
+			if err != nil {
+				t.Fatalf("tokenUser: %v", err)
+			}
+			if token.Owner != tt.owner {
+				t.Errorf("token owner = %q, want %q", token.Owner, tt.owner)
+			}
+			if len(user.Roles) != len(tt.want) {
+				t.Fatalf("roles = %+v, want %+v", user.Roles, tt.want)
+			}
+			for i := range tt.want {
+				if user.Roles[i] != tt.want[i] {
+					t.Errorf("roles = %+v, want %+v", user.Roles, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenUserRejectsInactiveTokens(t *testing.T) {
+	f := newTokenFixture(t)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	token, raw, err := f.tokens.Create(models.APIToken{Name: "test", Kind: models.TokenKindPersonal, Owner: "vic", Scopes: []string{models.ScopeRead}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := f.tokens.Revoke(token.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, _, _, err := f.middleware.tokenUser(r, raw); err != ErrInvalidCredentials {
+		t.Errorf("revoked token: error = %v, want ErrInvalidCredentials", err)
+	}
+
+	raw = f.issue(t, models.TokenKindPersonal, "vic", models.ScopeRead)
+	if err := f.users.Delete("vic"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, _, err := f.middleware.tokenUser(r, raw); err != ErrInvalidCredentials {
+		t.Errorf("token of a deleted owner: error = %v, want ErrInvalidCredentials", err)
+	}
+
+	if _, _, _, err := f.middleware.tokenUser(r, "csm_unknown"); err != ErrInvalidCredentials {
+		t.Errorf("unknown token: error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	f := newTokenFixture(t)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	ingest := RequireScope(models.ScopeIngest, models.RoleClusterAdmin)(ok)
+
+	tests := []struct {
+		name   string
+		kind   string
+		owner  string
+		scopes []string
+		want   int
+	}{
+		{"cluster-admin with the scope", models.TokenKindPersonal, "carl", []string{models.ScopeIngest}, http.StatusOK},
+		{"admin with the scope", models.TokenKindPersonal, "ada", []string{models.ScopeIngest}, http.StatusOK},
+		{"viewer with the scope", models.TokenKindPersonal, "vic", []string{models.ScopeIngest}, http.StatusForbidden},
+		{"cluster-admin without the scope", models.TokenKindPersonal, "carl", []string{models.ScopeAdmin}, http.StatusForbidden},
+		{"service token with the scope", models.TokenKindService, "ada", []string{models.ScopeIngest}, http.StatusOK},
+		{"service token without the scope", models.TokenKindService, "ada", []string{models.ScopeRead}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Tokens of viewers cannot be created through the API, but may
+			// predate the check
+			raw := f.issue(t, tt.kind, tt.owner, tt.scopes...)
+			if got := f.authenticate(raw, ingest); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireScopeSessions(t *testing.T) {
+	ingest := RequireScope(models.ScopeIngest, models.RoleClusterAdmin)
+	tests := []struct {
+		name string
+		user *models.User
+		want int
+	}{
+		{"cluster-admin", grants(models.RoleClusterAdmin, "asuka"), http.StatusOK},
+		{"viewer", grants(models.RoleViewer, ""), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/ingest/load_average", nil)
+			if got := serve(ingest, r, tt.user); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireScopeCertificates(t *testing.T) {
+	tests := []struct {
+		scope string
+		want  int
+	}{
+		{models.ScopeIngest, http.StatusOK},
+		{models.ScopeRead, http.StatusForbidden},
+		{models.ScopeAdmin, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/ingest/load_average", nil)
+			r = r.WithContext(WithCertificate(r.Context(), &x509.Certificate{}))
+			// Certificate requests carry a user without roles
+			if got := serve(RequireScope(tt.scope, models.RoleViewer), r, grants()); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanScope(t *testing.T) {
+	f := newTokenFixture(t)
+
+	tests := []struct {
+		name    string
+		owner   string
+		role    string
+		cluster string
+		want    bool
+	}{
+		{"own cluster", "carl", models.RoleClusterAdmin, "asuka", true},
+		{"other cluster", "carl", models.RoleClusterAdmin, "naruko", false},
+		{"every cluster", "carl", models.RoleAdmin, "", false},
+		{"admin on every cluster", "ada", models.RoleAdmin, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := f.issue(t, models.TokenKindPersonal, tt.owner, models.ScopeIngest)
+			var got bool
+			f.authenticate(raw, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = CanScope(r.Context(), tt.role, tt.cluster)
+			}))
+			if got != tt.want {
+				t.Errorf("CanScope(%q, %q) = %v, want %v", tt.role, tt.cluster, got, tt.want)
+			}
+		})
+	}
+
+	// Sessions are checked against the user's own roles
+	ctx := WithUser(context.Background(), grants(models.RoleClusterAdmin, "asuka"))
+	if !CanScope(ctx, models.RoleClusterAdmin, "asuka") || CanScope(ctx, models.RoleClusterAdmin, "naruko") {
+		t.Error("CanScope does not follow the session user's grants")
+	}
+
+	// Certificates hold no roles, even when the user carries grants
+	ctx = WithCertificate(ctx, &x509.Certificate{Subject: pkix.Name{CommonName: "asuka00"}})
+	if CanScope(ctx, models.RoleClusterAdmin, "asuka") {
+		t.Error("CanScope allowed a certificate request")
+	}
+}
+
+func TestCanCertificate(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "asuka00"}, DNSNames: []string{"asuka00.example.org"}}
+	tests := []struct {
+		name  string
+		cert  *x509.Certificate
+		names []string
+		want  bool
+	}{
+		{"common name", cert, []string{"asuka", "ASUKA00"}, true},
+		{"DNS name", cert, []string{"asuka00.example.org"}, true},
+		{"other names", cert, []string{"naruko", "naruko00"}, false},
+		{"no names", cert, nil, false},
+		{"no certificate", nil, []string{"asuka00"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithUser(context.Background(), grants(models.RoleAdmin, ""))
+			if tt.cert != nil {
+				ctx = WithCertificate(ctx, tt.cert)
+			}
+			if got := CanCertificate(ctx, tt.names...); got != tt.want {
+				t.Errorf("CanCertificate(%v) = %v, want %v", tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenScopesAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		user   *models.User
+		scopes []string
+		want   bool
+	}{
+		{"viewer reads", grants(models.RoleViewer, "asuka"), []string{models.ScopeRead}, true},
+		{"viewer ingests", grants(models.RoleViewer, ""), []string{models.ScopeIngest}, false},
+		{"viewer administers", grants(models.RoleViewer, ""), []string{models.ScopeRead, models.ScopeAdmin}, false},
+		{"cluster-admin ingests", grants(models.RoleClusterAdmin, "asuka"), []string{models.ScopeIngest}, true},
+		{"admin administers", grants(models.RoleAdmin, ""), []string{models.ScopeAdmin}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := TokenScopesAllowed(tt.user, tt.scopes); got != tt.want {
+				t.Errorf("TokenScopesAllowed(%v) = %v, want %v", tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
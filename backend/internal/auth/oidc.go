@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidLoginState is returned for OIDC callbacks that do not belong to a
+// login started by the same browser
+var ErrInvalidLoginState = errors.New("invalid or expired login state")
+
+// StateCookieName is the cookie binding an OIDC login to the browser that
+// started it
+const StateCookieName = "csm_oidc_state"
+
+// OIDC logs users in with an OpenID Connect provider, using the
+// authorization code flow with PKCE. The provider is discovered on first use.
+type OIDC struct {
+	Issuer         string
+	ClientID       string
+	ClientSecret   string   // Empty for public clients
+	RedirectURL    string   // Callback URL registered with the provider
+	Scopes         []string // Requested in addition to "openid"
+	UsernameClaim  string
+	NameClaim      string
+	EmailClaim     string
+	GroupsClaim    string   // Claim listing groups; dots reach into nested objects
+	RequiredGroups []string // Groups allowed to log in; empty allows everyone
+	LoginTimeout   time.Duration
+	CookiePath     string // Path of the state cookie, covering the callback
+	Secure         bool   // Send the state cookie over HTTPS only
+	HTTPClient     *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	pending  map[string]pendingLogin
+}
+
+// pendingLogin is a login waiting for the provider's callback
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	returnTo string
+	expires  time.Time
+}
+
+// NewOIDC creates an OIDC login for a client registered with the issuer
+func NewOIDC(issuer, clientID, redirectURL string) *OIDC {
+	return &OIDC{
+		Issuer:        issuer,
+		ClientID:      clientID,
+		RedirectURL:   redirectURL,
+		Scopes:        []string{"profile", "email"},
+		UsernameClaim: "preferred_username",
+		NameClaim:     "name",
+		EmailClaim:    "email",
+		GroupsClaim:   "groups",
+		LoginTimeout:  10 * time.Minute,
+		CookiePath:    "/",
+		HTTPClient:    &http.Client{Timeout: 10 * time.Second},
+		pending:       make(map[string]pendingLogin),
+	}
+}
+
+// Start begins a login and returns the provider URL to redirect the browser
+// to. returnTo is where the browser goes after the login; anything but a
+// local path is replaced with "/".
+func (o *OIDC) Start(ctx context.Context, w http.ResponseWriter, returnTo string) (string, error) {
+	config, _, err := o.config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+	if !isLocalPath(returnTo) {
+		returnTo = "/"
+	}
+
+	now := time.Now()
+	o.mu.Lock()
+	for s, p := range o.pending {
+		if now.After(p.expires) {
+			delete(o.pending, s)
+		}
+	}
+	o.pending[state] = pendingLogin{
+		nonce:    nonce,
+		verifier: verifier,
+		returnTo: returnTo,
+		expires:  now.Add(o.LoginTimeout),
+	}
+	o.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     StateCookieName,
+		Value:    state,
+		Path:     o.CookiePath,
+		MaxAge:   int(o.LoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   o.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Finish completes the login from the provider's callback request. It
+// returns the identity from the verified ID token and the path to send the
+// browser to.
+func (o *OIDC) Finish(ctx context.Context, w http.ResponseWriter, r *http.Request) (*Identity, string, error) {
+	// The state is single use whatever the outcome
+	http.SetCookie(w, &http.Cookie{
+		Name:     StateCookieName,
+		Value:    "",
+		Path:     o.CookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   o.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	state := query.Get("state")
+	cookie, err := r.Cookie(StateCookieName)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		return nil, "", ErrInvalidLoginState
+	}
+	o.mu.Lock()
+	login, ok := o.pending[state]
+	delete(o.pending, state)
+	o.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return nil, "", ErrInvalidLoginState
+	}
+
+	if e := query.Get("error"); e != "" {
+		return nil, "", fmt.Errorf("%w: provider returned %s: %s", ErrInvalidCredentials, e, query.Get("error_description"))
+	}
+
+	config, provider, err := o.config(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	ctx = oidc.ClientContext(ctx, o.HTTPClient)
+	token, err := config.Exchange(ctx, query.Get("code"), oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		var rerr *oauth2.RetrieveError
+		if errors.As(err, &rerr) {
+			return nil, "", fmt.Errorf("%w: code exchange: %v", ErrInvalidCredentials, err)
+		}
+		return nil, "", fmt.Errorf("%w: code exchange: %v", ErrUnavailable, err)
+	}
+
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: no ID token in the token response", ErrInvalidCredentials)
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: o.ClientID}).Verify(ctx, raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(login.nonce)) != 1 {
+		return nil, "", fmt.Errorf("%w: ID token nonce mismatch", ErrInvalidCredentials)
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, "", fmt.Errorf("failed to decode ID token claims: %w", err)
+	}
+	// Some providers only put profile and group claims in the UserInfo
+	// response
+	if claim(claims, o.UsernameClaim) == nil || claim(claims, o.GroupsClaim) == nil {
+		if info, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token)); err == nil && info.Subject == idToken.Subject {
+			extra := map[string]interface{}{}
+			if err := info.Claims(&extra); err == nil {
+				for k, v := range extra {
+					if _, ok := claims[k]; !ok {
+						claims[k] = v
+					}
+				}
+			}
+		}
+	}
+
+	id, err := o.identity(claims)
+	if err != nil {
+		return nil, "", err
+	}
+	return id, login.returnTo, nil
+}
+
+// identity maps ID token claims to an identity
+func (o *OIDC) identity(claims map[string]interface{}) (*Identity, error) {
+	username, _ := claim(claims, o.UsernameClaim).(string)
+	if username == "" {
+		return nil, fmt.Errorf("%w: ID token has no %s claim", ErrInvalidCredentials, o.UsernameClaim)
+	}
+
+	id := &Identity{Username: username, Source: models.UserSourceOIDC}
+	id.Name, _ = claim(claims, o.NameClaim).(string)
+	id.Email, _ = claim(claims, o.EmailClaim).(string)
+	switch groups := claim(claims, o.GroupsClaim).(type) {
+	case string:
+		id.Groups = []string{groups}
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	}
+
+	if len(o.RequiredGroups) > 0 && !intersects(id.Groups, o.RequiredGroups) {
+		return nil, fmt.Errorf("%w: %s is not in a group allowed to log in", ErrInvalidCredentials, username)
+	}
+	return id, nil
+}
+
+// config discovers the provider if needed and returns the OAuth2 client
+// configuration
+func (o *OIDC) config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.provider == nil {
+		// Discovery happens under the lock, which also keeps concurrent
+		// first logins from fetching the document several times
+		p, err := oidc.NewProvider(oidc.ClientContext(ctx, o.HTTPClient), o.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: discovery: %v", ErrUnavailable, err)
+		}
+		o.provider = p
+	}
+
+	return &oauth2.Config{
+		ClientID:     o.ClientID,
+		ClientSecret: o.ClientSecret,
+		RedirectURL:  o.RedirectURL,
+		Endpoint:     o.provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, o.Scopes...),
+	}, o.provider, nil
+}
+
+// claim looks up a claim, following dots into nested objects
+func claim(claims map[string]interface{}, name string) interface{} {
+	if name == "" {
+		return nil
+	}
+	if v, ok := claims[name]; ok {
+		return v
+	}
+	var v interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+// isLocalPath reports whether p is a path on this server, so redirecting to
+// it cannot leave the site
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
+
+// randomToken returns 32 random bytes, base64url encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+func TestIsLocalPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/clusters/asuka?tab=nodes", true},
+		{"", false},
+		{"clusters", false},
+		{"//evil.example.com", false},
+		{"/\\evil.example.com", false},
+		{"https://evil.example.com/", false},
+		{"javascript:alert(1)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isLocalPath(tt.path); got != tt.want {
+				t.Errorf("isLocalPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		groups   string
+		required []string
+		claims   map[string]interface{}
+		want     *Identity
+	}{
+		{
+			name:   "flat claims",
+			groups: "groups",
+			claims: map[string]interface{}{"preferred_username": "alice", "name": "Alice", "email": "alice@example.com", "groups": []interface{}{"hpc-admins", "staff"}},
+			want:   &Identity{Username: "alice", Name: "Alice", Email: "alice@example.com", Groups: []string{"hpc-admins", "staff"}},
+		},
+		{
+			name:   "single group as a string",
+			groups: "groups",
+			claims: map[string]interface{}{"preferred_username": "alice", "groups": "staff"},
+			want:   &Identity{Username: "alice", Groups: []string{"staff"}},
+		},
+		{
+			name:   "nested groups claim",
+			groups: "realm_access.roles",
+			claims: map[string]interface{}{"preferred_username": "alice", "realm_access": map[string]interface{}{"roles": []interface{}{"ops", 42}}},
+			want:   &Identity{Username: "alice", Groups: []string{"ops"}},
+		},
+		{
+			name:   "dotted claim name",
+			groups: "https://example.com/groups",
+			claims: map[string]interface{}{"preferred_username": "alice", "https://example.com/groups": []interface{}{"ops"}},
+			want:   &Identity{Username: "alice", Groups: []string{"ops"}},
+		},
+		{
+			name:     "in a required group",
+			groups:   "groups",
+			required: []string{"ops", "hpc-admins"},
+			claims:   map[string]interface{}{"preferred_username": "alice", "groups": []interface{}{"hpc-admins"}},
+			want:     &Identity{Username: "alice", Groups: []string{"hpc-admins"}},
+		},
+		{
+			name:     "not in a required group",
+			groups:   "groups",
+			required: []string{"ops"},
+			claims:   map[string]interface{}{"preferred_username": "alice", "groups": []interface{}{"staff"}},
+		},
+		{
+			name:     "no groups with required groups",
+			groups:   "groups",
+			required: []string{"ops"},
+			claims:   map[string]interface{}{"preferred_username": "alice"},
+		},
+		{
+			name:   "no username",
+			groups: "groups",
+			claims: map[string]interface{}{"sub": "1234", "groups": []interface{}{"ops"}},
+		},
+		{
+			name:   "username of the wrong type",
+			groups: "groups",
+			claims: map[string]interface{}{"preferred_username": 1234},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOIDC("https://idp.example.com", "monitor", "https://monitor.example.com/api/v1/auth/oidc/callback")
+			o.GroupsClaim = tt.groups
+			o.RequiredGroups = tt.required
+
+			id, err := o.identity(tt.claims)
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("identity error = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("identity: %v", err)
+			}
+			tt.want.Source = models.UserSourceOIDC
+			if !reflect.DeepEqual(id, tt.want) {
+				t.Errorf("identity = %+v, want %+v", id, tt.want)
+			}
+		})
+	}
+}
+
+// testProvider is an OpenID provider issuing ID tokens for a single code
+type testProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	code      string
+	challenge string                 // PKCE challenge of the pending login
+	claims    map[string]interface{} // Claims of the next ID token
+	userInfo  map[string]interface{}
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	p := &testProvider{key: key, code: "code-1234"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"userinfo_endpoint":                     p.URL + "/userinfo",
+			"jwks_uri":                              p.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != p.code || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-1234",
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     p.sign(t),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p.userInfo)
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// sign returns the next ID token, issued now for the monitor client
+func (p *testProvider) sign(t *testing.T) string {
+	claims := map[string]interface{}{
+		"iss": p.URL,
+		"aud": "monitor",
+		"sub": "1234",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	payload, _ := json.Marshal(claims)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		t.Errorf("NewSigner: %v", err)
+		return ""
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Errorf("Sign: %v", err)
+		return ""
+	}
+	raw, _ := jws.CompactSerialize()
+	return raw
+}
+
+// start begins a login and returns its state cookie and the parameters of
+// the authorization request
+func (p *testProvider) start(t *testing.T, o *OIDC, returnTo string) (*http.Cookie, url.Values) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	redirect, err := o.Start(context.Background(), rec, returnTo)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatalf("Start returned %q: %v", redirect, err)
+	}
+	params := u.Query()
+	p.challenge = params.Get("code_challenge")
+
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == StateCookieName {
+			return c, params
+		}
+	}
+	t.Fatal("Start set no state cookie")
+	return nil, nil
+}
+
+// callback returns the provider's redirect back to the monitor
+func callback(cookie *http.Cookie, query url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+func TestOIDCLogin(t *testing.T) {
+	p := newTestProvider(t)
+	o := NewOIDC(p.URL, "monitor", "https://monitor.example.com/api/v1/auth/oidc/callback")
+
+	cookie, params := p.start(t, o, "/clusters/asuka")
+	if params.Get("code_challenge_method") != "S256" || params.Get("nonce") == "" || params.Get("state") != cookie.Value {
+		t.Fatalf("authorization request = %v, state cookie %q", params, cookie.Value)
+	}
+	// The groups are only in the UserInfo response
+	p.claims = map[string]interface{}{"nonce": params.Get("nonce"), "preferred_username": "alice"}
+	p.userInfo = map[string]interface{}{"sub": "1234", "groups": []interface{}{"hpc-admins"}}
+
+	query := url.Values{"state": {params.Get("state")}, "code": {p.code}}
+	id, returnTo, err := o.Finish(context.Background(), httptest.NewRecorder(), callback(cookie, query))
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if id.Username != "alice" || !reflect.DeepEqual(id.Groups, []string{"hpc-admins"}) {
+		t.Errorf("identity = %+v", id)
+	}
+	if returnTo != "/clusters/asuka" {
+		t.Errorf("returnTo = %q, want /clusters/asuka", returnTo)
+	}
+
+	// The state is single use
+	if _, _, err := o.Finish(context.Background(), httptest.NewRecorder(), callback(cookie, query)); !errors.Is(err, ErrInvalidLoginState) {
+		t.Errorf("replayed callback: error = %v, want ErrInvalidLoginState", err)
+	}
+}
+
+func TestOIDCLoginRejected(t *testing.T) {
+	p := newTestProvider(t)
+
+	tests := []struct {
+		name string
+		// callback alters the claims of the ID token or the callback
+		// request of a started login
+		callback func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request
+		want     error
+	}{
+		{"no state cookie", func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request {
+			return callback(nil, query)
+		}, ErrInvalidLoginState},
+		{"state of another browser", func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request {
+			cookie.Value = "another"
+			return callback(cookie, query)
+		}, ErrInvalidLoginState},
+		{"provider error", func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request {
+			query.Set("error", "access_denied")
+			return callback(cookie, query)
+		}, ErrInvalidCredentials},
+		{"wrong code", func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request {
+			query.Set("code", "forged")
+			return callback(cookie, query)
+		}, ErrInvalidCredentials},
+		{"nonce of another login", func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request {
+			claims["nonce"] = "another"
+			return callback(cookie, query)
+		}, ErrInvalidCredentials},
+		{"token for another client", func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request {
+			claims["aud"] = "another"
+			return callback(cookie, query)
+		}, ErrInvalidCredentials},
+		{"expired token", func(cookie *http.Cookie, query url.Values, claims map[string]interface{}) *http.Request {
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return callback(cookie, query)
+		}, ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOIDC(p.URL, "monitor", "https://monitor.example.com/api/v1/auth/oidc/callback")
+			cookie, params := p.start(t, o, "/")
+			p.claims = map[string]interface{}{"nonce": params.Get("nonce"), "preferred_username": "alice", "groups": "ops"}
+			p.userInfo = nil
+
+			query := url.Values{"state": {params.Get("state")}, "code": {p.code}}
+			r := tt.callback(cookie, query, p.claims)
+			if _, _, err := o.Finish(context.Background(), httptest.NewRecorder(), r); !errors.Is(err, tt.want) {
+				t.Errorf("Finish error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCStartReturnTo(t *testing.T) {
+	p := newTestProvider(t)
+	o := NewOIDC(p.URL, "monitor", "https://monitor.example.com/api/v1/auth/oidc/callback")
+	cookie, params := p.start(t, o, "//evil.example.com")
+	p.claims = map[string]interface{}{"nonce": params.Get("nonce"), "preferred_username": "alice", "groups": "ops"}
+
+	query := url.Values{"state": {params.Get("state")}, "code": {p.code}}
+	_, returnTo, err := o.Finish(context.Background(), httptest.NewRecorder(), callback(cookie, query))
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if returnTo != "/" {
+		t.Errorf("returnTo = %q, want /", returnTo)
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// TrustedProxies sets the client address of requests passed on by a
+// trusted reverse proxy from its X-Forwarded-For or X-Real-IP header. The
+// headers of other requests are ignored, so clients cannot pick the address
+// that rate limits and the audit log see. The proxies can be replaced while
+// the server runs.
+type TrustedProxies struct {
+	current atomic.Pointer[[]netip.Prefix]
+}
+
+// ParseTrustedProxies parses a list of IP addresses and CIDR ranges
+func ParseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy range %q", entry)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy address %q", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// NewTrustedProxies creates the client address handling for proxies
+func NewTrustedProxies(proxies []netip.Prefix) *TrustedProxies {
+	t := &TrustedProxies{}
+	t.Set(proxies)
+	return t
+}
+
+// Set replaces the proxies
+func (t *TrustedProxies) Set(proxies []netip.Prefix) {
+	t.current.Store(&proxies)
+}
+
+// Handler replaces the remote address of requests from a trusted proxy with
+// the client address it forwarded, and marks them for ViaTrustedProxy
+func (t *TrustedProxies) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxies := *t.current.Load()
+		if !trusted(proxies, ClientIP(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), proxyKey, true))
+		if client, ok := forwardedFor(proxies, r); ok {
+			r.RemoteAddr = client
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ViaTrustedProxy reports whether a request was passed on by a trusted
+// proxy, whose other forwarded headers, such as X-Forwarded-Proto, can then
+// be believed
+func ViaTrustedProxy(r *http.Request) bool {
+	via, _ := r.Context().Value(proxyKey).(bool)
+	return via
+}
+
+// forwardedFor returns the client address forwarded by a trusted proxy.
+// X-Forwarded-For is read from the right, skipping trusted proxies, since
+// entries left of the first untrusted one may have been made up by the
+// client.
+func forwardedFor(proxies []netip.Prefix, r *http.Request) (string, bool) {
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		client = hop
+		if !trusted(proxies, hop) {
+			break
+		}
+	}
+	if client == "" {
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			if _, err := netip.ParseAddr(realIP); err == nil {
+				client = realIP
+			}
+		}
+	}
+	return client, client != ""
+}
+
+// trusted reports whether an address is one of the proxies
+func trusted(proxies []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client. Requests from a trusted proxy
+// carry the address it forwarded once TrustedProxies has run.
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
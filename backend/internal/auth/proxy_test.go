@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		realIP    string
+		wantIP    string
+		wantProxy bool
+	}{
+		{"direct client", "192.0.2.7:5000", "", "", "192.0.2.7", false},
+		{"made-up header from a client", "192.0.2.7:5000", "203.0.113.9", "", "192.0.2.7", false},
+		{"trusted proxy", "10.0.0.2:5000", "203.0.113.9", "", "203.0.113.9", true},
+		{"chain of proxies", "10.0.0.2:5000", "198.51.100.1, 203.0.113.9, 10.0.0.3", "", "203.0.113.9", true},
+		{"real IP header", "[::1]:5000", "", "203.0.113.9", "203.0.113.9", true},
+		{"trusted proxy without headers", "10.0.0.2:5000", "", "", "10.0.0.2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/status", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			var ip string
+			var via bool
+			NewTrustedProxies(proxies).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip, via = ClientIP(r), ViaTrustedProxy(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			if ip != tt.wantIP || via != tt.wantProxy {
+				t.Errorf("client %s via proxy %v, want %s and %v", ip, via, tt.wantIP, tt.wantProxy)
+			}
+		})
+	}
+
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseTrustedProxies accepted an invalid range")
+	}
+}
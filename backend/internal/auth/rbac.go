@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// Anonymous is the user of every request when authentication is disabled.
+// It holds the admin role, so disabling authentication disables
+// authorization too.
+var Anonymous = models.User{
+	Username: "anonymous",
+	Source:   models.UserSourceLocal,
+	Roles:    []models.RoleGrant{{Role: models.RoleAdmin}},
+}
+
+// AllowAnonymous runs every request as Anonymous
+func AllowAnonymous(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := Anonymous
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), &user)))
+	})
+}
+
+// RequireRole returns middleware rejecting users that do not hold role on
+// every cluster with 403
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return require(func(u *models.User, r *http.Request) (bool, string) {
+		return u.HasRole(role, ""), fmt.Sprintf("The %s role is required on every cluster", role)
+	})
+}
+
+// RequireAnyRole returns middleware rejecting users that do not hold role
+// on at least one cluster with 403
+func RequireAnyRole(role string) func(http.Handler) http.Handler {
+	return require(func(u *models.User, r *http.Request) (bool, string) {
+		return u.HasAnyRole(role), fmt.Sprintf("The %s role is required on at least one cluster", role)
+	})
+}
+
+// RequireClusterRole returns middleware rejecting users that do not hold
+// role on the cluster named by the URL parameter with 403
+func RequireClusterRole(role, param string) func(http.Handler) http.Handler {
+	return require(func(u *models.User, r *http.Request) (bool, string) {
+		cluster := chi.URLParam(r, param)
+		return u.HasRole(role, cluster), fmt.Sprintf("The %s role is required on cluster %s", role, cluster)
+	})
+}
+
+// RequireScope returns middleware for routes meant for API tokens. Token
+// requests need scope and an owner holding role on at least one cluster;
+// client certificates only hold the ingest scope; other requests need role
+// on at least one cluster. Handlers check the cluster they act on with
+// CanScope, or CanCertificate for certificate requests.
+func RequireScope(scope, role string) func(http.Handler) http.Handler {
+	return require(func(u *models.User, r *http.Request) (bool, string) {
+		if _, ok := CertificateFromContext(r.Context()); ok {
+			return scope == models.ScopeIngest, fmt.Sprintf("Client certificates do not grant the %s scope", scope)
+		}
+		if token, ok := TokenFromContext(r.Context()); ok {
+			if !token.HasScope(scope) {
+				return false, fmt.Sprintf("The API token needs the %s scope", scope)
+			}
+			owner, ok := tokenOwnerFromContext(r.Context())
+			return ok && owner.HasAnyRole(role), fmt.Sprintf("The owner of the API token needs the %s role on at least one cluster", role)
+		}
+		return u.HasAnyRole(role), fmt.Sprintf("The %s role is required on at least one cluster", role)
+	})
+}
+
+// CanScope reports whether a request passed by RequireScope may act with
+// role on the cluster. Tokens are checked against their owner's roles.
+// Client certificates hold no roles, so they are refused here and checked
+// with CanCertificate instead. An empty cluster asks for a grant covering
+// every cluster.
+func CanScope(ctx context.Context, role, cluster string) bool {
+	if _, ok := CertificateFromContext(ctx); ok {
+		return false
+	}
+	if _, ok := TokenFromContext(ctx); ok {
+		owner, ok := tokenOwnerFromContext(ctx)
+		return ok && owner.HasRole(role, cluster)
+	}
+	return Can(ctx, role, cluster)
+}
+
+// TokenScopesAllowed reports whether a user may create a personal token
+// with scopes: the ingest and admin scopes need the cluster-admin role on at
+// least one cluster, so a token never does more than its owner could
+func TokenScopesAllowed(user *models.User, scopes []string) (bool, string) {
+	for _, s := range scopes {
+		if (s == models.ScopeIngest || s == models.ScopeAdmin) && !user.HasAnyRole(models.RoleClusterAdmin) {
+			return false, fmt.Sprintf("The %s scope needs the %s role on at least one cluster", s, models.RoleClusterAdmin)
+		}
+	}
+	return true, ""
+}
+
+// EnforceScopes returns 403 for API token requests outside the token's
+// scopes: reading needs the read or admin scope, anything else needs admin
+func EnforceScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := TokenFromContext(r.Context())
+		if !ok || token.HasScope(models.ScopeAdmin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		safe := r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+		if !safe {
+			Forbidden(w, "The API token needs the admin scope for changes")
+			return
+		}
+		if !token.HasScope(models.ScopeRead) {
+			Forbidden(w, "The API token needs the read scope")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// require builds authorization middleware from a check returning whether the
+// user is allowed and the message explaining a refusal
+func require(check func(u *models.User, r *http.Request) (bool, string)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				Forbidden(w, "No user is signed in")
+				return
+			}
+			if allowed, message := check(user, r); !allowed {
+				Forbidden(w, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Can reports whether the user of ctx holds role on the cluster; an empty
+// cluster asks for a grant covering every cluster
+func Can(ctx context.Context, role, cluster string) bool {
+	user, ok := UserFromContext(ctx)
+	return ok && user.HasRole(role, cluster)
+}
+
+// Forbidden writes a 403 response in the error envelope of the API
+func Forbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   "Forbidden",
+		"message": message,
+	})
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// grants returns a user holding role grants given as role, cluster pairs
+func grants(pairs ...string) *models.User {
+	user := &models.User{Username: "alice", Roles: []models.RoleGrant{}}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		user.Roles = append(user.Roles, models.RoleGrant{Role: pairs[i], Cluster: pairs[i+1]})
+	}
+	return user
+}
+
+// serve runs a request through middleware, as user when not nil, and
+// returns the status code
+func serve(middleware func(http.Handler) http.Handler, r *http.Request, user *models.User) int {
+	if user != nil {
+		r = r.WithContext(WithUser(r.Context(), user))
+	}
+	rec := httptest.NewRecorder()
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(rec, r)
+	return rec.Code
+}
+
+func TestUserHasRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    *models.User
+		role    string
+		cluster string
+		want    bool
+	}{
+		{"global grant covers a cluster", grants(models.RoleViewer, ""), models.RoleViewer, "asuka", true},
+		{"global grant covers every cluster", grants(models.RoleViewer, ""), models.RoleViewer, "", true},
+		{"cluster grant covers its cluster", grants(models.RoleViewer, "asuka"), models.RoleViewer, "asuka", true},
+		{"cluster grant does not cover another", grants(models.RoleViewer, "asuka"), models.RoleViewer, "naruko", false},
+		{"cluster grant does not cover every cluster", grants(models.RoleViewer, "asuka"), models.RoleViewer, "", false},
+		{"higher role includes lower", grants(models.RoleClusterAdmin, "asuka"), models.RoleViewer, "asuka", true},
+		{"lower role excludes higher", grants(models.RoleViewer, ""), models.RoleClusterAdmin, "asuka", false},
+		{"admin includes everything", grants(models.RoleAdmin, ""), models.RoleClusterAdmin, "asuka", true},
+		{"unknown role grants nothing", grants("operator", ""), models.RoleViewer, "asuka", false},
+		{"no grants", grants(), models.RoleViewer, "asuka", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.HasRole(tt.role, tt.cluster); got != tt.want {
+				t.Errorf("HasRole(%q, %q) = %v, want %v", tt.role, tt.cluster, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		user       *models.User
+		want       int
+	}{
+		{"every cluster", RequireRole(models.RoleViewer), grants(models.RoleViewer, ""), http.StatusOK},
+		{"one cluster is not every cluster", RequireRole(models.RoleViewer), grants(models.RoleViewer, "asuka"), http.StatusForbidden},
+		{"any cluster", RequireAnyRole(models.RoleClusterAdmin), grants(models.RoleClusterAdmin, "asuka"), http.StatusOK},
+		{"any cluster with a lower role", RequireAnyRole(models.RoleClusterAdmin), grants(models.RoleViewer, ""), http.StatusForbidden},
+		{"no user", RequireAnyRole(models.RoleViewer), nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if got := serve(tt.middleware, r, tt.user); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireClusterRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    *models.User
+		cluster string
+		want    int
+	}{
+		{"granted cluster", grants(models.RoleViewer, "asuka"), "asuka", http.StatusOK},
+		{"other cluster", grants(models.RoleViewer, "asuka"), "naruko", http.StatusForbidden},
+		{"global grant", grants(models.RoleViewer, ""), "naruko", http.StatusOK},
+		{"higher role on the cluster", grants(models.RoleClusterAdmin, "naruko"), "naruko", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Route through chi so that the URL parameter is set
+			router := chi.NewRouter()
+			router.With(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), tt.user)))
+				})
+			}, RequireClusterRole(models.RoleViewer, "name")).Get("/clusters/{name}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clusters/"+tt.cluster, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
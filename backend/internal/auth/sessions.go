@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// sessionsKey is the storage key holding the active sessions
+const sessionsKey = "auth_sessions"
+
+// CookieName is the name of the session cookie
+const CookieName = "csm_session"
+
+// ErrInvalidSession is returned for missing, tampered, expired and revoked
+// session cookies
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// Sessions issues and checks session cookies. A cookie holds the session
+// ID and expiry signed with HMAC-SHA256; the session must also still be
+// stored, so logging out revokes it on the server.
+type Sessions struct {
+	storage storage.Storage
+	secret  []byte
+	TTL     time.Duration // Lifetime of a session
+	Secure  bool          // Send the cookie over HTTPS only
+	mu      sync.Mutex
+}
+
+// NewSessions creates a session manager signing cookies with secret
+func NewSessions(store storage.Storage, secret []byte) *Sessions {
+	return &Sessions{storage: store, secret: secret, TTL: 12 * time.Hour}
+}
+
+// RandomSecret returns a random signing secret, for when none is configured.
+// Sessions signed with it do not survive a restart.
+func RandomSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Create starts a session for a user and sets its cookie on the response
+func (s *Sessions) Create(w http.ResponseWriter, username string) (*models.Session, error) {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	session := models.Session{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.TTL).Truncate(time.Second),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	active := sessions[:0]
+	for _, existing := range sessions {
+		if existing.ExpiresAt.After(now) {
+			active = append(active, existing)
+		}
+	}
+	active = append(active, session)
+	if err := storage.SetData(s.storage, sessionsKey, active); err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    s.sign(session.ID, session.ExpiresAt),
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   s.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return &session, nil
+}
+
+// Validate returns the session of the request cookie
+func (s *Sessions) Validate(r *http.Request) (*models.Session, error) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	id, ok := s.verify(cookie.Value)
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, session := range sessions {
+		if session.ID == id && session.ExpiresAt.After(now) {
+			return &session, nil
+		}
+	}
+	return nil, ErrInvalidSession
+}
+
+// Revoke ends the session of the request, if any, and clears its cookie
+func (s *Sessions) Revoke(w http.ResponseWriter, r *http.Request) error {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return nil
+	}
+	id, ok := s.verify(cookie.Value)
+	if !ok {
+		return nil
+	}
+	return s.remove(func(session models.Session) bool { return session.ID == id })
+}
+
+// RevokeUser ends every session of a user
+func (s *Sessions) RevokeUser(username string) error {
+	return s.remove(func(session models.Session) bool { return session.Username == username })
+}
+
+// remove deletes the sessions matching fn
+func (s *Sessions) remove(fn func(models.Session) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return err
+	}
+	kept := sessions[:0]
+	for _, session := range sessions {
+		if !fn(session) {
+			kept = append(kept, session)
+		}
+	}
+	if len(kept) == len(sessions) {
+		return nil
+	}
+	return storage.SetData(s.storage, sessionsKey, kept)
+}
+
+// sign returns the cookie value "id.expiry.signature"
+func (s *Sessions) sign(id string, expires time.Time) string {
+	payload := id + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + s.mac(payload)
+}
+
+// verify checks the signature and expiry of a cookie value and returns the
+// session ID
+func (s *Sessions) verify(value string) (string, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.mac(payload))) {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// mac returns the base64 HMAC-SHA256 of payload
+func (s *Sessions) mac(payload string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// load reads the sessions from storage
+func (s *Sessions) load() ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := storage.GetData(s.storage, sessionsKey, &sessions)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+	return sessions, nil
+}
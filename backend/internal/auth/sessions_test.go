@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// newTestStorage returns an empty JSON storage in a temporary directory
+func newTestStorage(t *testing.T) storage.Storage {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	return store
+}
+
+// sessionCookie starts a session and returns its cookie
+func sessionCookie(t *testing.T, s *Sessions, username string) *http.Cookie {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if _, err := s.Create(rec, username); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CookieName {
+			return c
+		}
+	}
+	t.Fatal("Create set no session cookie")
+	return nil
+}
+
+// requestWithCookie returns a request carrying a session cookie value
+func requestWithCookie(value string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	if value != "" {
+		r.AddCookie(&http.Cookie{Name: CookieName, Value: value})
+	}
+	return r
+}
+
+func TestSessionsValidate(t *testing.T) {
+	store := newTestStorage(t)
+	sessions := NewSessions(store, []byte("secret"))
+	valid := sessionCookie(t, sessions, "alice").Value
+	parts := strings.Split(valid, ".")
+
+	other := NewSessions(store, []byte("another secret"))
+	// The signature of a later expiry, made with the wrong key
+	extended := parts[0] + "." + strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10)
+	forged := extended + "." + other.mac(extended)
+
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"valid", valid, true},
+		{"no cookie", "", false},
+		{"malformed", "not-a-session", false},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), false},
+		{"extended expiry", extended + "." + parts[2], false},
+		{"signed with another key", forged, false},
+		{"unknown session", sessions.sign("unknown", time.Now().Add(time.Hour)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := sessions.Validate(requestWithCookie(tt.value))
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				if session.Username != "alice" {
+					t.Errorf("username = %q, want alice", session.Username)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidSession) {
+				t.Errorf("Validate error = %v, want ErrInvalidSession", err)
+			}
+		})
+	}
+}
+
+func TestSessionsExpiry(t *testing.T) {
+	sessions := NewSessions(newTestStorage(t), []byte("secret"))
+	sessions.TTL = -time.Minute
+	cookie := sessionCookie(t, sessions, "alice")
+
+	if _, err := sessions.Validate(requestWithCookie(cookie.Value)); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Validate of an expired session = %v, want ErrInvalidSession", err)
+	}
+	if !cookie.Expires.Before(time.Now()) {
+		t.Errorf("cookie expires %v, want in the past", cookie.Expires)
+	}
+}
+
+func TestSessionsRevoke(t *testing.T) {
+	sessions := NewSessions(newTestStorage(t), []byte("secret"))
+	first := sessionCookie(t, sessions, "alice").Value
+	second := sessionCookie(t, sessions, "alice").Value
+	bob := sessionCookie(t, sessions, "bob").Value
+
+	if err := sessions.Revoke(httptest.NewRecorder(), requestWithCookie(first)); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := sessions.Validate(requestWithCookie(first)); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("revoked session is still valid: %v", err)
+	}
+	if _, err := sessions.Validate(requestWithCookie(second)); err != nil {
+		t.Errorf("other session of the user was revoked: %v", err)
+	}
+
+	if err := sessions.RevokeUser("alice"); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	if _, err := sessions.Validate(requestWithCookie(second)); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("session of a revoked user is still valid: %v", err)
+	}
+	if _, err := sessions.Validate(requestWithCookie(bob)); err != nil {
+		t.Errorf("session of another user was revoked: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// tokensKey is the storage key holding the API tokens
+const tokensKey = "auth_tokens"
+
+// tokenPrefix starts every API token, so leaked tokens are easy to search for
+const tokenPrefix = "csm_"
+
+var (
+	// ErrTokenNotFound is returned for unknown API tokens
+	ErrTokenNotFound = errors.New("API token not found")
+	// ErrInvalidToken is returned for API tokens that fail validation
+	ErrInvalidToken = errors.New("invalid API token")
+)
+
+// tokenRecord is a stored API token with the SHA-256 hash of its secret.
+// Secrets are random, so a fast hash is enough to make a leaked store
+// useless.
+type tokenRecord struct {
+	models.APIToken
+	Hash string `json:"hash"`
+}
+
+// Tokens stores API tokens hashed
+type Tokens struct {
+	storage     storage.Storage
+	DefaultTTL  time.Duration // Lifetime of tokens created without an expiry; 0 never expires them
+	UsedEvery   time.Duration // How often last-used times are written, to spare storage
+	mu          sync.Mutex
+	lastWritten map[string]time.Time
+}
+
+// NewTokens creates an API token store
+func NewTokens(store storage.Storage) *Tokens {
+	return &Tokens{
+		storage:     store,
+		DefaultTTL:  90 * 24 * time.Hour,
+		UsedEvery:   time.Minute,
+		lastWritten: make(map[string]time.Time),
+	}
+}
+
+// List returns the tokens of an owner, or every token for an empty owner,
+// newest first
+func (t *Tokens) List(owner string) ([]models.APIToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	tokens := []models.APIToken{}
+	for _, r := range records {
+		if owner == "" || r.Owner == owner {
+			tokens = append(tokens, r.APIToken)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// Get returns one token
+func (t *Tokens) Get(id string) (*models.APIToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if r.ID == id {
+			return &r.APIToken, nil
+		}
+	}
+	return nil, ErrTokenNotFound
+}
+
+// Create stores a new token and returns it with its secret, which is not
+// kept. Name, Kind, Owner, Scopes and ExpiresAt are taken from token.
+func (t *Tokens) Create(token models.APIToken) (*models.APIToken, string, error) {
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidToken)
+	}
+	if token.Kind != models.TokenKindPersonal && token.Kind != models.TokenKindService {
+		return nil, "", fmt.Errorf("%w: kind must be %s or %s", ErrInvalidToken, models.TokenKindPersonal, models.TokenKindService)
+	}
+	if token.Owner == "" {
+		return nil, "", fmt.Errorf("%w: owner is required", ErrInvalidToken)
+	}
+	if len(token.Scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidToken)
+	}
+	for _, s := range token.Scopes {
+		if s != models.ScopeRead && s != models.ScopeIngest && s != models.ScopeAdmin {
+			return nil, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidToken, s)
+		}
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt == nil && t.DefaultTTL > 0 {
+		expires := now.Add(t.DefaultTTL)
+		token.ExpiresAt = &expires
+	}
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return nil, "", fmt.Errorf("%w: expiry must be in the future", ErrInvalidToken)
+	}
+
+	id := make([]byte, 9)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	raw := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	token.ID = base64.RawURLEncoding.EncodeToString(id)
+	token.Prefix = raw[:len(tokenPrefix)+6]
+	token.CreatedAt = now
+	token.LastUsedAt = nil
+	token.LastUsedIP = ""
+	token.RevokedAt = nil
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records, err := t.load()
+	if err != nil {
+		return nil, "", err
+	}
+	records = append(records, tokenRecord{APIToken: token, Hash: hashToken(raw)})
+	if err := t.save(records); err != nil {
+		return nil, "", err
+	}
+	return &token, raw, nil
+}
+
+// Revoke makes a token unusable. Revoked tokens stay listed.
+func (t *Tokens) Revoke(id string) (*models.APIToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ID == id {
+			if records[i].RevokedAt == nil {
+				now := time.Now().UTC()
+				records[i].RevokedAt = &now
+				if err := t.save(records); err != nil {
+					return nil, err
+				}
+			}
+			return &records[i].APIToken, nil
+		}
+	}
+	return nil, ErrTokenNotFound
+}
+
+// Authenticate returns the active token matching a bearer secret and records
+// its use. Unknown, revoked and expired tokens give ErrInvalidCredentials.
+func (t *Tokens) Authenticate(raw, ip string) (*models.APIToken, error) {
+	if !strings.HasPrefix(raw, tokenPrefix) {
+		return nil, ErrInvalidCredentials
+	}
+	hash := hashToken(raw)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for i := range records {
+		r := &records[i]
+		if r.Hash != hash {
+			continue
+		}
+		if !r.Active(now) {
+			return nil, ErrInvalidCredentials
+		}
+
+		r.LastUsedAt = &now
+		r.LastUsedIP = ip
+		if now.Sub(t.lastWritten[r.ID]) >= t.UsedEvery {
+			t.lastWritten[r.ID] = now
+			if err := t.save(records); err != nil {
+				log.Printf("Failed to record use of API token %s: %v", r.ID, err)
+			}
+		}
+		return &r.APIToken, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// hashToken returns the hex SHA-256 of a token secret
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// load reads the stored tokens
+func (t *Tokens) load() ([]tokenRecord, error) {
+	records := []tokenRecord{}
+	err := storage.GetData(t.storage, tokensKey, &records)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load API tokens: %w", err)
+	}
+	return records, nil
+}
+
+// save writes the tokens
+func (t *Tokens) save(records []tokenRecord) error {
+	return storage.SetData(t.storage, tokensKey, records)
+}
@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// usersKey is the storage key holding the local users
+const usersKey = "auth_users"
+
+// minPasswordLength is the shortest password accepted for local users
+const minPasswordLength = 8
+
+var (
+	// ErrUserNotFound is returned for unknown users
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidUser is returned for users and passwords that fail validation
+	ErrInvalidUser = errors.New("invalid user")
+	// ErrInvalidCredentials is returned when a username and password do not match
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// dummyHash is compared against when a user does not exist, so unknown and
+// known users take the same time to reject
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("cluster-status-monitor"), bcrypt.DefaultCost)
+
+// userRecord is a stored user with its password hash, which never leaves
+// this package
+type userRecord struct {
+	models.User
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// Users stores the users that can sign in: local users with bcrypt password
+// hashes, and directory users recorded when they log in. It is also the
+// Authenticator of the local users.
+type Users struct {
+	storage storage.Storage
+	Cost    int // bcrypt cost of new hashes
+	mu      sync.Mutex
+}
+
+// NewUsers creates a local user store
+func NewUsers(store storage.Storage) *Users {
+	return &Users{storage: store, Cost: bcrypt.DefaultCost}
+}
+
+// List returns every user ordered by username
+func (u *Users) List() ([]models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	records, err := u.load()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]models.User, 0, len(records))
+	for _, r := range records {
+		users = append(users, r.User)
+	}
+	return users, nil
+}
+
+// Get returns one user
+func (u *Users) Get(username string) (*models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	records, err := u.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if r.Username == username {
+			return &r.User, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// Put creates or updates a local user with its roles. The password is
+// required for new users and replaces the current one when set. Directory
+// users cannot be changed; their details come from the directory.
+func (u *Users) Put(user models.User, password string) (*models.User, error) {
+	if !usernamePattern.MatchString(user.Username) {
+		return nil, fmt.Errorf("%w: invalid username %q", ErrInvalidUser, user.Username)
+	}
+	if user.Roles == nil {
+		user.Roles = []models.RoleGrant{}
+	}
+	for _, g := range user.Roles {
+		if err := ValidateGrant(g); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUser, err)
+		}
+	}
+	var hash string
+	if password != "" {
+		if len(password) < minPasswordLength {
+			return nil, fmt.Errorf("%w: password must be at least %d characters", ErrInvalidUser, minPasswordLength)
+		}
+		h, err := bcrypt.GenerateFromPassword([]byte(password), u.Cost)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUser, err)
+		}
+		hash = string(h)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	records, err := u.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	user.Name = strings.TrimSpace(user.Name)
+	user.Email = strings.TrimSpace(user.Email)
+	user.Source = models.UserSourceLocal
+	user.Groups = nil
+	user.UpdatedAt = now
+	for i := range records {
+		if records[i].Username != user.Username {
+			continue
+		}
+		if records[i].Source != models.UserSourceLocal {
+			return nil, fmt.Errorf("%w: %s is managed by %s", ErrInvalidUser, user.Username, records[i].Source)
+		}
+		user.CreatedAt = records[i].CreatedAt
+		user.LastLogin = records[i].LastLogin
+		records[i].User = user
+		if hash != "" {
+			records[i].PasswordHash = hash
+		}
+		if err := u.save(records); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	if hash == "" {
+		return nil, fmt.Errorf("%w: password is required", ErrInvalidUser)
+	}
+	user.CreatedAt = now
+	user.LastLogin = nil
+	records = append(records, userRecord{User: user, PasswordHash: hash})
+	if err := u.save(records); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Delete removes a user
+func (u *Users) Delete(username string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	records, err := u.load()
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		if records[i].Username == username {
+			records = append(records[:i], records[i+1:]...)
+			return u.save(records)
+		}
+	}
+	return ErrUserNotFound
+}
+
+// Name returns the source of local users
+func (u *Users) Name() string {
+	return models.UserSourceLocal
+}
+
+// Authenticate checks the password of a local user
+func (u *Users) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	u.mu.Lock()
+	records, err := u.load()
+	u.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		if r.Username != username || r.PasswordHash == "" {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(r.PasswordHash), []byte(password)) != nil {
+			return nil, ErrInvalidCredentials
+		}
+		return &Identity{Username: r.Username, Name: r.Name, Email: r.Email, Source: models.UserSourceLocal}, nil
+	}
+
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+	return nil, ErrInvalidCredentials
+}
+
+// Login records the login of an authenticated identity and returns its
+// user. Directory users are created or updated with their details and the
+// given roles; they cannot take over a user of another source.
+func (u *Users) Login(id *Identity, roles []models.RoleGrant) (*models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	records, err := u.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	i := -1
+	for idx := range records {
+		if records[idx].Username == id.Username {
+			i = idx
+		}
+	}
+	switch {
+	case i >= 0 && records[i].Source != id.Source:
+		log.Printf("Rejected %s login of %s: the user belongs to %s", id.Source, id.Username, records[i].Source)
+		return nil, ErrInvalidCredentials
+	case i < 0 && id.Source == models.UserSourceLocal:
+		return nil, ErrUserNotFound
+	case i < 0:
+		records = append(records, userRecord{User: models.User{Username: id.Username, Source: id.Source, CreatedAt: now}})
+		i = len(records) - 1
+	}
+
+	r := &records[i]
+	if id.Source != models.UserSourceLocal {
+		r.Name = id.Name
+		r.Email = id.Email
+		r.Groups = id.Groups
+		r.Roles = roles
+		r.UpdatedAt = now
+	}
+	if r.Roles == nil {
+		r.Roles = []models.RoleGrant{}
+	}
+	r.LastLogin = &now
+	user := r.User
+
+	if err := u.save(records); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// load reads the users from storage
+func (u *Users) load() ([]userRecord, error) {
+	records := []userRecord{}
+	err := storage.GetData(u.storage, usersKey, &records)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	return records, nil
+}
+
+// save writes the users ordered by username
+func (u *Users) save(records []userRecord) error {
+	sort.Slice(records, func(i, j int) bool { return records[i].Username < records[j].Username })
+	return storage.SetData(u.storage, usersKey, records)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUsersAuthenticate(t *testing.T) {
+	store := newTestStorage(t)
+	users := NewUsers(store)
+	users.Cost = bcrypt.MinCost
+	if _, err := users.Put(models.User{Username: "alice", Source: models.UserSourceLocal}, "correct horse"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// Directory users are stored without a password
+	if _, err := users.Login(&Identity{Username: "bob", Source: models.UserSourceLDAP}, nil); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		ok       bool
+	}{
+		{"correct password", "alice", "correct horse", true},
+		{"wrong password", "alice", "battery staple", false},
+		{"empty password", "alice", "", false},
+		{"unknown user", "mallory", "correct horse", false},
+		{"directory user", "bob", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := users.Authenticate(context.Background(), tt.username, tt.password)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Authenticate: %v", err)
+				}
+				if id.Username != tt.username || id.Source != models.UserSourceLocal {
+					t.Errorf("identity = %+v", id)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Authenticate error = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+
+	// Only the bcrypt hash is stored
+	raw, err := store.Get(usersKey)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := json.Marshal(raw)
+	if strings.Contains(string(data), "correct horse") || !strings.Contains(string(data), "$2a$") {
+		t.Errorf("stored users do not hold a bcrypt hash only: %s", data)
+	}
+}
+
+func TestUsersPutValidation(t *testing.T) {
+	users := NewUsers(newTestStorage(t))
+	users.Cost = bcrypt.MinCost
+
+	tests := []struct {
+		name     string
+		user     models.User
+		password string
+	}{
+		{"short password", models.User{Username: "alice"}, "short"},
+		{"invalid username", models.User{Username: "alice smith"}, "long enough"},
+		{"unknown role", models.User{Username: "alice", Roles: []models.RoleGrant{{Role: "operator"}}}, "long enough"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := users.Put(tt.user, tt.password); !errors.Is(err, ErrInvalidUser) {
+				t.Errorf("Put error = %v, want ErrInvalidUser", err)
+			}
+		})
+	}
+}
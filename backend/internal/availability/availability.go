@@ -0,0 +1,182 @@
+package availability
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// ErrInvalidWindow is returned when a window does not start in the past and
+// end after it starts
+var ErrInvalidWindow = errors.New("invalid window, end must be after start")
+
+// Calculator computes node availability from the state transitions recorded
+// by the node inventory. Availability is the percent of the observed time a
+// node was online; maintenance counts neither as up nor as down, and time
+// before a node was first seen is not observed.
+type Calculator struct {
+	inventory *inventory.Inventory
+	Lookback  time.Duration // How far before a window the state at its start is searched
+}
+
+// NewCalculator creates an availability calculator
+func NewCalculator(inv *inventory.Inventory) *Calculator {
+	return &Calculator{inventory: inv, Lookback: 365 * 24 * time.Hour}
+}
+
+// Nodes returns the availability of the nodes of a cluster, or of every
+// node when cluster is empty, ordered by cluster and name
+func (c *Calculator) Nodes(cluster string, start, end time.Time) ([]models.Availability, error) {
+	if now := time.Now(); end.After(now) {
+		// The current state is not assumed to last into the future
+		end = now
+	}
+	if !end.After(start) {
+		return nil, ErrInvalidWindow
+	}
+
+	transitions, err := c.inventory.Transitions(start, end, c.Lookback)
+	if err != nil {
+		return nil, err
+	}
+
+	byNode := make(map[string][]models.NodeTransition)
+	for _, t := range transitions {
+		if cluster == "" || t.Cluster == cluster {
+			byNode[t.Node] = append(byNode[t.Node], t)
+		}
+	}
+
+	result := make([]models.Availability, 0, len(byNode))
+	for node, ts := range byNode {
+		result = append(result, nodeAvailability(node, ts, start.UTC(), end.UTC()))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cluster != result[j].Cluster {
+			return result[i].Cluster < result[j].Cluster
+		}
+		return result[i].Node < result[j].Node
+	})
+	return result, nil
+}
+
+// Clusters returns the availability of every cluster with recorded nodes,
+// or of one cluster when cluster is set
+func (c *Calculator) Clusters(cluster string, start, end time.Time) ([]models.Availability, error) {
+	nodes, err := c.Nodes(cluster, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.Availability
+	for _, n := range nodes {
+		if len(result) == 0 || result[len(result)-1].Cluster != n.Cluster {
+			result = append(result, models.Availability{Cluster: n.Cluster, Start: n.Start, End: n.End})
+		}
+		a := &result[len(result)-1]
+		a.Nodes++
+		a.UptimeSeconds += n.UptimeSeconds
+		a.DowntimeSeconds += n.DowntimeSeconds
+		a.MaintenanceSeconds += n.MaintenanceSeconds
+		a.Outages += n.Outages
+	}
+	for i := range result {
+		summarize(&result[i])
+	}
+	if result == nil {
+		result = []models.Availability{}
+	}
+	return result, nil
+}
+
+// Flakiest returns up to limit nodes with outages in the window, most
+// outages first and least available first among equals
+func (c *Calculator) Flakiest(cluster string, start, end time.Time, limit int) ([]models.Availability, error) {
+	nodes, err := c.Nodes(cluster, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.Availability{}
+	for _, n := range nodes {
+		if n.Outages > 0 {
+			result = append(result, n)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Outages != result[j].Outages {
+			return result[i].Outages > result[j].Outages
+		}
+		return percent(result[i]) < percent(result[j])
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// nodeAvailability walks the transitions of one node, oldest first, and sums
+// the time spent in each state within [start, end)
+func nodeAvailability(node string, transitions []models.NodeTransition, start, end time.Time) models.Availability {
+	a := models.Availability{Cluster: transitions[len(transitions)-1].Cluster, Node: node, Start: start, End: end}
+
+	state := ""
+	since := start
+	add := func(until time.Time) {
+		seconds := until.Sub(since).Seconds()
+		switch state {
+		case models.NodeStateOnline:
+			a.UptimeSeconds += seconds
+		case models.NodeStateOffline:
+			a.DowntimeSeconds += seconds
+		case models.NodeStateMaintenance:
+			a.MaintenanceSeconds += seconds
+		}
+	}
+
+	i := 0
+	for ; i < len(transitions) && transitions[i].At.Before(start); i++ {
+		state = transitions[i].To
+	}
+	if state == models.NodeStateOffline {
+		// Down at the start of the window: the outage overlaps it
+		a.Outages++
+	}
+	for _, t := range transitions[i:] {
+		add(t.At)
+		if t.To == models.NodeStateOffline && state != models.NodeStateOffline {
+			a.Outages++
+		}
+		state = t.To
+		since = t.At
+	}
+	add(end)
+
+	summarize(&a)
+	return a
+}
+
+// summarize derives the availability, MTBF and MTTR from the totals
+func summarize(a *models.Availability) {
+	if observed := a.UptimeSeconds + a.DowntimeSeconds; observed > 0 {
+		p := a.UptimeSeconds / observed * 100
+		a.Availability = &p
+	}
+	if a.Outages > 0 {
+		mtbf := a.UptimeSeconds / float64(a.Outages)
+		mttr := a.DowntimeSeconds / float64(a.Outages)
+		a.MTBFSeconds = &mtbf
+		a.MTTRSeconds = &mttr
+	}
+}
+
+// percent returns the availability, treating unknown as fully available
+func percent(a models.Availability) float64 {
+	if a.Availability == nil {
+		return 100
+	}
+	return *a.Availability
+}
@@ -0,0 +1,181 @@
+package availability
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+const hour = 3600.0
+
+// at returns a transition of asuka01 to state, hours after start
+func at(start time.Time, hours float64, to string) models.NodeTransition {
+	return models.NodeTransition{Node: "asuka01", Cluster: "asuka", To: to, At: start.Add(time.Duration(hours * float64(time.Hour)))}
+}
+
+func TestNodeAvailability(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(100 * time.Hour)
+	online, offline, maintenance := models.NodeStateOnline, models.NodeStateOffline, models.NodeStateMaintenance
+
+	tests := []struct {
+		name            string
+		transitions     []models.NodeTransition
+		up, down, maint float64 // Hours
+		outages         int
+		availability    float64 // Negative expects none
+		mtbf, mttr      float64 // Hours; checked with outages
+	}{
+		{
+			name:         "online throughout",
+			transitions:  []models.NodeTransition{at(start, -24, online)},
+			up:           100,
+			availability: 100,
+		},
+		{
+			name:         "outage at window start",
+			transitions:  []models.NodeTransition{at(start, -5, online), at(start, -2, offline), at(start, 10, online)},
+			up:           90,
+			down:         10,
+			outages:      1,
+			availability: 90,
+			mtbf:         90,
+			mttr:         10,
+		},
+		{
+			name:         "two outages",
+			transitions:  []models.NodeTransition{at(start, -1, online), at(start, 20, offline), at(start, 25, online), at(start, 60, offline), at(start, 75, online)},
+			up:           80,
+			down:         20,
+			outages:      2,
+			availability: 80,
+			mtbf:         40,
+			mttr:         10,
+		},
+		{
+			// Maintenance counts neither way, and leaving it for offline is
+			// an outage of its own
+			name:         "maintenance",
+			transitions:  []models.NodeTransition{at(start, -1, online), at(start, 40, maintenance), at(start, 90, offline)},
+			up:           40,
+			down:         10,
+			maint:        50,
+			outages:      1,
+			availability: 80,
+			mtbf:         40,
+			mttr:         10,
+		},
+		{
+			name:         "first seen during the window",
+			transitions:  []models.NodeTransition{at(start, 50, online)},
+			up:           50,
+			availability: 100,
+		},
+		{
+			name:         "only maintenance",
+			transitions:  []models.NodeTransition{at(start, -1, maintenance)},
+			maint:        100,
+			availability: -1,
+		},
+		{
+			name:         "offline state repeated",
+			transitions:  []models.NodeTransition{at(start, -1, offline), at(start, 50, offline)},
+			down:         100,
+			outages:      1,
+			availability: 0,
+			mtbf:         0,
+			mttr:         100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := nodeAvailability("asuka01", tt.transitions, start, end)
+			if a.Cluster != "asuka" || a.Node != "asuka01" || !a.Start.Equal(start) || !a.End.Equal(end) {
+				t.Errorf("availability of %s/%s over %v..%v", a.Cluster, a.Node, a.Start, a.End)
+			}
+			if a.UptimeSeconds != tt.up*hour || a.DowntimeSeconds != tt.down*hour || a.MaintenanceSeconds != tt.maint*hour {
+				t.Errorf("up %v, down %v, maintenance %v seconds, want %v, %v, %v hours", a.UptimeSeconds, a.DowntimeSeconds, a.MaintenanceSeconds, tt.up, tt.down, tt.maint)
+			}
+			if a.Outages != tt.outages {
+				t.Errorf("outages = %d, want %d", a.Outages, tt.outages)
+			}
+			if tt.availability < 0 {
+				if a.Availability != nil {
+					t.Errorf("availability = %v, want none", *a.Availability)
+				}
+			} else if a.Availability == nil || *a.Availability != tt.availability {
+				t.Errorf("availability = %v, want %v", a.Availability, tt.availability)
+			}
+			if tt.outages == 0 {
+				if a.MTBFSeconds != nil || a.MTTRSeconds != nil {
+					t.Errorf("MTBF and MTTR without outages: %v, %v", a.MTBFSeconds, a.MTTRSeconds)
+				}
+				return
+			}
+			if a.MTBFSeconds == nil || *a.MTBFSeconds != tt.mtbf*hour || a.MTTRSeconds == nil || *a.MTTRSeconds != tt.mttr*hour {
+				t.Errorf("MTBF %v, MTTR %v, want %v and %v hours", a.MTBFSeconds, a.MTTRSeconds, tt.mtbf, tt.mttr)
+			}
+		})
+	}
+}
+
+func TestCalculator(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	inv := inventory.New(store, nil)
+	c := NewCalculator(inv)
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	online, offline := models.NodeStateOnline, models.NodeStateOffline
+	nodes := []models.Node{{Name: "asuka01", Cluster: "asuka"}, {Name: "asuka02", Cluster: "asuka"}, {Name: "naruko01", Cluster: "naruko"}}
+	if err := storage.SetData(store, "node_inventory", nodes); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	transitions := []models.NodeTransition{
+		{Node: "asuka01", Cluster: "asuka", To: online, At: start.Add(-time.Hour)},
+		{Node: "asuka02", Cluster: "asuka", To: online, At: start.Add(-time.Hour)},
+		{Node: "naruko01", Cluster: "naruko", To: online, At: start.Add(-time.Hour)},
+		{Node: "asuka02", Cluster: "asuka", From: online, To: offline, At: start.Add(2 * time.Hour)},
+		{Node: "asuka02", Cluster: "asuka", From: offline, To: online, At: start.Add(3 * time.Hour)},
+		{Node: "naruko01", Cluster: "naruko", From: online, To: offline, At: start.Add(4 * time.Hour)},
+		{Node: "asuka02", Cluster: "asuka", From: online, To: offline, At: start.Add(6 * time.Hour)},
+		{Node: "asuka02", Cluster: "asuka", From: offline, To: online, At: start.Add(7 * time.Hour)},
+	}
+	if err := storage.SetData(store, "node_transitions_2024-05", transitions); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+
+	clusters, err := c.Clusters("", start, end)
+	if err != nil {
+		t.Fatalf("Clusters: %v", err)
+	}
+	if len(clusters) != 2 || clusters[0].Cluster != "asuka" || clusters[0].Nodes != 2 || clusters[1].Cluster != "naruko" {
+		t.Fatalf("clusters = %+v", clusters)
+	}
+	if a := clusters[0]; *a.Availability != 90 || a.Outages != 2 || *a.MTTRSeconds != hour {
+		t.Errorf("asuka = %+v", a)
+	}
+
+	flakiest, err := c.Flakiest("", start, end, 0)
+	if err != nil {
+		t.Fatalf("Flakiest: %v", err)
+	}
+	// Most outages first
+	if len(flakiest) != 2 || flakiest[0].Node != "asuka02" || flakiest[1].Node != "naruko01" {
+		t.Errorf("flakiest = %+v", flakiest)
+	}
+	if limited, _ := c.Flakiest("naruko", start, end, 1); len(limited) != 1 || limited[0].Node != "naruko01" {
+		t.Errorf("flakiest of naruko = %+v", limited)
+	}
+
+	if _, err := c.Nodes("", end, start); !errors.Is(err, ErrInvalidWindow) {
+		t.Errorf("Nodes of an inverted window: error = %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package chargeback
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// groupsKey is the storage key holding the research groups
+const groupsKey = "chargeback_groups"
+
+var (
+	// ErrGroupNotFound is returned for unknown research groups
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrInvalidGroup is returned for groups that fail validation
+	ErrInvalidGroup = errors.New("invalid group")
+	// ErrNoGroupFile is returned when importing without a configured file
+	ErrNoGroupFile = errors.New("no group file configured")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Groups stores the research groups and their members. Groups are either
+// managed through the API or imported from an /etc/group format file; an
+// import replaces the imported groups and leaves the manual ones alone.
+type Groups struct {
+	storage storage.Storage
+	File    string // /etc/group format file imported by ImportFile
+	MinGID  int    // Groups below this GID are not imported
+	mu      sync.Mutex
+}
+
+// NewGroups creates a group store
+func NewGroups(store storage.Storage) *Groups {
+	return &Groups{storage: store, MinGID: 1000}
+}
+
+// List returns every group ordered by name
+func (g *Groups) List() ([]models.ResearchGroup, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.load()
+}
+
+// Get returns one group
+func (g *Groups) Get(name string) (*models.ResearchGroup, error) {
+	groups, err := g.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.Name == name {
+			return &group, nil
+		}
+	}
+	return nil, ErrGroupNotFound
+}
+
+// Put creates or replaces a manual group
+func (g *Groups) Put(group models.ResearchGroup) (*models.ResearchGroup, error) {
+	if !namePattern.MatchString(group.Name) {
+		return nil, fmt.Errorf("%w: invalid name %q", ErrInvalidGroup, group.Name)
+	}
+	members, err := normalizeMembers(group.Members)
+	if err != nil {
+		return nil, err
+	}
+	group.Members = members
+	group.Source = models.GroupSourceManual
+	group.UpdatedAt = time.Now().UTC()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	groups, err := g.load()
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := false
+	for i := range groups {
+		if groups[i].Name == group.Name {
+			groups[i] = group
+			replaced = true
+		}
+	}
+	if !replaced {
+		groups = append(groups, group)
+	}
+
+	if err := g.save(groups); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Delete removes a group
+func (g *Groups) Delete(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	groups, err := g.load()
+	if err != nil {
+		return err
+	}
+
+	for i := range groups {
+		if groups[i].Name == name {
+			groups = append(groups[:i], groups[i+1:]...)
+			return g.save(groups)
+		}
+	}
+	return ErrGroupNotFound
+}
+
+// Import replaces the imported groups with the groups of an /etc/group
+// format file whose GID is at least minGID. Groups without members are
+// skipped, as are groups with the name of a manual group. It returns the
+// number of groups imported.
+func (g *Groups) Import(r io.Reader, minGID int) (int, error) {
+	parsed, err := ParseGroupFile(r, minGID)
+	if err != nil {
+		return 0, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	groups, err := g.load()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make([]models.ResearchGroup, 0, len(groups)+len(parsed))
+	manual := make(map[string]bool)
+	for _, group := range groups {
+		if group.Source == models.GroupSourceManual {
+			kept = append(kept, group)
+			manual[group.Name] = true
+		}
+	}
+
+	now := time.Now().UTC()
+	imported := 0
+	for _, group := range parsed {
+		if manual[group.Name] || len(group.Members) == 0 {
+			continue
+		}
+		group.Source = models.GroupSourceImported
+		group.UpdatedAt = now
+		kept = append(kept, group)
+		imported++
+	}
+
+	if err := g.save(kept); err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// ImportFile imports the groups of the configured group file
+func (g *Groups) ImportFile() (int, error) {
+	if g.File == "" {
+		return 0, ErrNoGroupFile
+	}
+
+	f, err := os.Open(g.File)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open group file: %w", err)
+	}
+	defer f.Close()
+
+	return g.Import(f, g.MinGID)
+}
+
+// Membership maps each user to the group they are charged to. A user in
+// several groups is charged to the first manual group, or failing that the
+// first imported group, by name.
+func (g *Groups) Membership() (map[string]string, error) {
+	groups, err := g.List()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Source == models.GroupSourceManual && groups[j].Source != models.GroupSourceManual
+	})
+
+	membership := make(map[string]string)
+	for _, group := range groups {
+		for _, user := range group.Members {
+			if _, ok := membership[user]; !ok {
+				membership[user] = group.Name
+			}
+		}
+	}
+	return membership, nil
+}
+
+// ParseGroupFile parses an /etc/group format file ("name:password:gid:members")
+// and returns the groups whose GID is at least minGID. Comments, blank lines
+// and NIS "+" entries are ignored.
+func ParseGroupFile(r io.Reader, minGID int) ([]models.ResearchGroup, error) {
+	groups := []models.ResearchGroup{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "+") || strings.HasPrefix(text, "-") {
+			continue
+		}
+
+		fields := strings.Split(text, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%w: line %d: expected 4 fields", ErrInvalidGroup, line)
+		}
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid gid %q", ErrInvalidGroup, line, fields[2])
+		}
+		if gid < minGID || !namePattern.MatchString(fields[0]) {
+			continue
+		}
+
+		members, err := normalizeMembers(strings.Split(fields[3], ","))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		groups = append(groups, models.ResearchGroup{Name: fields[0], Members: members})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read group file: %w", err)
+	}
+	return groups, nil
+}
+
+// normalizeMembers trims, validates, sorts and deduplicates user names
+func normalizeMembers(members []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, m := range members {
+		m = strings.TrimSpace(m)
+		if m == "" || seen[m] {
+			continue
+		}
+		if !namePattern.MatchString(m) {
+			return nil, fmt.Errorf("%w: invalid member %q", ErrInvalidGroup, m)
+		}
+		seen[m] = true
+		result = append(result, m)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// load reads the groups from storage
+func (g *Groups) load() ([]models.ResearchGroup, error) {
+	groups := []models.ResearchGroup{}
+	err := storage.GetData(g.storage, groupsKey, &groups)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+	return groups, nil
+}
+
+// save writes the groups ordered by name
+func (g *Groups) save(groups []models.ResearchGroup) error {
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return storage.SetData(g.storage, groupsKey, groups)
+}
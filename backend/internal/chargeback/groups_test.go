@@ -0,0 +1,103 @@
+package chargeback
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func newTestGroups(t *testing.T) *Groups {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	return NewGroups(store)
+}
+
+func TestParseGroupFile(t *testing.T) {
+	file := `# local groups
+root:x:0:
+wheel:x:10:alice
+physics:x:1001:bob,alice, bob
+chem:*:1002:
++@nisgroups
+
+bad name:x:1003:carol
+`
+	groups, err := ParseGroupFile(strings.NewReader(file), 1000)
+	if err != nil {
+		t.Fatalf("ParseGroupFile: %v", err)
+	}
+	want := []models.ResearchGroup{
+		{Name: "physics", Members: []string{"alice", "bob"}},
+		{Name: "chem", Members: []string{}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("ParseGroupFile = %+v, want %+v", groups, want)
+	}
+
+	for _, bad := range []string{"physics:x:1001", "physics:x:gid:bob", "physics:x:1001:b@d"} {
+		if _, err := ParseGroupFile(strings.NewReader(bad), 1000); !errors.Is(err, ErrInvalidGroup) {
+			t.Errorf("ParseGroupFile(%q): error = %v, want ErrInvalidGroup", bad, err)
+		}
+	}
+}
+
+func TestGroupsImport(t *testing.T) {
+	g := newTestGroups(t)
+	if _, err := g.Put(models.ResearchGroup{Name: "physics", Members: []string{"carol"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := g.Put(models.ResearchGroup{Name: "bad name"}); !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("Put of an invalid name: error = %v", err)
+	}
+
+	file := "physics:x:1001:bob\nchem:x:1002:alice,bob\nempty:x:1003:\n"
+	n, err := g.Import(strings.NewReader(file), 1000)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	// The manual group is kept and groups without members are skipped
+	if n != 1 {
+		t.Errorf("imported %d groups, want 1", n)
+	}
+	groups, err := g.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Name != "chem" || groups[0].Source != models.GroupSourceImported ||
+		groups[1].Name != "physics" || groups[1].Source != models.GroupSourceManual || strings.Join(groups[1].Members, ",") != "carol" {
+		t.Errorf("groups = %+v", groups)
+	}
+
+	// Manual groups take precedence, then groups by name
+	if _, err := g.Put(models.ResearchGroup{Name: "zoology", Members: []string{"bob"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	membership, err := g.Membership()
+	if err != nil {
+		t.Fatalf("Membership: %v", err)
+	}
+	if want := map[string]string{"alice": "chem", "bob": "zoology", "carol": "physics"}; !reflect.DeepEqual(membership, want) {
+		t.Errorf("Membership = %v, want %v", membership, want)
+	}
+
+	// A new import replaces the imported groups
+	if _, err := g.Import(strings.NewReader("biology:x:1004:dave\n"), 1000); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if _, err := g.Get("chem"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Get of a group no longer imported: error = %v", err)
+	}
+	if err := g.Delete("biology"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	if err := g.Delete("biology"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Delete of a deleted group: error = %v", err)
+	}
+}
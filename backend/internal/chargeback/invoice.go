@@ -0,0 +1,210 @@
+package chargeback
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/accounting"
+	"github.com/taisei-ito/cluster-status-monitor/internal/jobs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// Unassigned is the group charged for users who are in no group
+const Unassigned = "unassigned"
+
+// ErrInvalidPeriod is returned for periods that are not formatted as YYYY-MM
+var ErrInvalidPeriod = errors.New("invalid period, expected YYYY-MM")
+
+// Rates is the price of one core hour
+type Rates struct {
+	Default    float64            // Rate of clusters without their own rate
+	Clusters   map[string]float64 // Rate per cluster
+	Accounting float64            // Rate of one CPU hour from process accounting
+}
+
+// Cluster returns the rate of a cluster
+func (r Rates) Cluster(name string) float64 {
+	if rate, ok := r.Clusters[name]; ok {
+		return rate
+	}
+	return r.Default
+}
+
+// Biller prices the core hours of each research group. PBS jobs are charged
+// their allocated cores × walltime to the month they started, as they are
+// stored by the job tracker. CPU time from process accounting is charged at
+// its own rate, except on the nodes PBS schedules, where it was already
+// charged as jobs.
+type Biller struct {
+	groups     *Groups
+	jobs       *jobs.Tracker
+	accounting *accounting.Store
+	Rates      Rates
+	Currency   string
+}
+
+// NewBiller creates a biller
+func NewBiller(groups *Groups, tracker *jobs.Tracker, acct *accounting.Store) *Biller {
+	return &Biller{
+		groups:     groups,
+		jobs:       tracker,
+		accounting: acct,
+		Currency:   "JPY",
+	}
+}
+
+// Invoices returns the invoice of every group with usage in a period,
+// ordered by group name
+func (b *Biller) Invoices(period string) ([]models.Invoice, error) {
+	if _, err := time.Parse("2006-01", period); err != nil {
+		return nil, ErrInvalidPeriod
+	}
+
+	membership, err := b.groups.Membership()
+	if err != nil {
+		return nil, err
+	}
+	monthJobs, err := b.jobs.Month(period)
+	if err != nil {
+		return nil, err
+	}
+	users, err := b.accounting.Period(period)
+	if err != nil {
+		return nil, err
+	}
+
+	type lineKey struct{ group, user, cluster, source string }
+	lines := make(map[lineKey]*models.InvoiceLine)
+	line := func(user, cluster, source string) *models.InvoiceLine {
+		group, ok := membership[user]
+		if !ok {
+			group = Unassigned
+		}
+		k := lineKey{group, user, cluster, source}
+		l, ok := lines[k]
+		if !ok {
+			l = &models.InvoiceLine{User: user, Cluster: cluster, Source: source}
+			lines[k] = l
+		}
+		return l
+	}
+
+	for _, job := range monthJobs {
+		l := line(job.User, job.Cluster, models.ChargeSourceJobs)
+		l.Jobs++
+		l.CoreHours += job.CoreHours()
+		l.Rate = b.Rates.Cluster(job.Cluster)
+	}
+	for _, u := range users {
+		hours := (u.CPUMinutes - u.PBSMinutes) / 60
+		if hours <= 0 {
+			continue
+		}
+		l := line(u.User, "", models.ChargeSourceAccounting)
+		l.CoreHours += hours
+		l.Rate = b.Rates.Accounting
+	}
+
+	byGroup := make(map[string]*models.Invoice)
+	now := time.Now().UTC()
+	for k, l := range lines {
+		inv, ok := byGroup[k.group]
+		if !ok {
+			inv = &models.Invoice{Period: period, Group: k.group, Currency: b.Currency, GeneratedAt: now}
+			byGroup[k.group] = inv
+		}
+		l.CoreHours = round(l.CoreHours)
+		l.Amount = round(l.CoreHours * l.Rate)
+		inv.Lines = append(inv.Lines, *l)
+		inv.CoreHours += l.CoreHours
+		inv.Amount += l.Amount
+	}
+
+	invoices := make([]models.Invoice, 0, len(byGroup))
+	for _, inv := range byGroup {
+		sort.Slice(inv.Lines, func(i, j int) bool {
+			a, b := inv.Lines[i], inv.Lines[j]
+			if a.User != b.User {
+				return a.User < b.User
+			}
+			if a.Source != b.Source {
+				return a.Source > b.Source
+			}
+			return a.Cluster < b.Cluster
+		})
+		inv.CoreHours = round(inv.CoreHours)
+		inv.Amount = round(inv.Amount)
+		invoices = append(invoices, *inv)
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].Group < invoices[j].Group })
+	return invoices, nil
+}
+
+// Invoice returns the invoice of one group in a period. A known group
+// without usage gets an empty invoice.
+func (b *Biller) Invoice(period, group string) (*models.Invoice, error) {
+	invoices, err := b.Invoices(period)
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range invoices {
+		if inv.Group == group {
+			return &inv, nil
+		}
+	}
+
+	if group != Unassigned {
+		if _, err := b.groups.Get(group); err != nil {
+			return nil, err
+		}
+	}
+	return &models.Invoice{
+		Period:      period,
+		Group:       group,
+		Currency:    b.Currency,
+		Lines:       []models.InvoiceLine{},
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+// WriteCSV writes the lines of invoices as CSV with a header row
+func WriteCSV(w io.Writer, invoices []models.Invoice) error {
+	cw := csv.NewWriter(w)
+	header := []string{"period", "group", "user", "cluster", "source", "jobs", "core_hours", "rate", "amount", "currency"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, inv := range invoices {
+		for _, l := range inv.Lines {
+			record := []string{
+				inv.Period,
+				inv.Group,
+				l.User,
+				l.Cluster,
+				l.Source,
+				strconv.Itoa(l.Jobs),
+				strconv.FormatFloat(l.CoreHours, 'f', 2, 64),
+				strconv.FormatFloat(l.Rate, 'f', -1, 64),
+				strconv.FormatFloat(l.Amount, 'f', 2, 64),
+				inv.Currency,
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// round rounds to two decimals, the precision of an invoice
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,132 @@
+package chargeback
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/accounting"
+	"github.com/taisei-ito/cluster-status-monitor/internal/jobs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// newTestBiller creates a biller over storage holding the groups, jobs and
+// process accounting of May 2024
+func newTestBiller(t *testing.T) *Biller {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+
+	groups := NewGroups(store)
+	if _, err := groups.Put(models.ResearchGroup{Name: "physics", Members: []string{"alice", "bob"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	start := time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)
+	tracker := jobs.NewTracker(store, "")
+	running := []models.Job{
+		{ID: "1.asuka", User: "alice", Cluster: "asuka", State: models.JobStateRunning, NCPUs: 4, WalltimeSeconds: 36000, StartedAt: start},
+		{ID: "2.asuka", User: "alice", Cluster: "asuka", State: models.JobStateRunning, NCPUs: 2, WalltimeSeconds: 3600, StartedAt: start},
+		{ID: "3.naruko", User: "bob", Cluster: "naruko", State: models.JobStateRunning, NCPUs: 1, WalltimeSeconds: 7200, StartedAt: start},
+		{ID: "4.asuka", User: "carol", Cluster: "asuka", State: models.JobStateRunning, NCPUs: 8, WalltimeSeconds: 1800, StartedAt: start},
+		{ID: "5.asuka", User: "alice", Cluster: "asuka", State: models.JobStateRunning, NCPUs: 8, WalltimeSeconds: 3600, StartedAt: start.AddDate(0, 1, 0)},
+	}
+	if err := tracker.Record(running, start); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// The CPU time on the PBS node asuka01 was charged as jobs already
+	acct := accounting.NewStore(store)
+	pbs := map[string]bool{"asuka01": true}
+	if err := acct.Record(start, map[string]map[string]float64{"files01": {"bob": 0}, "asuka01": {"alice": 0, "bob": 0}}, pbs); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := acct.Record(start.Add(time.Hour), map[string]map[string]float64{"files01": {"bob": 90}, "asuka01": {"alice": 120, "bob": 60}}, pbs); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	b := NewBiller(groups, tracker, acct)
+	b.Rates = Rates{Default: 10, Clusters: map[string]float64{"naruko": 25}, Accounting: 2}
+	return b
+}
+
+func TestInvoices(t *testing.T) {
+	b := newTestBiller(t)
+
+	invoices, err := b.Invoices("2024-05")
+	if err != nil {
+		t.Fatalf("Invoices: %v", err)
+	}
+	if len(invoices) != 2 {
+		t.Fatalf("invoices = %+v, want physics and unassigned", invoices)
+	}
+
+	physics := invoices[0]
+	wantLines := []models.InvoiceLine{
+		{User: "alice", Cluster: "asuka", Source: models.ChargeSourceJobs, Jobs: 2, CoreHours: 42, Rate: 10, Amount: 420},
+		{User: "bob", Cluster: "naruko", Source: models.ChargeSourceJobs, Jobs: 1, CoreHours: 2, Rate: 25, Amount: 50},
+		{User: "bob", Source: models.ChargeSourceAccounting, CoreHours: 1.5, Rate: 2, Amount: 3},
+	}
+	if physics.Group != "physics" || physics.Currency != "JPY" || !reflect.DeepEqual(physics.Lines, wantLines) {
+		t.Errorf("physics = %+v, want lines %+v", physics, wantLines)
+	}
+	if physics.CoreHours != 45.5 || physics.Amount != 473 {
+		t.Errorf("physics totals %v core hours, %v, want 45.5 and 473", physics.CoreHours, physics.Amount)
+	}
+
+	// Users in no group are charged to the unassigned group
+	if u := invoices[1]; u.Group != Unassigned || len(u.Lines) != 1 || u.Lines[0].User != "carol" || u.Amount != 40 {
+		t.Errorf("unassigned = %+v", u)
+	}
+
+	if _, err := b.Invoices("May"); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("Invoices of an invalid period: error = %v", err)
+	}
+}
+
+func TestInvoice(t *testing.T) {
+	b := newTestBiller(t)
+
+	inv, err := b.Invoice("2024-04", "physics")
+	if err != nil {
+		t.Fatalf("Invoice: %v", err)
+	}
+	if inv.Group != "physics" || len(inv.Lines) != 0 || inv.Amount != 0 {
+		t.Errorf("invoice without usage = %+v", inv)
+	}
+	if _, err := b.Invoice("2024-05", "chem"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Invoice of an unknown group: error = %v", err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	invoices := []models.Invoice{{
+		Period:   "2024-05",
+		Group:    "physics",
+		Currency: "JPY",
+		Lines: []models.InvoiceLine{
+			{User: "alice", Cluster: "asuka", Source: models.ChargeSourceJobs, Jobs: 2, CoreHours: 42, Rate: 10.5, Amount: 441},
+			{User: "bob", Source: models.ChargeSourceAccounting, CoreHours: 1.5, Rate: 2, Amount: 3},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, invoices); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	want := strings.Join([]string{
+		"period,group,user,cluster,source,jobs,core_hours,rate,amount,currency",
+		"2024-05,physics,alice,asuka,jobs,2,42.00,10.5,441.00,JPY",
+		"2024-05,physics,bob,,accounting,0,1.50,2,3.00,JPY",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
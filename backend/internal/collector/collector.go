@@ -0,0 +1,26 @@
+package collector
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+)
+
+// RunEvery calls collect immediately and then at the given interval until
+// ctx is done, logging the outcome of each run
+func RunEvery(ctx context.Context, interval *schedule.Interval, name string, collect func(context.Context) error) {
+	for {
+		start := time.Now()
+		if err := collect(ctx); err != nil {
+			log.Printf("%s collection failed: %v", name, err)
+		} else {
+			log.Printf("%s collected in %s", name, time.Since(start).Round(time.Millisecond))
+		}
+
+		if !interval.Wait(ctx, start) {
+			return
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// defaultUserRoot is indexed when a cluster does not configure user roots
+const defaultUserRoot = "/home"
+
+// DiskUserCollector imports per-user directory sizes from the duc databases
+// on each cluster master, replacing disk_user.sh
+type DiskUserCollector struct {
+	registry *registry.Registry
+	runner   Runner
+	store    *DiskUserStore
+	DucPath  string // Path to duc on the cluster masters
+	Index    bool   // Run `duc index` before listing; otherwise use the existing database
+}
+
+// NewDiskUserCollector creates a per-user disk usage collector
+func NewDiskUserCollector(reg *registry.Registry, runner Runner, store *DiskUserStore, ducPath string) *DiskUserCollector {
+	return &DiskUserCollector{
+		registry: reg,
+		runner:   runner,
+		store:    store,
+		DucPath:  ducPath,
+	}
+}
+
+// Collect lists per-user usage for every registered cluster. Clusters are
+// processed one at a time and duc runs with idle I/O priority, so a
+// collection never puts more than one master under indexing load.
+func (c *DiskUserCollector) Collect(ctx context.Context) error {
+	clusters, err := c.registry.List()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, cluster := range clusters {
+		roots := cluster.UserRoots
+		if len(roots) == 0 {
+			roots = []string{defaultUserRoot}
+		}
+
+		for _, root := range roots {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			snapshot, err := c.collectRoot(ctx, cluster.MasterHost, root)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s:%s: %w", cluster.Name, root, err))
+				continue
+			}
+			if err := c.store.Add(cluster.Name, *snapshot); err != nil {
+				errs = append(errs, fmt.Errorf("%s:%s: %w", cluster.Name, root, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run collects at the given interval until ctx is done
+func (c *DiskUserCollector) Run(ctx context.Context, interval *schedule.Interval) {
+	RunEvery(ctx, interval, "Per-user disk usage", c.Collect)
+}
+
+// collectRoot runs duc on host and parses the per-user sizes below root
+func (c *DiskUserCollector) collectRoot(ctx context.Context, host, root string) (*models.DiskUserSnapshot, error) {
+	command := fmt.Sprintf("ionice -c 3 %s ls -b %s", c.DucPath, shellQuote(root))
+	if c.Index {
+		command = fmt.Sprintf("ionice -c 3 %s index -q %s && %s", c.DucPath, shellQuote(root), command)
+	}
+
+	out, err := c.runner.Run(ctx, host, command)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := ParseDucLs(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DiskUserSnapshot{
+		Filesystem:  root,
+		CollectedAt: time.Now().UTC(),
+		Users:       users,
+	}, nil
+}
+
+// ParseDucLs parses `duc ls -b` output ("<bytes> <name>" per line) into
+// bytes per directory name
+func ParseDucLs(out []byte) (map[string]int64, error) {
+	users := make(map[string]int64)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duc ls line %q: %w", scanner.Text(), err)
+		}
+		name := strings.TrimSuffix(strings.Join(fields[1:], " "), "/")
+		users[name] += size
+	}
+
+	return users, scanner.Err()
+}
+
+// DiskUserStore keeps per-user disk usage snapshots per cluster in storage
+type DiskUserStore struct {
+	storage   storage.Storage
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+// NewDiskUserStore creates a snapshot store. Snapshots from the last day are
+// all kept; older ones are thinned to one per day and dropped after retention.
+func NewDiskUserStore(store storage.Storage, retention time.Duration) *DiskUserStore {
+	return &DiskUserStore{storage: store, retention: retention}
+}
+
+// Add stores a new snapshot for a cluster
+func (s *DiskUserStore) Add(cluster string, snapshot models.DiskUserSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshots, err := s.load(cluster)
+	if err != nil {
+		return err
+	}
+
+	snapshots = append(snapshots, snapshot)
+	snapshots = pruneSnapshots(snapshots, snapshot.CollectedAt, s.retention)
+
+	return storage.SetData(s.storage, diskUsersKey(cluster), snapshots)
+}
+
+// Report returns the top users of a cluster filesystem with their growth over
+// window. An empty filesystem selects the first one collected; top <= 0
+// returns every user.
+func (s *DiskUserStore) Report(cluster, filesystem string, top int, window time.Duration) (*models.DiskUserReport, error) {
+	s.mu.Lock()
+	snapshots, err := s.load(cluster)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.DiskUserReport{
+		Cluster:    cluster,
+		Filesystem: filesystem,
+		Users:      []models.UserDiskUsage{},
+	}
+
+	latest := latestSnapshot(snapshots, filesystem, time.Time{})
+	if latest == nil {
+		report.Message = "ユーザー別ディスクデータが取得できていません"
+		return report, nil
+	}
+	report.Filesystem = latest.Filesystem
+	report.CollectedAt = latest.CollectedAt
+	report.HasData = true
+
+	var previous map[string]int64
+	if base := latestSnapshot(snapshots, latest.Filesystem, latest.CollectedAt.Add(-window)); base != nil && base != latest {
+		previous = base.Users
+		report.ComparedTo = &base.CollectedAt
+	}
+
+	for user, size := range latest.Users {
+		usage := models.UserDiskUsage{
+			User:   user,
+			Bytes:  size,
+			UsedGB: float64(size) / (1 << 30),
+		}
+		if previous != nil {
+			old := previous[user]
+			usage.DeltaBytes = size - old
+			if old > 0 {
+				usage.DeltaPercent = float64(size-old) / float64(old) * 100
+			}
+		}
+		report.TotalBytes += size
+		report.Users = append(report.Users, usage)
+	}
+
+	sort.Slice(report.Users, func(i, j int) bool {
+		if report.Users[i].Bytes != report.Users[j].Bytes {
+			return report.Users[i].Bytes > report.Users[j].Bytes
+		}
+		return report.Users[i].User < report.Users[j].User
+	})
+	if top > 0 && len(report.Users) > top {
+		report.Users = report.Users[:top]
+	}
+
+	return report, nil
+}
+
+// load reads the snapshots of a cluster
+func (s *DiskUserStore) load(cluster string) ([]models.DiskUserSnapshot, error) {
+	snapshots := []models.DiskUserSnapshot{}
+	err := storage.GetData(s.storage, diskUsersKey(cluster), &snapshots)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load disk users of %s: %w", cluster, err)
+	}
+	return snapshots, nil
+}
+
+// diskUsersKey returns the storage key for a cluster's per-user snapshots
+func diskUsersKey(cluster string) string {
+	return "disk_users_" + cluster
+}
+
+// latestSnapshot returns the newest snapshot of filesystem collected at or
+// before notAfter (zero means no limit). An empty filesystem matches the
+// first filesystem in collection order.
+func latestSnapshot(snapshots []models.DiskUserSnapshot, filesystem string, notAfter time.Time) *models.DiskUserSnapshot {
+	if filesystem == "" && len(snapshots) > 0 {
+		filesystem = snapshots[0].Filesystem
+	}
+
+	var latest *models.DiskUserSnapshot
+	for i := range snapshots {
+		snap := &snapshots[i]
+		if snap.Filesystem != filesystem {
+			continue
+		}
+		if !notAfter.IsZero() && snap.CollectedAt.After(notAfter) {
+			continue
+		}
+		if latest == nil || snap.CollectedAt.After(latest.CollectedAt) {
+			latest = snap
+		}
+	}
+	return latest
+}
+
+// pruneSnapshots keeps every snapshot from the last day and the first
+// snapshot of each older day within retention
+func pruneSnapshots(snapshots []models.DiskUserSnapshot, now time.Time, retention time.Duration) []models.DiskUserSnapshot {
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CollectedAt.Before(snapshots[j].CollectedAt)
+	})
+
+	recent := now.Add(-24 * time.Hour)
+	oldest := now.Add(-retention)
+	daily := make(map[string]bool)
+
+	kept := snapshots[:0]
+	for _, snap := range snapshots {
+		switch {
+		case snap.CollectedAt.After(recent):
+			kept = append(kept, snap)
+		case snap.CollectedAt.Before(oldest):
+		default:
+			day := snap.Filesystem + "|" + snap.CollectedAt.Format("2006-01-02")
+			if !daily[day] {
+				daily[day] = true
+				kept = append(kept, snap)
+			}
+		}
+	}
+	return kept
+}
+
+// shellQuote quotes s for use as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
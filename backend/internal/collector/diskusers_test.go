@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestParseDucLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    map[string]int64
+		wantErr bool
+	}{
+		{"empty", "", map[string]int64{}, false},
+		{"users", "1024 alice\n2048 bob/\n", map[string]int64{"alice": 1024, "bob": 2048}, false},
+		{"name with spaces", "10 old  files\n", map[string]int64{"old files": 10}, false},
+		{"repeated name", "10 alice\n5 alice/\n", map[string]int64{"alice": 15}, false},
+		{"short lines skipped", "\n42\n7 carol\n", map[string]int64{"carol": 7}, false},
+		{"invalid size", "1.5K alice\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDucLs([]byte(tt.out))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDucLs = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDucLs: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDucLs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) models.DiskUserSnapshot {
+		return models.DiskUserSnapshot{Filesystem: "/home", CollectedAt: now.Add(-d)}
+	}
+	snapshots := []models.DiskUserSnapshot{
+		at(40 * 24 * time.Hour), // Beyond retention
+		at(3*24*time.Hour + time.Hour),
+		at(3 * 24 * time.Hour), // Second of the same day
+		at(2 * 24 * time.Hour),
+		at(2 * time.Hour),
+		at(time.Hour), // Recent snapshots are all kept
+	}
+
+	kept := pruneSnapshots(snapshots, now, 30*24*time.Hour)
+	var got []time.Duration
+	for _, s := range kept {
+		got = append(got, now.Sub(s.CollectedAt))
+	}
+	want := []time.Duration{3*24*time.Hour + time.Hour, 2 * 24 * time.Hour, 2 * time.Hour, time.Hour}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kept snapshots aged %v, want %v", got, want)
+	}
+}
+
+func TestDiskUserReport(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	users := NewDiskUserStore(store, 30*24*time.Hour)
+
+	now := time.Now().UTC()
+	for _, s := range []models.DiskUserSnapshot{
+		{Filesystem: "/home", CollectedAt: now.Add(-8 * 24 * time.Hour), Users: map[string]int64{"alice": 100, "bob": 300}},
+		{Filesystem: "/home", CollectedAt: now.Add(-time.Hour), Users: map[string]int64{"alice": 150, "bob": 300, "carol": 50}},
+		{Filesystem: "/data", CollectedAt: now, Users: map[string]int64{"alice": 1}},
+	} {
+		if err := users.Add("asuka", s); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	report, err := users.Report("asuka", "/home", 2, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if !report.HasData || report.ComparedTo == nil || report.TotalBytes != 500 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := []models.UserDiskUsage{
+		{User: "bob", Bytes: 300, UsedGB: 300.0 / (1 << 30)},
+		{User: "alice", Bytes: 150, UsedGB: 150.0 / (1 << 30), DeltaBytes: 50, DeltaPercent: 50},
+	}
+	if !reflect.DeepEqual(report.Users, want) {
+		t.Errorf("users = %+v, want %+v", report.Users, want)
+	}
+
+	// Without an older snapshot in the window there is nothing to compare
+	report, err = users.Report("asuka", "/data", 0, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if report.ComparedTo != nil || report.Users[0].DeltaBytes != 0 {
+		t.Errorf("report without a base = %+v", report)
+	}
+
+	report, err = users.Report("naruko", "", 0, time.Hour)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if report.HasData || len(report.Users) != 0 {
+		t.Errorf("report of an uncollected cluster = %+v", report)
+	}
+}
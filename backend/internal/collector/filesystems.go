@@ -0,0 +1,377 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// dfCommand prints capacity and inode usage of every mount, separated by a
+// marker line so both tables can be parsed from one remote call
+const dfCommand = "df -PT -B1 2>/dev/null; echo '--'; df -Pi 2>/dev/null"
+
+// pseudoFSTypes are never collected regardless of the mount patterns
+var pseudoFSTypes = map[string]bool{
+	"tmpfs":    true,
+	"devtmpfs": true,
+	"overlay":  true,
+	"squashfs": true,
+	"proc":     true,
+	"sysfs":    true,
+	"cgroup":   true,
+	"cgroup2":  true,
+	"efivarfs": true,
+}
+
+// FilesystemCollector records the capacity of every mount on each cluster
+// master and its online nodes, replacing disk_total.sh and disk_node.sh
+type FilesystemCollector struct {
+	registry    *registry.Registry
+	inventory   *inventory.Inventory
+	runner      Runner
+	store       *FilesystemStore
+	Concurrency int // Hosts queried in parallel
+}
+
+// NewFilesystemCollector creates a filesystem capacity collector
+func NewFilesystemCollector(reg *registry.Registry, inv *inventory.Inventory, runner Runner, store *FilesystemStore) *FilesystemCollector {
+	return &FilesystemCollector{
+		registry:    reg,
+		inventory:   inv,
+		runner:      runner,
+		store:       store,
+		Concurrency: 8,
+	}
+}
+
+// Collect queries every registered cluster and stores its filesystems
+func (c *FilesystemCollector) Collect(ctx context.Context) error {
+	clusters, err := c.registry.List()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, cluster := range clusters {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := c.collectCluster(ctx, cluster); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cluster.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run collects at the given interval until ctx is done
+func (c *FilesystemCollector) Run(ctx context.Context, interval *schedule.Interval) {
+	RunEvery(ctx, interval, "Filesystem capacity", c.Collect)
+}
+
+// collectCluster queries the master and online nodes of one cluster. Hosts
+// that fail are skipped; the remaining results are still stored.
+func (c *FilesystemCollector) collectCluster(ctx context.Context, cluster models.ClusterInfo) error {
+	hosts, err := c.hosts(cluster)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []models.Filesystem
+		errs    []error
+	)
+	sem := make(chan struct{}, max(c.Concurrency, 1))
+
+	for _, host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			out, err := c.runner.Run(ctx, host.address, dfCommand)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			for _, fs := range ParseDf(host.name, out, time.Now().UTC()) {
+				if mountSelected(cluster, fs) {
+					results = append(results, fs)
+				}
+			}
+		}(host)
+	}
+	wg.Wait()
+
+	if len(results) > 0 || len(errs) == 0 {
+		if err := c.store.Set(cluster.Name, results); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// target is a host to query and the name its filesystems are reported under
+type target struct {
+	name    string
+	address string
+}
+
+// hosts returns the master and the online nodes of a cluster
+func (c *FilesystemCollector) hosts(cluster models.ClusterInfo) ([]target, error) {
+	hosts := []target{{name: cluster.MasterHost, address: cluster.MasterHost}}
+	seen := map[string]bool{cluster.MasterHost: true}
+
+	nodes, err := c.inventory.ByCluster(cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		if n.State != models.NodeStateOnline || seen[n.Host] {
+			continue
+		}
+		seen[n.Host] = true
+		hosts = append(hosts, target{name: n.Name, address: n.Host})
+	}
+	return hosts, nil
+}
+
+// mountSelected applies the cluster's mount include/exclude patterns
+func mountSelected(cluster models.ClusterInfo, fs models.Filesystem) bool {
+	if pseudoFSTypes[fs.FSType] {
+		return false
+	}
+	if len(cluster.MountInclude) > 0 && !matchAny(cluster.MountInclude, fs.MountPoint) {
+		return false
+	}
+	return !matchAny(cluster.MountExclude, fs.MountPoint)
+}
+
+// matchAny reports whether name matches one of the shell patterns
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDf parses the output of dfCommand: `df -PT -B1` followed by `df -Pi`
+// after a "--" line. Inode counts are joined to capacity rows by mount point.
+func ParseDf(host string, out []byte, collectedAt time.Time) []models.Filesystem {
+	var filesystems []models.Filesystem
+	index := make(map[string]int)
+	inodes := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "--" {
+			inodes = true
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[0] == "Filesystem" {
+			continue
+		}
+
+		if !inodes {
+			if len(fields) < 7 {
+				continue
+			}
+			fs := models.Filesystem{
+				Host:           host,
+				Device:         fields[0],
+				FSType:         fields[1],
+				SizeBytes:      parseCount(fields[2]),
+				UsedBytes:      parseCount(fields[3]),
+				AvailableBytes: parseCount(fields[4]),
+				MountPoint:     strings.Join(fields[6:], " "),
+				CollectedAt:    collectedAt,
+			}
+			index[fs.MountPoint] = len(filesystems)
+			filesystems = append(filesystems, fs)
+			continue
+		}
+
+		mount := strings.Join(fields[5:], " ")
+		if i, ok := index[mount]; ok {
+			filesystems[i].InodesTotal = parseCount(fields[1])
+			filesystems[i].InodesUsed = parseCount(fields[2])
+		}
+	}
+
+	return filesystems
+}
+
+// parseCount parses a df number; "-" (not reported) is treated as zero
+func parseCount(s string) int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// FilesystemStore keeps the latest filesystems of each cluster in storage,
+// together with a capacity history used for forecasting. History is kept in
+// full for two days and thinned to one sample per six hours afterwards.
+type FilesystemStore struct {
+	storage   storage.Storage
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+// NewFilesystemStore creates a filesystem store keeping history for retention
+func NewFilesystemStore(store storage.Storage, retention time.Duration) *FilesystemStore {
+	return &FilesystemStore{storage: store, retention: retention}
+}
+
+// Set replaces the filesystems of a cluster and appends them to its history
+func (s *FilesystemStore) Set(cluster string, filesystems []models.Filesystem) error {
+	sort.Slice(filesystems, func(i, j int) bool {
+		if filesystems[i].Host != filesystems[j].Host {
+			return filesystems[i].Host < filesystems[j].Host
+		}
+		return filesystems[i].MountPoint < filesystems[j].MountPoint
+	})
+
+	if err := storage.SetData(s.storage, filesystemsKey(cluster), filesystems); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, err := s.loadHistory(cluster)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, fs := range filesystems {
+		history = append(history, models.FilesystemSample{
+			Host:        fs.Host,
+			MountPoint:  fs.MountPoint,
+			UsedBytes:   fs.UsedBytes,
+			UsableBytes: fs.UsedBytes + fs.AvailableBytes,
+			Time:        fs.CollectedAt,
+		})
+	}
+	history = pruneHistory(history, now, s.retention)
+
+	return storage.SetData(s.storage, filesystemHistoryKey(cluster), history)
+}
+
+// History returns the capacity samples of a cluster collected since the
+// given time, oldest first
+func (s *FilesystemStore) History(cluster string, since time.Time) ([]models.FilesystemSample, error) {
+	s.mu.Lock()
+	history, err := s.loadHistory(cluster)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.FilesystemSample{}
+	for _, sample := range history {
+		if !sample.Time.Before(since) {
+			result = append(result, sample)
+		}
+	}
+	return result, nil
+}
+
+// loadHistory reads the capacity history of a cluster
+func (s *FilesystemStore) loadHistory(cluster string) ([]models.FilesystemSample, error) {
+	history := []models.FilesystemSample{}
+	err := storage.GetData(s.storage, filesystemHistoryKey(cluster), &history)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load filesystem history of %s: %w", cluster, err)
+	}
+	return history, nil
+}
+
+// Get returns the latest filesystems of a cluster
+func (s *FilesystemStore) Get(cluster string) ([]models.Filesystem, error) {
+	filesystems := []models.Filesystem{}
+	err := storage.GetData(s.storage, filesystemsKey(cluster), &filesystems)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load filesystems of %s: %w", cluster, err)
+	}
+	return filesystems, nil
+}
+
+// DiskUsage returns the latest filesystems of a cluster in the frontend
+// DiskUsage shape
+func (s *FilesystemStore) DiskUsage(cluster string) ([]models.DiskUsage, error) {
+	filesystems, err := s.Get(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make([]models.DiskUsage, 0, len(filesystems))
+	for _, fs := range filesystems {
+		usage = append(usage, fs.DiskUsage())
+	}
+	return usage, nil
+}
+
+// filesystemsKey returns the storage key for a cluster's filesystems
+func filesystemsKey(cluster string) string {
+	return "filesystems_" + cluster
+}
+
+// filesystemHistoryKey returns the storage key for a cluster's capacity history
+func filesystemHistoryKey(cluster string) string {
+	return "filesystem_history_" + cluster
+}
+
+// pruneHistory keeps every sample from the last two days, one sample per
+// mount and six hour slot before that, and nothing older than retention
+func pruneHistory(history []models.FilesystemSample, now time.Time, retention time.Duration) []models.FilesystemSample {
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+
+	recent := now.Add(-48 * time.Hour)
+	oldest := now.Add(-retention)
+	slots := make(map[string]bool)
+
+	kept := history[:0]
+	for _, sample := range history {
+		switch {
+		case sample.Time.After(recent):
+			kept = append(kept, sample)
+		case sample.Time.Before(oldest):
+		default:
+			slot := fmt.Sprintf("%s|%s|%d", sample.Host, sample.MountPoint, sample.Time.Unix()/(6*3600))
+			if !slots[slot] {
+				slots[slot] = true
+				kept = append(kept, sample)
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+func TestParseDf(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	out := `Filesystem     Type     1-byte-blocks        Used    Available Capacity Mounted on
+/dev/sda1      xfs        107374182400 53687091200  53687091200      50% /
+nas:/export    nfs4      1099511627776 10995116277 1088516511499       1% /mnt/my data
+tmpfs          tmpfs         1073741824           0   1073741824       0% /dev/shm
+broken line
+--
+Filesystem       Inodes  IUsed    IFree IUse% Mounted on
+/dev/sda1      52428800 104857 52323943    1% /
+nas:/export           -      -        -     - /mnt/my data
+other            100     10       90   10% /not/in/capacity
+`
+	got := ParseDf("asuka01", []byte(out), now)
+	want := []models.Filesystem{
+		{Host: "asuka01", Device: "/dev/sda1", FSType: "xfs", SizeBytes: 107374182400, UsedBytes: 53687091200, AvailableBytes: 53687091200, InodesTotal: 52428800, InodesUsed: 104857, MountPoint: "/", CollectedAt: now},
+		{Host: "asuka01", Device: "nas:/export", FSType: "nfs4", SizeBytes: 1099511627776, UsedBytes: 10995116277, AvailableBytes: 1088516511499, MountPoint: "/mnt/my data", CollectedAt: now},
+		{Host: "asuka01", Device: "tmpfs", FSType: "tmpfs", SizeBytes: 1073741824, AvailableBytes: 1073741824, MountPoint: "/dev/shm", CollectedAt: now},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDf =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := ParseDf("asuka01", nil, now); len(got) != 0 {
+		t.Errorf("ParseDf of no output = %+v", got)
+	}
+}
+
+func TestMountSelected(t *testing.T) {
+	cluster := models.ClusterInfo{Name: "asuka", MountInclude: []string{"/", "/data*"}, MountExclude: []string{"/data/scratch"}}
+	tests := []struct {
+		mount  string
+		fsType string
+		want   bool
+	}{
+		{"/", "xfs", true},
+		{"/data", "nfs4", true},
+		{"/data2", "nfs4", true},
+		{"/data/scratch", "nfs4", false},
+		{"/home", "xfs", false},
+		{"/", "tmpfs", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mount+" "+tt.fsType, func(t *testing.T) {
+			if got := mountSelected(cluster, models.Filesystem{MountPoint: tt.mount, FSType: tt.fsType}); got != tt.want {
+				t.Errorf("mountSelected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without includes every real filesystem is selected
+	if !mountSelected(models.ClusterInfo{}, models.Filesystem{MountPoint: "/home", FSType: "xfs"}) {
+		t.Error("mount excluded without any patterns")
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	slot := time.Unix(now.Add(-5*24*time.Hour).Unix()/(6*3600)*6*3600, 0).UTC()
+	sample := func(at time.Time) models.FilesystemSample {
+		return models.FilesystemSample{Host: "asuka01", MountPoint: "/", Time: at}
+	}
+	history := []models.FilesystemSample{
+		sample(now.Add(-100 * 24 * time.Hour)),
+		sample(slot.Add(time.Hour)),
+		sample(slot), // Same six hour slot, kept as the earlier one
+		sample(slot.Add(6 * time.Hour)),
+		sample(now.Add(-time.Hour)),
+		sample(now.Add(-2 * time.Hour)),
+	}
+
+	var got []time.Time
+	for _, s := range pruneHistory(history, now, 90*24*time.Hour) {
+		got = append(got, s.Time)
+	}
+	want := []time.Time{slot, slot.Add(6 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Hour)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kept samples at %v, want %v", got, want)
+	}
+}
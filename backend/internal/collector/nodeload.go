@@ -0,0 +1,156 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// NodeLoadCollector reads the load average of every online node
+type NodeLoadCollector struct {
+	registry    *registry.Registry
+	inventory   *inventory.Inventory
+	runner      Runner
+	store       *NodeLoadStore
+	Concurrency int // Hosts queried in parallel
+}
+
+// NewNodeLoadCollector creates a node load collector
+func NewNodeLoadCollector(reg *registry.Registry, inv *inventory.Inventory, runner Runner, store *NodeLoadStore) *NodeLoadCollector {
+	return &NodeLoadCollector{
+		registry:    reg,
+		inventory:   inv,
+		runner:      runner,
+		store:       store,
+		Concurrency: 8,
+	}
+}
+
+// Collect reads /proc/loadavg on the online nodes of every cluster
+func (c *NodeLoadCollector) Collect(ctx context.Context) error {
+	clusters, err := c.registry.Names()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, cluster := range clusters {
+		nodes, err := c.inventory.ByCluster(cluster)
+		if err != nil {
+			return err
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+
+		var (
+			mu    sync.Mutex
+			wg    sync.WaitGroup
+			loads []models.NodeLoad
+		)
+		sem := make(chan struct{}, max(c.Concurrency, 1))
+		for _, n := range nodes {
+			if n.State != models.NodeStateOnline {
+				continue
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(n models.Node) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				out, err := c.runner.Run(ctx, n.Host, "cat /proc/loadavg")
+				var load *models.NodeLoad
+				if err == nil {
+					load, err = ParseLoadavg(n.Name, out)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", n.Name, err))
+					return
+				}
+				loads = append(loads, *load)
+			}(n)
+		}
+		wg.Wait()
+
+		if err := c.store.Set(cluster, loads); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run collects at the given interval until ctx is done
+func (c *NodeLoadCollector) Run(ctx context.Context, interval *schedule.Interval) {
+	RunEvery(ctx, interval, "Node load", c.Collect)
+}
+
+// ParseLoadavg parses the first three fields of /proc/loadavg
+func ParseLoadavg(node string, out []byte) (*models.NodeLoad, error) {
+	fields := strings.Fields(string(out))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid loadavg %q", strings.TrimSpace(string(out)))
+	}
+
+	var values [3]float64
+	for i := range values {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loadavg %q: %w", strings.TrimSpace(string(out)), err)
+		}
+		values[i] = v
+	}
+
+	return &models.NodeLoad{
+		Node:        node,
+		Load1:       values[0],
+		Load5:       values[1],
+		Load15:      values[2],
+		CollectedAt: time.Now().UTC(),
+	}, nil
+}
+
+// NodeLoadStore keeps the latest node loads of each cluster in storage
+type NodeLoadStore struct {
+	storage storage.Storage
+}
+
+// NewNodeLoadStore creates a node load store
+func NewNodeLoadStore(store storage.Storage) *NodeLoadStore {
+	return &NodeLoadStore{storage: store}
+}
+
+// Set replaces the node loads of a cluster
+func (s *NodeLoadStore) Set(cluster string, loads []models.NodeLoad) error {
+	return storage.SetData(s.storage, nodeLoadKey(cluster), loads)
+}
+
+// Get returns the latest node loads of a cluster
+func (s *NodeLoadStore) Get(cluster string) ([]models.NodeLoad, error) {
+	loads := []models.NodeLoad{}
+	err := storage.GetData(s.storage, nodeLoadKey(cluster), &loads)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load node load of %s: %w", cluster, err)
+	}
+	return loads, nil
+}
+
+// nodeLoadKey returns the storage key for a cluster's node loads
+func nodeLoadKey(cluster string) string {
+	return "node_load_" + cluster
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Runner executes a shell command on a cluster host
+type Runner interface {
+	Run(ctx context.Context, host, command string) ([]byte, error)
+}
+
+// RemoteShell runs commands through rsh/ssh, the same way the collection
+// scripts call `sudo -u guest /usr/bin/rsh <host> <command>`
+type RemoteShell struct {
+	Command []string // Command prefix, e.g. ["sudo", "-u", "guest", "/usr/bin/rsh"]
+}
+
+// NewRemoteShell creates a runner from a space separated command prefix
+func NewRemoteShell(command string) *RemoteShell {
+	return &RemoteShell{Command: strings.Fields(command)}
+}
+
+// Run executes command on host and returns its standard output
+func (r *RemoteShell) Run(ctx context.Context, host, command string) ([]byte, error) {
+	if len(r.Command) == 0 {
+		return nil, fmt.Errorf("remote shell command is not configured")
+	}
+
+	args := append(append([]string{}, r.Command[1:]...), host, command)
+	cmd := exec.CommandContext(ctx, r.Command[0], args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s on %s failed: %w: %s", command, host, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -1,59 +1,747 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/ratelimit"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
 	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
 )
 
-// Config holds application configuration
+// Config holds application configuration. The yaml keys are those of the
+// configuration file.
 type Config struct {
-	ServerPort string
-	Storage    storage.Config
+	Server     ServerConfig     `yaml:"server"`
+	Storage    storage.Config   `yaml:"storage"`
+	Registry   RegistryConfig   `yaml:"registry"`
+	Clusters   []ClusterConfig  `yaml:"clusters"`
+	Collector  CollectorConfig  `yaml:"collectors"`
+	Alerts     AlertConfig      `yaml:"alerts"`
+	Reports    ReportConfig     `yaml:"reports"`
+	Notify     NotifyConfig     `yaml:"notify"`
+	Digest     DigestConfig     `yaml:"digest"`
+	Usage      UsageConfig      `yaml:"usage"`
+	Chargeback ChargebackConfig `yaml:"chargeback"`
+	StatusPage StatusPageConfig `yaml:"status_page"`
+	Auth       AuthConfig       `yaml:"auth"`
+	LDAP       LDAPConfig       `yaml:"ldap"`
+	OIDC       OIDCConfig       `yaml:"oidc"`
+	Security   SecurityConfig   `yaml:"security"`
+	TLS        TLSConfig        `yaml:"tls"`
+	RateLimits RateLimitConfig  `yaml:"rate_limits"`
+	File       string           `yaml:"-"` // Configuration file the values were read from; empty for none
 }
 
-// Load loads configuration from environment variables
+// ServerConfig holds HTTP server configuration
+type ServerConfig struct {
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`     // Time allowed to read a request
+	WriteTimeout    time.Duration `yaml:"write_timeout"`    // Time allowed to write a response
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`     // How long idle keep-alive connections are kept
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // How long running requests may take on shutdown
+	WatchInterval   time.Duration `yaml:"watch_interval"`   // How often the configuration file is checked for changes; 0 disables
+}
+
+// RegistryConfig holds cluster registry configuration
+type RegistryConfig struct {
+	QstatPath         string        `yaml:"qstat_path"`         // Path to PBS qstat used for discovery
+	SyncInterval      time.Duration `yaml:"sync_interval"`      // How often the scheduler is queried
+	PbsnodesPath      string        `yaml:"pbsnodes_path"`      // Path to PBS pbsnodes used for the node inventory
+	InventoryInterval time.Duration `yaml:"inventory_interval"` // How often the node inventory is refreshed
+}
+
+// ClusterConfig is a cluster that is always registered, such as a file
+// server without a scheduler queue
+type ClusterConfig struct {
+	Name         string   `yaml:"name"`        // Registry name, also used in storage keys
+	Type         string   `yaml:"type"`        // "compute" or "fileserver"
+	MasterHost   string   `yaml:"master_host"` // Host the collectors run commands on
+	Description  string   `yaml:"description"`
+	Queue        string   `yaml:"queue"`         // PBS queue of a compute cluster
+	UserRoots    []string `yaml:"user_roots"`    // Directories holding per-user data, e.g. /home
+	MountInclude []string `yaml:"mount_include"` // Mount point patterns to collect; empty collects all
+	MountExclude []string `yaml:"mount_exclude"` // Mount point patterns to skip
+}
+
+// Info returns the cluster as kept in the registry
+func (c ClusterConfig) Info() models.ClusterInfo {
+	return models.ClusterInfo{
+		Name:         c.Name,
+		Type:         models.ClusterType(c.Type),
+		MasterHost:   c.MasterHost,
+		Description:  c.Description,
+		Queue:        c.Queue,
+		UserRoots:    c.UserRoots,
+		MountInclude: c.MountInclude,
+		MountExclude: c.MountExclude,
+	}
+}
+
+// storageOptionEnv maps environment variables to the storage options they set
+var storageOptionEnv = map[string]string{
+	"DB_MAX_OPEN_CONNS":     storage.OptionMaxOpenConns,
+	"DB_MAX_IDLE_CONNS":     storage.OptionMaxIdleConns,
+	"DB_CONN_MAX_LIFETIME":  storage.OptionConnMaxLifetime,
+	"DB_CONN_MAX_IDLE_TIME": storage.OptionConnMaxIdleTime,
+	"DB_TIMEOUT":            storage.OptionTimeout,
+	"DB_READ_TIMEOUT":       storage.OptionReadTimeout,
+	"DB_WRITE_TIMEOUT":      storage.OptionWriteTimeout,
+}
+
+// Load loads the configuration file named by CONFIG_FILE, if any, on top of
+// the defaults and then applies environment variables, which override the
+// values of the file. Environment variables that do not parse give a
+// ValidationError, which also lists the other invalid values.
 func Load() (*Config, error) {
 	// Load .env file if exists
 	_ = godotenv.Load()
 
-	config := &Config{
-		ServerPort: getEnv("PORT", "8080"),
+	config := defaults()
+	config.File = os.Getenv("CONFIG_FILE")
+	if config.File != "" {
+		if err := config.loadFile(config.File); err != nil {
+			return nil, err
+		}
+	}
+	invalid := config.applyEnv()
+
+	// MySQL configuration is only kept if storage type is MySQL
+	if config.Storage.Type != "mysql" {
+		config.Storage.MySQL = nil
+	}
+
+	if len(invalid) > 0 {
+		// Report the other invalid values too, so one start shows every
+		// mistake
+		var others ValidationError
+		if errors.As(config.Validate(), &others) {
+			invalid = append(invalid, others...)
+		}
+		return nil, invalid
+	}
+
+	return config, nil
+}
+
+// StaticClusters returns the clusters kept registered besides those the
+// scheduler reports. Without a clusters section these are the file servers
+// that cluschk.sh registered.
+func (c *Config) StaticClusters() []models.ClusterInfo {
+	if c.Clusters == nil {
+		return registry.DefaultFileServers()
+	}
+	clusters := make([]models.ClusterInfo, len(c.Clusters))
+	for i, cluster := range c.Clusters {
+		clusters[i] = cluster.Info()
+	}
+	return clusters
+}
+
+// defaults returns the configuration used where neither the file nor the
+// environment sets a value
+func defaults() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            "8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+			WatchInterval:   30 * time.Second,
+		},
 		Storage: storage.Config{
-			Type:     getEnv("STORAGE_TYPE", "json"),
-			JSONPath: getEnv("STORAGE_PATH", "./data"),
+			Type:     "json",
+			JSONPath: "./data",
+			MySQL: &storage.MySQLConfig{
+				Host:     "localhost",
+				Port:     "3306",
+				Database: "cluster_status",
+				User:     "cluster_user",
+				Password: "cluster_pass",
+			},
+		},
+		Registry: RegistryConfig{
+			QstatPath:         "/opt/pbs/bin/qstat",
+			SyncInterval:      24 * time.Hour,
+			PbsnodesPath:      "/opt/pbs/bin/pbsnodes",
+			InventoryInterval: 5 * time.Minute,
+		},
+		Collector: CollectorConfig{
+			RemoteShell:           "sudo -u guest /usr/bin/rsh",
+			DucPath:               "/usr/local/bin/duc",
+			DiskUserInterval:      time.Hour,
+			DiskUserRetention:     35 * 24 * time.Hour,
+			FilesystemInterval:    time.Hour,
+			FilesystemConcurrency: 8,
+			FilesystemRetention:   90 * 24 * time.Hour,
+			HostsFile:             "/etc/hosts.equiv",
+			SaPath:                "/sbin/sa",
+			AccountingInterval:    24 * time.Hour,
+			AccountingExclude:     []string{"root"},
+			NodeLoadInterval:      5 * time.Minute,
+			JobInterval:           5 * time.Minute,
+			UtilizationInterval:   10 * time.Minute,
+		},
+		Alerts: AlertConfig{
+			Interval:             5 * time.Minute,
+			ForecastWindow:       7 * 24 * time.Hour,
+			DiskFillWarningDays:  7,
+			DiskFillCriticalDays: 2,
+			AnomalyInterval:      5 * time.Minute,
+			AnomalyThreshold:     3,
+			AnomalyCritical:      5,
+			AnomalyWindow:        30 * time.Minute,
+			NodeDownCritical:     15 * time.Minute,
+			IncidentAutoOpen:     "critical",
+		},
+		Reports: ReportConfig{
+			TopUsers:             10,
+			LowEfficiency:        0.4,
+			LowEfficiencyMinTime: 12 * time.Hour,
+		},
+		Notify: NotifyConfig{
+			SMTPPort: 25,
+			SMTPFrom: "cluster-status@localhost",
+		},
+		Digest: DigestConfig{
+			Weekday:  "monday",
+			Hour:     8,
+			TopUsers: 5,
+		},
+		Usage: UsageConfig{
+			Retention: 90 * 24 * time.Hour,
+			FairShare: 0,
+		},
+		Chargeback: ChargebackConfig{
+			MinGID:         1000,
+			AccountingRate: 0,
+			Currency:       "JPY",
+		},
+		StatusPage: StatusPageConfig{
+			Title:          "Cluster status",
+			ResolvedWindow: 7 * 24 * time.Hour,
+			FeedWindow:     30 * 24 * time.Hour,
+		},
+		Auth: AuthConfig{
+			Enabled:     true,
+			SessionTTL:  12 * time.Hour,
+			AdminUser:   "admin",
+			DefaultRole: "viewer",
+			TokenTTL:    90 * 24 * time.Hour,
+		},
+		LDAP: LDAPConfig{
+			StartTLS:     true,
+			UserFilter:   "(&(objectClass=posixAccount)(uid={username}))",
+			GroupFilter:  "(&(objectClass=posixGroup)(memberUid={username}))",
+			UsernameAttr: "uid",
+			NameAttr:     "cn",
+			EmailAttr:    "mail",
+			GroupAttr:    "cn",
+			Timeout:      10 * time.Second,
+		},
+		OIDC: OIDCConfig{
+			Scopes:        []string{"profile", "email"},
+			UsernameClaim: "preferred_username",
+			NameClaim:     "name",
+			EmailClaim:    "email",
+			GroupsClaim:   "groups",
+		},
+		Security: SecurityConfig{
+			CORSMethods:           []string{"GET", "POST", "PUT", "DELETE"},
+			CORSMaxAge:            5 * time.Minute,
+			PublicOrigins:         []string{"*"},
+			ContentSecurityPolicy: "default-src 'none'; style-src 'unsafe-inline'; img-src 'self' data:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+			HSTSMaxAge:            180 * 24 * time.Hour,
+			TrustedProxies:        []string{"127.0.0.1", "::1"},
+		},
+		TLS: TLSConfig{},
+		RateLimits: RateLimitConfig{
+			IP:     "50/s,200",
+			API:    "20/s,100",
+			Legacy: "2/s,20",
+			Login:  "10/m",
+			Ingest: "10/s,50",
+			Public: "10/s,50",
 		},
 	}
+}
 
-	// MySQL configuration if storage type is MySQL
-	if config.Storage.Type == "mysql" {
-		config.Storage.MySQL = &storage.MySQLConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "3306"),
-			Database: getEnv("DB_NAME", "cluster_status"),
-			User:     getEnv("DB_USER", "cluster_user"),
-			Password: getEnv("DB_PASSWORD", "cluster_pass"),
+// applyEnv overrides configuration values with the environment variables
+// that are set. It returns an error for every variable that does not parse,
+// named by the variable.
+func (c *Config) applyEnv() ValidationError {
+	var v validator
+
+	c.Server.Port = getEnv("PORT", c.Server.Port)
+	c.Server.ReadTimeout = v.getEnvDuration("SERVER_READ_TIMEOUT", c.Server.ReadTimeout)
+	c.Server.WriteTimeout = v.getEnvDuration("SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout)
+	c.Server.IdleTimeout = v.getEnvDuration("SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout)
+	c.Server.ShutdownTimeout = v.getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+	c.Server.WatchInterval = v.getEnvDuration("CONFIG_WATCH_INTERVAL", c.Server.WatchInterval)
+	c.Storage.Type = getEnv("STORAGE_TYPE", c.Storage.Type)
+	c.Storage.JSONPath = getEnv("STORAGE_PATH", c.Storage.JSONPath)
+	if c.Storage.MySQL != nil {
+		c.Storage.MySQL.Host = getEnv("DB_HOST", c.Storage.MySQL.Host)
+		c.Storage.MySQL.Port = getEnv("DB_PORT", c.Storage.MySQL.Port)
+		c.Storage.MySQL.Database = getEnv("DB_NAME", c.Storage.MySQL.Database)
+		c.Storage.MySQL.User = getEnv("DB_USER", c.Storage.MySQL.User)
+		c.Storage.MySQL.Password = getEnv("DB_PASSWORD", c.Storage.MySQL.Password)
+	}
+	for _, key := range sortedKeys(storageOptionEnv) {
+		if value := os.Getenv(key); value != "" {
+			if c.Storage.Options == nil {
+				c.Storage.Options = map[string]string{}
+			}
+			c.Storage.Options[storageOptionEnv[key]] = value
 		}
 	}
+	c.Registry.QstatPath = getEnv("QSTAT_PATH", c.Registry.QstatPath)
+	c.Registry.SyncInterval = v.getEnvDuration("REGISTRY_SYNC_INTERVAL", c.Registry.SyncInterval)
+	c.Registry.PbsnodesPath = getEnv("PBSNODES_PATH", c.Registry.PbsnodesPath)
+	c.Registry.InventoryInterval = v.getEnvDuration("NODE_INVENTORY_INTERVAL", c.Registry.InventoryInterval)
+	c.Collector.RemoteShell = getEnv("REMOTE_SHELL", c.Collector.RemoteShell)
+	c.Collector.DucPath = getEnv("DUC_PATH", c.Collector.DucPath)
+	c.Collector.DucIndex = v.getEnvBool("DUC_INDEX", c.Collector.DucIndex)
+	c.Collector.DiskUserInterval = v.getEnvDuration("DISK_USER_INTERVAL", c.Collector.DiskUserInterval)
+	c.Collector.DiskUserRetention = v.getEnvDuration("DISK_USER_RETENTION", c.Collector.DiskUserRetention)
+	c.Collector.FilesystemInterval = v.getEnvDuration("FILESYSTEM_INTERVAL", c.Collector.FilesystemInterval)
+	c.Collector.FilesystemConcurrency = v.getEnvInt("FILESYSTEM_CONCURRENCY", c.Collector.FilesystemConcurrency)
+	c.Collector.FilesystemRetention = v.getEnvDuration("FILESYSTEM_HISTORY_RETENTION", c.Collector.FilesystemRetention)
+	c.Collector.HostsFile = getEnv("ACCOUNTING_HOSTS_FILE", c.Collector.HostsFile)
+	c.Collector.SaPath = getEnv("SA_PATH", c.Collector.SaPath)
+	c.Collector.AccountingInterval = v.getEnvDuration("ACCOUNTING_INTERVAL", c.Collector.AccountingInterval)
+	c.Collector.AccountingExclude = getEnvList("ACCOUNTING_EXCLUDE_USERS", c.Collector.AccountingExclude)
+	c.Collector.NodeLoadInterval = v.getEnvDuration("NODE_LOAD_INTERVAL", c.Collector.NodeLoadInterval)
+	c.Collector.JobInterval = v.getEnvDuration("JOB_INTERVAL", c.Collector.JobInterval)
+	c.Collector.UtilizationInterval = v.getEnvDuration("UTILIZATION_INTERVAL", c.Collector.UtilizationInterval)
+	c.Alerts.Interval = v.getEnvDuration("ALERT_INTERVAL", c.Alerts.Interval)
+	c.Alerts.ForecastWindow = v.getEnvDuration("FORECAST_WINDOW", c.Alerts.ForecastWindow)
+	c.Alerts.DiskFillWarningDays = v.getEnvFloat("DISK_FILL_WARNING_DAYS", c.Alerts.DiskFillWarningDays)
+	c.Alerts.DiskFillCriticalDays = v.getEnvFloat("DISK_FILL_CRITICAL_DAYS", c.Alerts.DiskFillCriticalDays)
+	c.Alerts.AnomalyInterval = v.getEnvDuration("ANOMALY_INTERVAL", c.Alerts.AnomalyInterval)
+	c.Alerts.AnomalyThreshold = v.getEnvFloat("ANOMALY_THRESHOLD", c.Alerts.AnomalyThreshold)
+	c.Alerts.AnomalyCritical = v.getEnvFloat("ANOMALY_CRITICAL_THRESHOLD", c.Alerts.AnomalyCritical)
+	c.Alerts.AnomalyWindow = v.getEnvDuration("ANOMALY_ALERT_WINDOW", c.Alerts.AnomalyWindow)
+	c.Alerts.NodeDownCritical = v.getEnvDuration("NODE_DOWN_CRITICAL_AFTER", c.Alerts.NodeDownCritical)
+	c.Alerts.IncidentAutoOpen = getEnv("INCIDENT_AUTO_OPEN", c.Alerts.IncidentAutoOpen)
+	c.Reports.TopUsers = v.getEnvInt("REPORT_TOP_USERS", c.Reports.TopUsers)
+	c.Reports.LowEfficiency = v.getEnvFloat("REPORT_LOW_EFFICIENCY", c.Reports.LowEfficiency)
+	c.Reports.LowEfficiencyMinTime = v.getEnvDuration("REPORT_LOW_EFFICIENCY_MIN_WALLTIME", c.Reports.LowEfficiencyMinTime)
+	c.Notify.SMTPHost = getEnv("SMTP_HOST", c.Notify.SMTPHost)
+	c.Notify.SMTPPort = v.getEnvInt("SMTP_PORT", c.Notify.SMTPPort)
+	c.Notify.SMTPUsername = getEnv("SMTP_USERNAME", c.Notify.SMTPUsername)
+	c.Notify.SMTPPassword = getEnv("SMTP_PASSWORD", c.Notify.SMTPPassword)
+	c.Notify.SMTPFrom = getEnv("SMTP_FROM", c.Notify.SMTPFrom)
+	c.Digest.Weekday = getEnv("DIGEST_WEEKDAY", c.Digest.Weekday)
+	c.Digest.Hour = v.getEnvInt("DIGEST_HOUR", c.Digest.Hour)
+	c.Digest.TopUsers = v.getEnvInt("DIGEST_TOP_USERS", c.Digest.TopUsers)
+	c.Usage.Retention = v.getEnvDuration("USAGE_RETENTION", c.Usage.Retention)
+	c.Usage.FairShare = v.getEnvFloat("USAGE_FAIR_SHARE", c.Usage.FairShare)
+	c.Chargeback.GroupFile = getEnv("CHARGEBACK_GROUP_FILE", c.Chargeback.GroupFile)
+	c.Chargeback.MinGID = v.getEnvInt("CHARGEBACK_GROUP_MIN_GID", c.Chargeback.MinGID)
+	c.Chargeback.Rates = getEnvList("CHARGEBACK_RATES", c.Chargeback.Rates)
+	c.Chargeback.AccountingRate = v.getEnvFloat("CHARGEBACK_ACCOUNTING_RATE", c.Chargeback.AccountingRate)
+	c.Chargeback.Currency = getEnv("CHARGEBACK_CURRENCY", c.Chargeback.Currency)
+	c.StatusPage.Title = getEnv("STATUS_PAGE_TITLE", c.StatusPage.Title)
+	c.StatusPage.URL = getEnv("STATUS_PAGE_URL", c.StatusPage.URL)
+	c.StatusPage.ResolvedWindow = v.getEnvDuration("STATUS_RESOLVED_WINDOW", c.StatusPage.ResolvedWindow)
+	c.StatusPage.FeedWindow = v.getEnvDuration("STATUS_FEED_WINDOW", c.StatusPage.FeedWindow)
+	c.Auth.Enabled = v.getEnvBool("AUTH_ENABLED", c.Auth.Enabled)
+	c.Auth.SessionSecret = getEnv("AUTH_SESSION_SECRET", c.Auth.SessionSecret)
+	c.Auth.SessionTTL = v.getEnvDuration("AUTH_SESSION_TTL", c.Auth.SessionTTL)
+	c.Auth.CookieSecure = v.getEnvBool("AUTH_COOKIE_SECURE", c.Auth.CookieSecure)
+	c.Auth.AdminUser = getEnv("AUTH_ADMIN_USER", c.Auth.AdminUser)
+	c.Auth.AdminPassword = getEnv("AUTH_ADMIN_PASSWORD", c.Auth.AdminPassword)
+	c.Auth.GroupRoles = getEnvList("AUTH_GROUP_ROLES", c.Auth.GroupRoles)
+	c.Auth.DefaultRole = getEnv("AUTH_DEFAULT_ROLE", c.Auth.DefaultRole)
+	c.Auth.TokenTTL = v.getEnvDuration("AUTH_TOKEN_TTL", c.Auth.TokenTTL)
+	c.LDAP.URL = getEnv("LDAP_URL", c.LDAP.URL)
+	c.LDAP.StartTLS = v.getEnvBool("LDAP_START_TLS", c.LDAP.StartTLS)
+	c.LDAP.CAFile = getEnv("LDAP_CA_FILE", c.LDAP.CAFile)
+	c.LDAP.SkipVerify = v.getEnvBool("LDAP_TLS_SKIP_VERIFY", c.LDAP.SkipVerify)
+	c.LDAP.BindDN = getEnv("LDAP_BIND_DN", c.LDAP.BindDN)
+	c.LDAP.BindPassword = getEnv("LDAP_BIND_PASSWORD", c.LDAP.BindPassword)
+	c.LDAP.BaseDN = getEnv("LDAP_BASE_DN", c.LDAP.BaseDN)
+	c.LDAP.UserFilter = getEnv("LDAP_USER_FILTER", c.LDAP.UserFilter)
+	c.LDAP.GroupBaseDN = getEnv("LDAP_GROUP_BASE_DN", c.LDAP.GroupBaseDN)
+	c.LDAP.GroupFilter = getEnv("LDAP_GROUP_FILTER", c.LDAP.GroupFilter)
+	c.LDAP.RequiredGroups = getEnvList("LDAP_REQUIRED_GROUPS", c.LDAP.RequiredGroups)
+	c.LDAP.UsernameAttr = getEnv("LDAP_USERNAME_ATTR", c.LDAP.UsernameAttr)
+	c.LDAP.NameAttr = getEnv("LDAP_NAME_ATTR", c.LDAP.NameAttr)
+	c.LDAP.EmailAttr = getEnv("LDAP_EMAIL_ATTR", c.LDAP.EmailAttr)
+	c.LDAP.GroupAttr = getEnv("LDAP_GROUP_ATTR", c.LDAP.GroupAttr)
+	c.LDAP.Timeout = v.getEnvDuration("LDAP_TIMEOUT", c.LDAP.Timeout)
+	c.OIDC.Issuer = getEnv("OIDC_ISSUER", c.OIDC.Issuer)
+	c.OIDC.ClientID = getEnv("OIDC_CLIENT_ID", c.OIDC.ClientID)
+	c.OIDC.ClientSecret = getEnv("OIDC_CLIENT_SECRET", c.OIDC.ClientSecret)
+	c.OIDC.RedirectURL = getEnv("OIDC_REDIRECT_URL", c.OIDC.RedirectURL)
+	c.OIDC.Scopes = getEnvList("OIDC_SCOPES", c.OIDC.Scopes)
+	c.OIDC.UsernameClaim = getEnv("OIDC_USERNAME_CLAIM", c.OIDC.UsernameClaim)
+	c.OIDC.NameClaim = getEnv("OIDC_NAME_CLAIM", c.OIDC.NameClaim)
+	c.OIDC.EmailClaim = getEnv("OIDC_EMAIL_CLAIM", c.OIDC.EmailClaim)
+	c.OIDC.GroupsClaim = getEnv("OIDC_GROUPS_CLAIM", c.OIDC.GroupsClaim)
+	c.OIDC.RequiredGroups = getEnvList("OIDC_REQUIRED_GROUPS", c.OIDC.RequiredGroups)
+	c.Security.CORSOrigins = getEnvList("CORS_ALLOWED_ORIGINS", c.Security.CORSOrigins)
+	c.Security.CORSMethods = getEnvList("CORS_ALLOWED_METHODS", c.Security.CORSMethods)
+	c.Security.CORSCredentials = v.getEnvBool("CORS_ALLOW_CREDENTIALS", c.Security.CORSCredentials)
+	c.Security.CORSMaxAge = v.getEnvDuration("CORS_MAX_AGE", c.Security.CORSMaxAge)
+	c.Security.PublicOrigins = getEnvList("CORS_PUBLIC_ORIGINS", c.Security.PublicOrigins)
+	c.Security.ContentSecurityPolicy = getEnv("SECURITY_CSP", c.Security.ContentSecurityPolicy)
+	c.Security.FrameOptions = getEnv("SECURITY_FRAME_OPTIONS", c.Security.FrameOptions)
+	c.Security.HSTSMaxAge = v.getEnvDuration("SECURITY_HSTS_MAX_AGE", c.Security.HSTSMaxAge)
+	c.Security.TrustedProxies = getEnvList("TRUSTED_PROXIES", c.Security.TrustedProxies)
+	c.TLS.CertFile = getEnv("TLS_CERT_FILE", c.TLS.CertFile)
+	c.TLS.KeyFile = getEnv("TLS_KEY_FILE", c.TLS.KeyFile)
+	c.TLS.ClientCAFile = getEnv("TLS_CLIENT_CA_FILE", c.TLS.ClientCAFile)
+	c.TLS.ClientCertRequired = v.getEnvBool("TLS_CLIENT_CERT_REQUIRED", c.TLS.ClientCertRequired)
+	c.TLS.GlobalIngestNames = getEnvList("TLS_GLOBAL_INGEST_NAMES", c.TLS.GlobalIngestNames)
+	c.RateLimits.IP = getEnv("RATE_LIMIT_IP", c.RateLimits.IP)
+	c.RateLimits.API = getEnv("RATE_LIMIT_API", c.RateLimits.API)
+	c.RateLimits.Legacy = getEnv("RATE_LIMIT_LEGACY", c.RateLimits.Legacy)
+	c.RateLimits.Login = getEnv("RATE_LIMIT_LOGIN", c.RateLimits.Login)
+	c.RateLimits.Ingest = getEnv("RATE_LIMIT_INGEST", c.RateLimits.Ingest)
+	c.RateLimits.Public = getEnv("RATE_LIMIT_PUBLIC", c.RateLimits.Public)
+	return v.errs
+}
 
-	return config, nil
+// FieldError is a configuration value that failed validation
+type FieldError struct {
+	Field   string `json:"field"` // Key in the configuration file, such as "alerts.interval"
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every invalid value of a configuration
+type ValidationError []FieldError
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validator collects the field errors of a configuration
+type validator struct {
+	errs ValidationError
+}
+
+// check records an error for field unless ok
+func (v *validator) check(ok bool, field, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// add records err for field unless it is nil
+func (v *validator) add(field string, err error) {
+	if err != nil {
+		v.errs = append(v.errs, FieldError{Field: field, Message: err.Error()})
+	}
 }
 
-// Validate validates the configuration
+// positive checks that durations are positive
+func (v *validator) positive(durations map[string]time.Duration) {
+	for _, field := range sortedKeys(durations) {
+		v.check(durations[field] > 0, field, "must be positive")
+	}
+}
+
+// Validate validates the configuration. It returns a ValidationError listing
+// every invalid value rather than stopping at the first one.
 func (c *Config) Validate() error {
-	if c.ServerPort == "" {
-		return fmt.Errorf("server port is required")
+	var v validator
+
+	v.check(c.Server.Port != "", "server.port", "is required")
+	v.positive(map[string]time.Duration{
+		"server.read_timeout":     c.Server.ReadTimeout,
+		"server.write_timeout":    c.Server.WriteTimeout,
+		"server.idle_timeout":     c.Server.IdleTimeout,
+		"server.shutdown_timeout": c.Server.ShutdownTimeout,
+	})
+	v.check(c.Server.WatchInterval >= 0, "server.watch_interval", "must not be negative")
+
+	switch c.Storage.Type {
+	case "json":
+		v.check(c.Storage.JSONPath != "", "storage.json_path", "is required for JSON storage")
+	case "mysql":
+		v.check(c.Storage.MySQL != nil, "storage.mysql", "is required when storage type is mysql")
+	default:
+		v.check(false, "storage.type", "invalid storage type %q, expected json or mysql", c.Storage.Type)
+	}
+	options := storage.CheckOptions(c.Storage.Type, c.Storage.Options)
+	for _, name := range sortedKeys(options) {
+		v.add("storage.options."+name, options[name])
 	}
 
-	if c.Storage.Type == "mysql" && c.Storage.MySQL == nil {
-		return fmt.Errorf("MySQL configuration is required when storage type is mysql")
+	v.positive(map[string]time.Duration{
+		"registry.sync_interval":      c.Registry.SyncInterval,
+		"registry.inventory_interval": c.Registry.InventoryInterval,
+	})
+
+	names := map[string]bool{}
+	for i, cluster := range c.Clusters {
+		field := fmt.Sprintf("clusters[%d]", i)
+		v.add(field, registry.Validate(cluster.Info()))
+		v.check(!names[cluster.Name], field, "duplicate cluster name %q", cluster.Name)
+		names[cluster.Name] = true
+	}
+
+	v.positive(map[string]time.Duration{
+		"collectors.disk_user_interval":   c.Collector.DiskUserInterval,
+		"collectors.filesystem_interval":  c.Collector.FilesystemInterval,
+		"collectors.accounting_interval":  c.Collector.AccountingInterval,
+		"collectors.node_load_interval":   c.Collector.NodeLoadInterval,
+		"collectors.job_interval":         c.Collector.JobInterval,
+		"collectors.utilization_interval": c.Collector.UtilizationInterval,
+	})
+	v.check(c.Collector.FilesystemConcurrency > 0, "collectors.filesystem_concurrency", "must be positive")
+
+	v.positive(map[string]time.Duration{
+		"alerts.interval":         c.Alerts.Interval,
+		"alerts.anomaly_interval": c.Alerts.AnomalyInterval,
+	})
+	v.check(c.Alerts.DiskFillWarningDays >= c.Alerts.DiskFillCriticalDays, "alerts.disk_fill_warning_days",
+		"must be at least disk_fill_critical_days")
+	v.check(c.Alerts.AnomalyThreshold > 0, "alerts.anomaly_threshold", "must be positive")
+	v.check(c.Alerts.AnomalyCritical >= c.Alerts.AnomalyThreshold, "alerts.anomaly_critical",
+		"must be at least the anomaly threshold")
+	switch c.Alerts.IncidentAutoOpen {
+	case "warning", "critical", "none":
+	default:
+		v.check(false, "alerts.incident_auto_open", "invalid severity %q, expected warning, critical or none", c.Alerts.IncidentAutoOpen)
+	}
+
+	v.check(c.Notify.SMTPPort > 0 && c.Notify.SMTPPort < 65536, "notify.smtp_port", "must be a TCP port")
+
+	if _, err := c.Digest.ParseWeekday(); err != nil {
+		v.add("digest.weekday", err)
+	}
+	v.check(c.Digest.Hour >= 0 && c.Digest.Hour <= 23, "digest.hour", "must be between 0 and 23")
+
+	v.check(c.Usage.FairShare >= 0 && c.Usage.FairShare <= 100, "usage.fair_share", "must be between 0 and 100 percent")
+
+	if _, err := c.Chargeback.ParseRates(); err != nil {
+		v.add("chargeback.rates", err)
+	}
+	v.check(c.Chargeback.AccountingRate >= 0, "chargeback.accounting_rate", "must not be negative")
+
+	v.check(c.StatusPage.ResolvedWindow >= 0, "status_page.resolved_window", "must not be negative")
+	v.check(c.StatusPage.FeedWindow > 0, "status_page.feed_window", "must be positive")
+
+	v.check(c.Auth.SessionSecret == "" || len(c.Auth.SessionSecret) >= 32, "auth.session_secret", "must be at least 32 characters")
+	v.check(c.Auth.SessionTTL > 0, "auth.session_ttl", "must be positive")
+	v.check(c.Auth.TokenTTL >= 0, "auth.token_ttl", "must not be negative")
+	if _, err := auth.ParseRoleMap(c.Auth.GroupRoles); err != nil {
+		v.add("auth.group_roles", err)
+	}
+	if c.Auth.DefaultRole != "" {
+		v.add("auth.default_role", auth.ValidateGrant(models.RoleGrant{Role: c.Auth.DefaultRole}))
 	}
 
+	if c.LDAP.URL != "" {
+		u, err := url.Parse(c.LDAP.URL)
+		v.check(err == nil && (u.Scheme == "ldap" || u.Scheme == "ldaps"), "ldap.url", "must be an ldap:// or ldaps:// URL")
+		v.check(c.LDAP.BaseDN != "", "ldap.base_dn", "is required")
+		v.check(c.LDAP.Timeout > 0, "ldap.timeout", "must be positive")
+	}
+
+	if c.OIDC.Issuer != "" {
+		u, err := url.Parse(c.OIDC.Issuer)
+		v.check(err == nil && (u.Scheme == "https" || u.Scheme == "http"), "oidc.issuer", "must be an http:// or https:// URL")
+		v.check(c.OIDC.ClientID != "", "oidc.client_id", "is required")
+		u, err = url.Parse(c.OIDC.RedirectURL)
+		v.check(err == nil && u.IsAbs(), "oidc.redirect_url", "must be an absolute URL")
+		v.check(c.OIDC.UsernameClaim != "", "oidc.username_claim", "is required")
+	}
+
+	v.add("security.cors_origins", validateOrigins(c.Security.CORSOrigins))
+	v.add("security.public_origins", validateOrigins(c.Security.PublicOrigins))
+	v.check(!c.Security.CORSCredentials || !contains(c.Security.CORSOrigins, "*"), "security.cors_credentials",
+		"cannot be allowed for every origin")
+	for _, m := range c.Security.CORSMethods {
+		switch m {
+		case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE":
+		default:
+			v.check(false, "security.cors_methods", "invalid method %q", m)
+		}
+	}
+	v.check(c.Security.CORSMaxAge >= 0, "security.cors_max_age", "must not be negative")
+	v.check(c.Security.HSTSMaxAge >= 0, "security.hsts_max_age", "must not be negative")
+	if _, err := auth.ParseTrustedProxies(c.Security.TrustedProxies); err != nil {
+		v.add("security.trusted_proxies", err)
+	}
+	switch c.Security.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		v.check(false, "security.frame_options", "invalid value %q, expected DENY, SAMEORIGIN or empty", c.Security.FrameOptions)
+	}
+
+	v.check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls.key_file", "must be set together with the certificate file")
+	v.check(c.TLS.ClientCAFile == "" || c.TLS.CertFile != "", "tls.client_ca_file", "requires a TLS certificate")
+	v.check(!c.TLS.ClientCertRequired || c.TLS.ClientCAFile != "", "tls.client_cert_required", "needs a TLS client CA file")
+	v.check(len(c.TLS.GlobalIngestNames) == 0 || c.TLS.ClientCAFile != "", "tls.global_ingest_names", "needs a TLS client CA file")
+
+	limits := c.RateLimits.Groups()
+	for _, name := range sortedKeys(limits) {
+		if _, err := ratelimit.ParseLimit(limits[name]); err != nil {
+			v.add("rate_limits."+name, err)
+		}
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// validateOrigins checks that origins are "*" or scheme://host[:port]
+func validateOrigins(origins []string) error {
+	for _, o := range origins {
+		if o == "*" {
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("invalid origin %q, expected * or scheme://host[:port]", o)
+		}
+	}
 	return nil
 }
 
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectorConfig holds configuration of the Go collectors
+type CollectorConfig struct {
+	RemoteShell           string        `yaml:"remote_shell"`           // Command prefix used to run commands on cluster hosts
+	DucPath               string        `yaml:"duc_path"`               // Path to duc on the cluster masters
+	DucIndex              bool          `yaml:"duc_index"`              // Re-index before listing per-user usage
+	DiskUserInterval      time.Duration `yaml:"disk_user_interval"`     // Per-user disk usage collection interval
+	DiskUserRetention     time.Duration `yaml:"disk_user_retention"`    // How long per-user snapshots are kept
+	FilesystemInterval    time.Duration `yaml:"filesystem_interval"`    // Filesystem capacity collection interval
+	FilesystemConcurrency int           `yaml:"filesystem_concurrency"` // Hosts queried in parallel by the filesystem collector
+	FilesystemRetention   time.Duration `yaml:"filesystem_retention"`   // How long filesystem history is kept
+	HostsFile             string        `yaml:"hosts_file"`             // Hosts queried for process accounting
+	SaPath                string        `yaml:"sa_path"`                // Path to sa on the hosts
+	AccountingInterval    time.Duration `yaml:"accounting_interval"`    // Process accounting collection interval
+	AccountingExclude     []string      `yaml:"accounting_exclude"`     // Users not recorded by process accounting
+	NodeLoadInterval      time.Duration `yaml:"node_load_interval"`     // Per-node load average collection interval
+	JobInterval           time.Duration `yaml:"job_interval"`           // How often running PBS jobs are recorded
+	UtilizationInterval   time.Duration `yaml:"utilization_interval"`   // How often cluster utilization is sampled for reports
+}
+
+// AlertConfig holds alert rule configuration
+type AlertConfig struct {
+	Interval             time.Duration `yaml:"interval"`                // How often the rules are evaluated
+	ForecastWindow       time.Duration `yaml:"forecast_window"`         // History used to forecast disk fill
+	DiskFillWarningDays  float64       `yaml:"disk_fill_warning_days"`  // Warn when a mount fills within this many days
+	DiskFillCriticalDays float64       `yaml:"disk_fill_critical_days"` // Critical when a mount fills within this many days
+	AnomalyInterval      time.Duration `yaml:"anomaly_interval"`        // How often series are checked for anomalies
+	AnomalyThreshold     float64       `yaml:"anomaly_threshold"`       // Score recorded as an anomaly and alerted as warning
+	AnomalyCritical      float64       `yaml:"anomaly_critical"`        // Score alerted as critical
+	AnomalyWindow        time.Duration `yaml:"anomaly_window"`          // How long an anomaly keeps its alert firing
+	NodeDownCritical     time.Duration `yaml:"node_down_critical"`      // Node outages longer than this are critical
+	IncidentAutoOpen     string        `yaml:"incident_auto_open"`      // Lowest alert severity opening an incident; "none" disables
+}
+
+// ReportConfig holds monthly report configuration
+type ReportConfig struct {
+	TopUsers             int           `yaml:"top_users"`               // Users listed in a report
+	LowEfficiency        float64       `yaml:"low_efficiency"`          // Jobs below this CPU efficiency (0-1) are listed
+	LowEfficiencyMinTime time.Duration `yaml:"low_efficiency_min_time"` // Minimum walltime of a listed job
+}
+
+// NotifyConfig holds mail notifier configuration
+type NotifyConfig struct {
+	SMTPHost     string `yaml:"smtp_host"` // Mail server; empty disables mail
+	SMTPPort     int    `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	SMTPFrom     string `yaml:"smtp_from"`
+}
+
+// DigestConfig holds weekly digest configuration
+type DigestConfig struct {
+	Weekday  string `yaml:"weekday"`   // Local day the digest is sent, e.g. "monday"
+	Hour     int    `yaml:"hour"`      // Local hour the digest is sent
+	TopUsers int    `yaml:"top_users"` // Disk users listed per cluster
+}
+
+// UsageConfig holds per-user usage configuration
+type UsageConfig struct {
+	Retention time.Duration `yaml:"retention"`  // How long per-user usage samples are kept
+	FairShare float64       `yaml:"fair_share"` // Target share per user in percent; 0 splits evenly among active users
+}
+
+// ChargebackConfig holds research group chargeback configuration
+type ChargebackConfig struct {
+	GroupFile      string   `yaml:"group_file"`      // /etc/group format file imported on startup; empty disables the import
+	MinGID         int      `yaml:"min_gid"`         // Groups below this GID are not imported
+	Rates          []string `yaml:"rates"`           // Core hour rates as "cluster=rate"; "default=rate" applies to other clusters
+	AccountingRate float64  `yaml:"accounting_rate"` // Rate of one CPU hour from process accounting
+	Currency       string   `yaml:"currency"`        // Currency shown on invoices
+}
+
+// ParseRates returns the configured core hour rate per cluster, with the
+// rate of other clusters under "default"
+func (c ChargebackConfig) ParseRates() (map[string]float64, error) {
+	rates := make(map[string]float64, len(c.Rates))
+	for _, item := range c.Rates {
+		name, value, ok := strings.Cut(item, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || rate < 0 || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid chargeback rate %q, expected cluster=rate", item)
+		}
+		rates[strings.TrimSpace(name)] = rate
+	}
+	return rates, nil
+}
+
+// ParseWeekday returns the configured digest weekday
+func (c DigestConfig) ParseWeekday() (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), c.Weekday) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid digest weekday %q", c.Weekday)
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -61,3 +749,168 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration parses a duration environment variable or returns a default value
+func (v *validator) getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil {
+			return d
+		}
+		v.check(false, key, "invalid duration %q", value)
+	}
+	return defaultValue
+}
+
+// getEnvBool parses a boolean environment variable or returns a default value
+func (v *validator) getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err == nil {
+			return b
+		}
+		v.check(false, key, "invalid boolean %q, expected true or false", value)
+	}
+	return defaultValue
+}
+
+// getEnvInt parses an integer environment variable or returns a default value
+func (v *validator) getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			return n
+		}
+		v.check(false, key, "invalid integer %q", value)
+	}
+	return defaultValue
+}
+
+// getEnvList parses a comma separated environment variable or returns a
+// default value. Like the other getters, an empty variable keeps the default.
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvFloat parses a float environment variable or returns a default value
+func (v *validator) getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		f, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return f
+		}
+		v.check(false, key, "invalid number %q", value)
+	}
+	return defaultValue
+}
+
+// StatusPageConfig holds public status page configuration
+type StatusPageConfig struct {
+	Title          string        `yaml:"title"`           // Title of the page and the Atom feed
+	URL            string        `yaml:"url"`             // Public URL of the server used in feed links; empty uses the request host
+	ResolvedWindow time.Duration `yaml:"resolved_window"` // How long resolved incidents stay on the page
+	FeedWindow     time.Duration `yaml:"feed_window"`     // How far back the Atom feed goes
+}
+
+// AuthConfig holds API authentication configuration
+type AuthConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // Require a session for /api routes
+	SessionSecret string        `yaml:"session_secret"` // Key signing session cookies; empty generates one per start
+	SessionTTL    time.Duration `yaml:"session_ttl"`    // Lifetime of a session
+	CookieSecure  bool          `yaml:"cookie_secure"`  // Send the session cookie over HTTPS only
+	AdminUser     string        `yaml:"admin_user"`     // Local user created on startup when AdminPassword is set
+	AdminPassword string        `yaml:"admin_password"` // Password of AdminUser; it is only set when the user does not exist
+	GroupRoles    []string      `yaml:"group_roles"`    // Roles of directory groups as "group=role" or "group=role@cluster"
+	DefaultRole   string        `yaml:"default_role"`   // Role of directory users in no mapped group; empty for none
+	TokenTTL      time.Duration `yaml:"token_ttl"`      // Lifetime of API tokens created without an expiry; 0 for none
+}
+
+// SecurityConfig holds cross-origin and security header configuration
+type SecurityConfig struct {
+	CORSOrigins           []string      `yaml:"cors_origins"`            // Origins allowed to call /api from a browser; empty allows only the server's own origin
+	CORSMethods           []string      `yaml:"cors_methods"`            // Methods allowed for cross-origin /api requests
+	CORSCredentials       bool          `yaml:"cors_credentials"`        // Allow cross-origin /api requests to send the session cookie
+	CORSMaxAge            time.Duration `yaml:"cors_max_age"`            // How long browsers may cache preflight responses
+	PublicOrigins         []string      `yaml:"public_origins"`          // Origins allowed to read the public status page data
+	ContentSecurityPolicy string        `yaml:"content_security_policy"` // Content-Security-Policy header; empty omits it
+	FrameOptions          string        `yaml:"frame_options"`           // X-Frame-Options header, DENY or SAMEORIGIN; empty omits it
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age"`            // Strict-Transport-Security max age on HTTPS requests; 0 omits it
+	TrustedProxies        []string      `yaml:"trusted_proxies"`         // Addresses and CIDR ranges of reverse proxies whose X-Forwarded-For is trusted
+}
+
+// TLSConfig holds native HTTPS configuration
+type TLSConfig struct {
+	CertFile           string   `yaml:"cert_file"`            // PEM certificate chain; empty serves plain HTTP
+	KeyFile            string   `yaml:"key_file"`             // PEM private key of the certificate
+	ClientCAFile       string   `yaml:"client_ca_file"`       // PEM CAs verifying client certificates on the ingest routes; empty disables mTLS
+	ClientCertRequired bool     `yaml:"client_cert_required"` // Ingest requests need a client certificate; otherwise tokens are accepted too
+	GlobalIngestNames  []string `yaml:"global_ingest_names"`  // Client certificate names allowed to push the global ingest keys
+}
+
+// RateLimitConfig holds the rate limit of each route group as
+// "requests/unit[,burst]" with a unit of s, m or h, or "off"
+type RateLimitConfig struct {
+	IP     string `yaml:"ip"`     // Every /api route per IP, before authentication
+	API    string `yaml:"api"`    // Authenticated /api routes, per token, user or IP
+	Legacy string `yaml:"legacy"` // The /api/metrics and /api/cluster dumps, on top of API
+	Login  string `yaml:"login"`  // Login endpoints, per IP
+	Ingest string `yaml:"ingest"` // The ingest API, per token or certificate
+	Public string `yaml:"public"` // Health check and status page, per IP
+}
+
+// Groups returns the limits by route group name
+func (c RateLimitConfig) Groups() map[string]string {
+	return map[string]string{
+		"ip":     c.IP,
+		"api":    c.API,
+		"legacy": c.Legacy,
+		"login":  c.Login,
+		"ingest": c.Ingest,
+		"public": c.Public,
+	}
+}
+
+// LDAPConfig holds LDAP authentication configuration
+type LDAPConfig struct {
+	URL            string        `yaml:"url"`             // ldap:// or ldaps:// URL; empty disables LDAP
+	StartTLS       bool          `yaml:"start_tls"`       // Upgrade ldap:// connections with StartTLS
+	CAFile         string        `yaml:"ca_file"`         // PEM file of the CA certificates trusted for the server
+	SkipVerify     bool          `yaml:"skip_verify"`     // Do not verify the server certificate (testing only)
+	BindDN         string        `yaml:"bind_dn"`         // Service account searching the directory; empty binds anonymously
+	BindPassword   string        `yaml:"bind_password"`   // Password of BindDN
+	BaseDN         string        `yaml:"base_dn"`         // Where users are searched
+	UserFilter     string        `yaml:"user_filter"`     // Filter finding a user; {username} is replaced
+	GroupBaseDN    string        `yaml:"group_base_dn"`   // Where groups are searched; empty uses BaseDN
+	GroupFilter    string        `yaml:"group_filter"`    // Filter finding the groups of a user; {username} and {dn} are replaced
+	RequiredGroups []string      `yaml:"required_groups"` // Only members of one of these groups may log in
+	UsernameAttr   string        `yaml:"username_attr"`   // Attribute holding the login name
+	NameAttr       string        `yaml:"name_attr"`       // Attribute holding the display name
+	EmailAttr      string        `yaml:"email_attr"`      // Attribute holding the email address
+	GroupAttr      string        `yaml:"group_attr"`      // Attribute holding the name of a group
+	Timeout        time.Duration `yaml:"timeout"`         // Connection and search timeout
+}
+
+// OIDCConfig holds OpenID Connect login configuration
+type OIDCConfig struct {
+	Issuer         string   `yaml:"issuer"`          // Issuer URL, used for discovery; empty disables OIDC
+	ClientID       string   `yaml:"client_id"`       // Client registered with the provider
+	ClientSecret   string   `yaml:"client_secret"`   // Secret of the client; empty for public clients
+	RedirectURL    string   `yaml:"redirect_url"`    // Callback URL registered with the provider
+	Scopes         []string `yaml:"scopes"`          // Requested in addition to "openid"
+	UsernameClaim  string   `yaml:"username_claim"`  // Claim holding the login name
+	NameClaim      string   `yaml:"name_claim"`      // Claim holding the display name
+	EmailClaim     string   `yaml:"email_claim"`     // Claim holding the email address
+	GroupsClaim    string   `yaml:"groups_claim"`    // Claim listing groups; dots reach into nested objects
+	RequiredGroups []string `yaml:"required_groups"` // Only members of one of these groups may log in
+}
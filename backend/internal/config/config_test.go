@@ -0,0 +1,161 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fields returns the fields of a ValidationError
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	var invalid ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("error = %v, want a ValidationError", err)
+	}
+	names := make([]string, len(invalid))
+	for i, fe := range invalid {
+		names[i] = fe.Field
+	}
+	return names
+}
+
+// writeFile writes a configuration file and points CONFIG_FILE at it
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	return path
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if err := defaults().Validate(); err != nil {
+		t.Errorf("defaults do not validate: %v", err)
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	c := defaults()
+	c.Server.Port = ""
+	c.Storage.Type = "sqlite"
+	c.Alerts.Interval = 0
+	c.Alerts.DiskFillWarningDays = 1
+	c.Digest.Hour = 24
+	c.Security.CORSOrigins = []string{"example.com"}
+	c.TLS.KeyFile = "server.key"
+	c.RateLimits.Login = "10/d"
+
+	want := []string{
+		"server.port",
+		"storage.type",
+		"alerts.interval",
+		"alerts.disk_fill_warning_days",
+		"digest.hour",
+		"security.cors_origins",
+		"tls.key_file",
+		"rate_limits.login",
+	}
+	if got := fields(t, c.Validate()); !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+}
+
+func TestValidateClusters(t *testing.T) {
+	c := defaults()
+	c.Clusters = []ClusterConfig{
+		{Name: "files", Type: "fileserver", MasterHost: "files01"},
+		{Name: "files", Type: "fileserver", MasterHost: "files02"},
+		{Name: "bad name", Type: "fileserver", MasterHost: "files03"},
+	}
+	got := fields(t, c.Validate())
+	if want := []string{"clusters[1]", "clusters[2]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	writeFile(t, `
+server:
+  port: "9090"
+  read_timeout: 5s
+alerts:
+  interval: 1m
+collectors:
+  accounting_exclude: [root, daemon]
+`)
+	t.Setenv("PORT", "9191")
+
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// The environment overrides the file, which overrides the defaults
+	if c.Server.Port != "9191" || c.Server.ReadTimeout != 5*time.Second || c.Alerts.Interval != time.Minute {
+		t.Errorf("server %+v, alert interval %v", c.Server, c.Alerts.Interval)
+	}
+	if !reflect.DeepEqual(c.Collector.AccountingExclude, []string{"root", "daemon"}) {
+		t.Errorf("accounting exclude = %v", c.Collector.AccountingExclude)
+	}
+	if c.Server.WriteTimeout != 15*time.Second {
+		t.Errorf("write timeout missing from the file = %v, want the default", c.Server.WriteTimeout)
+	}
+	if c.Storage.MySQL != nil {
+		t.Error("MySQL configuration kept for JSON storage")
+	}
+}
+
+func TestLoadFileUnknownKey(t *testing.T) {
+	writeFile(t, "alerts:\n  intervall: 1m\n")
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "intervall") {
+		t.Errorf("error = %v, want the unknown key named", err)
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+	t.Setenv("PORT", "")
+	t.Setenv("STORAGE_TYPE", "sqlite")
+
+	_, err := Load()
+	// Unparsable variables are named by the variable, followed by the
+	// values that parse but do not validate
+	got := fields(t, err)
+	if want := []string{"SERVER_READ_TIMEOUT", "storage.type"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+}
+
+func TestGetEnvList(t *testing.T) {
+	defaults := []string{"root"}
+	tests := []struct {
+		name  string
+		value string
+		set   bool
+		want  []string
+	}{
+		{"unset", "", false, defaults},
+		{"empty keeps the default", "", true, defaults},
+		{"blank keeps the default", "  ", true, defaults},
+		{"list", "root, daemon,,nobody", true, []string{"root", "daemon", "nobody"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ACCOUNTING_EXCLUDE_USERS", tt.value)
+			if !tt.set {
+				os.Unsetenv("ACCOUNTING_EXCLUDE_USERS")
+			}
+			if got := getEnvList("ACCOUNTING_EXCLUDE_USERS", defaults); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEnvList = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Diff returns the keys whose values differ between two configurations, as
+// named in the configuration file, such as "alerts.interval". Lists and maps
+// are compared as a whole.
+func Diff(a, b *Config) []string {
+	var keys []string
+	diff("", reflect.ValueOf(*a), reflect.ValueOf(*b), &keys)
+	return keys
+}
+
+// diff appends the keys of the values that differ below prefix
+func diff(prefix string, a, b reflect.Value, keys *[]string) {
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*keys = append(*keys, prefix)
+			}
+			return
+		}
+		diff(prefix, a.Elem(), b.Elem(), keys)
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			diff(name, a.Field(i), b.Field(i), keys)
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*keys = append(*keys, prefix)
+		}
+	}
+}
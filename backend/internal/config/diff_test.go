@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	a := defaults()
+	if keys := Diff(a, defaults()); len(keys) != 0 {
+		t.Errorf("Diff of equal configurations = %v", keys)
+	}
+
+	b := defaults()
+	b.Alerts.Interval = time.Minute
+	b.Storage.MySQL.Host = "db01"
+	b.Security.CORSOrigins = []string{"https://status.example.com"}
+	b.Clusters = []ClusterConfig{{Name: "files", Type: "fileserver", MasterHost: "files01"}}
+	b.File = "other.yaml"
+
+	// Keys are named as in the file, in field order; fields without a key
+	// are ignored
+	want := []string{"storage.mysql.host", "clusters", "alerts.interval", "security.cors_origins"}
+	if keys := Diff(a, b); !reflect.DeepEqual(keys, want) {
+		t.Errorf("Diff = %v, want %v", keys, want)
+	}
+
+	b.Storage.MySQL = nil
+	if keys := Diff(a, b); len(keys) == 0 || keys[0] != "storage.mysql" {
+		t.Errorf("Diff with a nil section = %v, want storage.mysql first", keys)
+	}
+}
@@ -0,0 +1,237 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/notify"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+	"github.com/taisei-ito/cluster-status-monitor/internal/utilization"
+)
+
+// stateKey holds when the digest was last sent
+const stateKey = "digest_state"
+
+// week is the period covered by a digest
+const week = 7 * 24 * time.Hour
+
+// state is the persisted scheduler state
+type state struct {
+	LastSent time.Time `json:"last_sent"`
+}
+
+// Scheduler builds weekly digests from stored data and mails them to the
+// subscribers once a week
+type Scheduler struct {
+	storage       storage.Storage
+	registry      *registry.Registry
+	inventory     *inventory.Inventory
+	utilization   *utilization.Recorder
+	diskUsers     *collector.DiskUserStore
+	alerts        *alert.Engine
+	subscriptions *Subscriptions
+	notifier      notify.Notifier
+	Weekday       time.Weekday // Local day the digest is sent
+	Hour          int          // Local hour the digest is sent
+	TopUsers      int          // Disk users listed per cluster
+}
+
+// NewScheduler creates a digest scheduler
+func NewScheduler(store storage.Storage, reg *registry.Registry, inv *inventory.Inventory, util *utilization.Recorder,
+	diskUsers *collector.DiskUserStore, alerts *alert.Engine, subs *Subscriptions, notifier notify.Notifier) *Scheduler {
+	return &Scheduler{
+		storage:       store,
+		registry:      reg,
+		inventory:     inv,
+		utilization:   util,
+		diskUsers:     diskUsers,
+		alerts:        alerts,
+		subscriptions: subs,
+		notifier:      notifier,
+		Weekday:       time.Monday,
+		Hour:          8,
+		TopUsers:      5,
+	}
+}
+
+// Build compiles the digest of the week ending at end. An empty cluster
+// list covers every cluster.
+func (s *Scheduler) Build(clusters []string, end time.Time) (*models.Digest, error) {
+	if len(clusters) == 0 {
+		names, err := s.registry.Names()
+		if err != nil {
+			return nil, err
+		}
+		clusters = names
+	}
+	selected := make(map[string]bool, len(clusters))
+	for _, c := range clusters {
+		selected[c] = true
+	}
+
+	d := &models.Digest{
+		Start:     end.Add(-week),
+		End:       end,
+		Clusters:  []models.ClusterUtilization{},
+		DiskUsers: []models.DiskUserReport{},
+		DownNodes: []models.Node{},
+		Alerts:    []models.Alert{},
+	}
+
+	utilizations, err := s.utilization.Between(d.Start, d.End)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range utilizations {
+		if selected[u.Cluster] && u.Days > 0 {
+			d.Clusters = append(d.Clusters, u)
+		}
+	}
+	sort.SliceStable(d.Clusters, func(i, j int) bool {
+		if d.Clusters[i].PBSUsage != d.Clusters[j].PBSUsage {
+			return d.Clusters[i].PBSUsage > d.Clusters[j].PBSUsage
+		}
+		return d.Clusters[i].LoadAverage > d.Clusters[j].LoadAverage
+	})
+
+	for _, c := range clusters {
+		report, err := s.diskUsers.Report(c, "", s.TopUsers, week)
+		if err != nil {
+			return nil, err
+		}
+		if report.HasData {
+			d.DiskUsers = append(d.DiskUsers, *report)
+		}
+	}
+
+	nodes, err := s.inventory.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		if selected[n.Cluster] && n.State == models.NodeStateOffline {
+			d.DownNodes = append(d.DownNodes, n)
+		}
+	}
+
+	firing, err := s.alerts.Firing()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range firing {
+		if selected[a.Cluster] {
+			d.Alerts = append(d.Alerts, a)
+		}
+	}
+	return d, nil
+}
+
+// Send mails the digest of the week ending at end to one subscriber
+func (s *Scheduler) Send(ctx context.Context, sub models.DigestSubscription, end time.Time) error {
+	d, err := s.Build(sub.Clusters, end)
+	if err != nil {
+		return err
+	}
+
+	var text, html bytes.Buffer
+	if err := RenderText(&text, d); err != nil {
+		return err
+	}
+	if err := RenderHTML(&html, d); err != nil {
+		return err
+	}
+
+	return s.notifier.Send(ctx, notify.Message{
+		To:      []string{sub.Recipient},
+		Subject: Subject(d),
+		Text:    text.String(),
+		HTML:    html.String(),
+	})
+}
+
+// SendAll mails the digest to every subscriber and returns the number of
+// digests sent
+func (s *Scheduler) SendAll(ctx context.Context) (int, error) {
+	subs, err := s.subscriptions.List()
+	if err != nil {
+		return 0, err
+	}
+
+	end := time.Now().UTC()
+	sent := 0
+	var errs []error
+	for _, sub := range subs {
+		if err := s.Send(ctx, sub, end); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.Recipient, err))
+			continue
+		}
+		sent++
+	}
+
+	if err := storage.SetData(s.storage, stateKey, state{LastSent: end}); err != nil {
+		errs = append(errs, err)
+	}
+	return sent, errors.Join(errs...)
+}
+
+// Run sends the digest at the configured weekday and hour until ctx is done.
+// The last send time is stored, so a restart neither repeats nor skips a
+// week; on first start the schedule begins with the next occurrence.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sendIfDue(ctx, time.Now()); err != nil {
+			log.Printf("Weekly digest failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendIfDue sends the digest when the latest scheduled time has passed since
+// the last send
+func (s *Scheduler) sendIfDue(ctx context.Context, now time.Time) error {
+	var st state
+	err := storage.GetData(s.storage, stateKey, &st)
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.SetData(s.storage, stateKey, state{LastSent: now.UTC()})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load digest state: %w", err)
+	}
+
+	if !st.LastSent.Before(s.lastScheduled(now)) {
+		return nil
+	}
+
+	sent, err := s.SendAll(ctx)
+	log.Printf("Weekly digest sent to %d subscribers", sent)
+	return err
+}
+
+// lastScheduled returns the latest scheduled send time at or before now
+func (s *Scheduler) lastScheduled(now time.Time) time.Time {
+	local := now.Local()
+	t := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, 0, 0, 0, time.Local)
+	t = t.AddDate(0, 0, -((int(t.Weekday()) - int(s.Weekday) + 7) % 7))
+	if t.After(local) {
+		t = t.AddDate(0, 0, -7)
+	}
+	return t
+}
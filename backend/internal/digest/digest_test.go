@@ -0,0 +1,81 @@
+package digest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestLastScheduled(t *testing.T) {
+	s := &Scheduler{Weekday: time.Monday, Hour: 8}
+	// 2024-05-13 is a Monday
+	monday := time.Date(2024, 5, 13, 8, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"at the scheduled time", monday, monday},
+		{"later that day", monday.Add(5 * time.Hour), monday},
+		{"earlier that day", monday.Add(-time.Hour), monday.AddDate(0, 0, -7)},
+		{"later in the week", monday.AddDate(0, 0, 4), monday},
+		{"the day before", monday.AddDate(0, 0, -1), monday.AddDate(0, 0, -7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.lastScheduled(tt.now); !got.Equal(tt.want) {
+				t.Errorf("lastScheduled(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	reg := registry.New(store, nil, nil)
+	if _, err := reg.Put(models.ClusterInfo{Name: "asuka", Type: models.ClusterTypeCompute, MasterHost: "asuka00"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	subs := NewSubscriptions(store, reg)
+
+	sub, err := subs.Put(models.DigestSubscription{Recipient: "Ada <ada@example.com>", Clusters: []string{"asuka"}})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if sub.Recipient != "ada@example.com" {
+		t.Errorf("recipient = %q, want the bare address", sub.Recipient)
+	}
+	if _, err := subs.Put(models.DigestSubscription{Recipient: "bob@example.com"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if list, _ := subs.List(); len(list) != 2 || list[1].Recipient != "bob@example.com" || list[1].Clusters == nil {
+		t.Errorf("subscriptions = %+v", list)
+	}
+
+	for _, bad := range []models.DigestSubscription{
+		{Recipient: "not an address"},
+		{Recipient: "carol@example.com", Clusters: []string{"naruko"}},
+	} {
+		if _, err := subs.Put(bad); !errors.Is(err, ErrInvalidSubscription) {
+			t.Errorf("Put(%+v): error = %v, want ErrInvalidSubscription", bad, err)
+		}
+	}
+
+	if err := subs.Delete("ada@example.com"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := subs.Delete("ada@example.com"); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("Delete of a deleted subscription: error = %v", err)
+	}
+	if list, _ := subs.List(); len(list) != 1 || list[0].Recipient != "bob@example.com" {
+		t.Errorf("subscriptions = %+v", list)
+	}
+}
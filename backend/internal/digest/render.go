@@ -0,0 +1,134 @@
+package digest
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// funcs are the helpers shared by the digest templates
+var funcs = map[string]interface{}{
+	"gb":     func(b int64) string { return fmt.Sprintf("%.1f GB", float64(b)/(1<<30)) },
+	"number": func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"date":   func(t time.Time) string { return t.Local().Format("2006-01-02") },
+	"percent": func(v interface{}) string {
+		switch p := v.(type) {
+		case float64:
+			return fmt.Sprintf("%.1f%%", p)
+		case *float64:
+			if p != nil {
+				return fmt.Sprintf("%.1f%%", *p)
+			}
+		}
+		return "-"
+	},
+}
+
+var textTemplate = template.Must(template.New("text").Funcs(funcs).Parse(`Weekly cluster digest {{date .Start}} - {{date .End}}
+
+Busiest clusters
+{{- range .Clusters}}
+  {{printf "%-14s" .Cluster}} PBS {{percent .PBSUsage}}  load {{number .LoadAverage}}  availability {{percent .Availability}}
+{{- else}}
+  No utilization data
+{{- end}}
+
+Top disk users
+{{- range .DiskUsers}}
+  {{.Cluster}} {{.Filesystem}}
+{{- range .Users}}
+    {{printf "%-14s" .User}} {{gb .Bytes}} ({{if ge .DeltaBytes 0}}+{{end}}{{gb .DeltaBytes}} this week)
+{{- end}}
+{{- else}}
+  No disk usage data
+{{- end}}
+
+Nodes down
+{{- range .DownNodes}}
+  {{.Cluster}}: {{.Name}} ({{.PBSState}})
+{{- else}}
+  None
+{{- end}}
+
+Firing alerts
+{{- range .Alerts}}
+  [{{.Severity}}] {{.Summary}}
+{{- else}}
+  None
+{{- end}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #222;">
+<h2>Weekly cluster digest {{date .Start}} - {{date .End}}</h2>
+
+<h3>Busiest clusters</h3>
+{{- if .Clusters}}
+<table cellpadding="4" style="border-collapse: collapse;">
+<tr><th align="left">Cluster</th><th align="right">PBS usage</th><th align="right">Load average</th><th align="right">Availability</th></tr>
+{{- range .Clusters}}
+<tr><td>{{.Cluster}}</td><td align="right">{{percent .PBSUsage}}</td><td align="right">{{number .LoadAverage}}</td><td align="right">{{percent .Availability}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No utilization data</p>
+{{- end}}
+
+<h3>Top disk users</h3>
+{{- range .DiskUsers}}
+<p><b>{{.Cluster}}</b> {{.Filesystem}}</p>
+<table cellpadding="4" style="border-collapse: collapse;">
+<tr><th align="left">User</th><th align="right">Used</th><th align="right">This week</th></tr>
+{{- range .Users}}
+<tr><td>{{.User}}</td><td align="right">{{gb .Bytes}}</td><td align="right">{{if ge .DeltaBytes 0}}+{{end}}{{gb .DeltaBytes}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No disk usage data</p>
+{{- end}}
+
+<h3>Nodes down</h3>
+{{- if .DownNodes}}
+<ul>
+{{- range .DownNodes}}
+<li>{{.Cluster}}: {{.Name}} ({{.PBSState}})</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>None</p>
+{{- end}}
+
+<h3>Firing alerts</h3>
+{{- if .Alerts}}
+<ul>
+{{- range .Alerts}}
+<li>[{{.Severity}}] {{.Summary}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>None</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// Subject returns the mail subject of a digest
+func Subject(d *models.Digest) string {
+	return fmt.Sprintf("Weekly cluster digest %s - %s",
+		d.Start.Local().Format("2006-01-02"), d.End.Local().Format("2006-01-02"))
+}
+
+// RenderText writes a digest as plain text
+func RenderText(w io.Writer, d *models.Digest) error {
+	return textTemplate.Execute(w, d)
+}
+
+// RenderHTML writes a digest as an HTML mail body
+func RenderHTML(w io.Writer, d *models.Digest) error {
+	return htmlTemplate.Execute(w, d)
+}
@@ -0,0 +1,136 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// subscriptionsKey is the storage key holding the digest subscriptions
+const subscriptionsKey = "digest_subscriptions"
+
+var (
+	// ErrSubscriptionNotFound is returned when a recipient has no subscription
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrInvalidSubscription is returned for subscriptions that fail validation
+	ErrInvalidSubscription = errors.New("invalid subscription")
+)
+
+// Subscriptions stores which clusters each recipient's digest covers
+type Subscriptions struct {
+	storage  storage.Storage
+	registry *registry.Registry
+	mu       sync.Mutex
+}
+
+// NewSubscriptions creates a subscription store
+func NewSubscriptions(store storage.Storage, reg *registry.Registry) *Subscriptions {
+	return &Subscriptions{storage: store, registry: reg}
+}
+
+// List returns every subscription ordered by recipient
+func (s *Subscriptions) List() ([]models.DigestSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+// Get returns the subscription of a recipient
+func (s *Subscriptions) Get(recipient string) (*models.DigestSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		if subs[i].Recipient == recipient {
+			return &subs[i], nil
+		}
+	}
+	return nil, ErrSubscriptionNotFound
+}
+
+// Put creates or replaces the subscription of a recipient
+func (s *Subscriptions) Put(sub models.DigestSubscription) (*models.DigestSubscription, error) {
+	addr, err := mail.ParseAddress(sub.Recipient)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	sub.Recipient = addr.Address
+	if sub.Clusters == nil {
+		sub.Clusters = []string{}
+	}
+	for _, name := range sub.Clusters {
+		if _, err := s.registry.Get(name); err != nil {
+			if errors.Is(err, registry.ErrClusterNotFound) {
+				return nil, fmt.Errorf("%w: unknown cluster %q", ErrInvalidSubscription, name)
+			}
+			return nil, err
+		}
+	}
+	sub.UpdatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := false
+	for i := range subs {
+		if subs[i].Recipient == sub.Recipient {
+			subs[i] = sub
+			replaced = true
+		}
+	}
+	if !replaced {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Recipient < subs[j].Recipient })
+
+	if err := storage.SetData(s.storage, subscriptionsKey, subs); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// Delete removes the subscription of a recipient
+func (s *Subscriptions) Delete(recipient string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	for i := range subs {
+		if subs[i].Recipient == recipient {
+			subs = append(subs[:i], subs[i+1:]...)
+			return storage.SetData(s.storage, subscriptionsKey, subs)
+		}
+	}
+	return ErrSubscriptionNotFound
+}
+
+// load reads the subscriptions from storage
+func (s *Subscriptions) load() ([]models.DigestSubscription, error) {
+	subs := []models.DigestSubscription{}
+	err := storage.GetData(s.storage, subscriptionsKey, &subs)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load digest subscriptions: %w", err)
+	}
+	return subs, nil
+}
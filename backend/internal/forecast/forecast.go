@@ -0,0 +1,180 @@
+package forecast
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// minSamples is the number of samples needed to fit a trend
+const minSamples = 3
+
+// z95 is the normal quantile used for the 95% confidence bounds
+const z95 = 1.96
+
+// horizonDays is how far ahead full dates are given; trends reaching
+// capacity later are as good as flat
+const horizonDays = 10 * 365
+
+// Forecaster projects when filesystems fill up by fitting a linear trend to
+// their stored capacity history
+type Forecaster struct {
+	filesystems *collector.FilesystemStore
+}
+
+// New creates a forecaster reading history from the filesystem store
+func New(filesystems *collector.FilesystemStore) *Forecaster {
+	return &Forecaster{filesystems: filesystems}
+}
+
+// Cluster returns a forecast for every mount of a cluster, fitted over the
+// samples collected within window. Mounts closest to full come first.
+func (f *Forecaster) Cluster(cluster string, window time.Duration) ([]models.FilesystemForecast, error) {
+	now := time.Now().UTC()
+	history, err := f.filesystems.History(cluster, now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	type mount struct{ host, path string }
+	series := make(map[mount][]models.FilesystemSample)
+	for _, s := range history {
+		key := mount{s.Host, s.MountPoint}
+		series[key] = append(series[key], s)
+	}
+
+	forecasts := make([]models.FilesystemForecast, 0, len(series))
+	for key, samples := range series {
+		fc := Fit(samples, now)
+		fc.Cluster = cluster
+		fc.Host = key.host
+		fc.MountPoint = key.path
+		forecasts = append(forecasts, fc)
+	}
+
+	sort.Slice(forecasts, func(i, j int) bool {
+		a, b := forecasts[i], forecasts[j]
+		switch {
+		case a.DaysToFull != nil && b.DaysToFull != nil && *a.DaysToFull != *b.DaysToFull:
+			return *a.DaysToFull < *b.DaysToFull
+		case (a.DaysToFull == nil) != (b.DaysToFull == nil):
+			return a.DaysToFull != nil
+		case a.Host != b.Host:
+			return a.Host < b.Host
+		}
+		return a.MountPoint < b.MountPoint
+	})
+	return forecasts, nil
+}
+
+// Fit fits used bytes against time with least squares and projects when the
+// trend reaches the usable capacity of the latest sample. The confidence
+// bounds use the 95% interval of the fitted slope, pivoting on the mean of
+// the samples; the latest bound is left empty when the slope may be zero or
+// negative. Trends reaching the capacity beyond horizonDays get no days to
+// full nor full dates, so they rank with flat trends; a bound beyond the
+// horizon is left empty.
+func Fit(samples []models.FilesystemSample, now time.Time) models.FilesystemForecast {
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+
+	fc := models.FilesystemForecast{Samples: len(samples)}
+	if len(samples) == 0 {
+		fc.Message = "No history available"
+		return fc
+	}
+
+	last := samples[len(samples)-1]
+	fc.UsedBytes = last.UsedBytes
+	fc.UsableBytes = last.UsableBytes
+	if last.UsableBytes > 0 {
+		fc.UsagePercent = float64(last.UsedBytes) / float64(last.UsableBytes) * 100
+	}
+
+	if len(samples) < minSamples {
+		fc.Message = "Not enough history to fit a trend"
+		return fc
+	}
+
+	// Fit in days relative to the first sample to keep the numbers small
+	origin := samples[0].Time
+	n := float64(len(samples))
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += s.Time.Sub(origin).Hours() / 24
+		sumY += float64(s.UsedBytes)
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy, syy float64
+	for _, s := range samples {
+		dx := s.Time.Sub(origin).Hours()/24 - meanX
+		dy := float64(s.UsedBytes) - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		fc.Message = "Samples do not span enough time to fit a trend"
+		return fc
+	}
+
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+	fc.GrowthBytesPerDay = slope
+	if syy > 0 {
+		fc.RSquared = (sxy * sxy) / (sxx * syy)
+	} else {
+		fc.RSquared = 1
+	}
+
+	var sse float64
+	for _, s := range samples {
+		x := s.Time.Sub(origin).Hours() / 24
+		r := float64(s.UsedBytes) - (intercept + slope*x)
+		sse += r * r
+	}
+	slopeErr := 0.0
+	if len(samples) > 2 {
+		slopeErr = math.Sqrt(sse/(n-2)) / math.Sqrt(sxx)
+	}
+
+	if slope <= 0 {
+		fc.Message = "Usage is not growing"
+		return fc
+	}
+
+	nowX := now.Sub(origin).Hours() / 24
+	remaining := float64(last.UsableBytes) - (intercept + slope*nowX)
+	capacity := float64(last.UsableBytes)
+
+	days := math.Max(remaining/slope, 0)
+	if days > horizonDays {
+		fc.Message = "Usage does not reach the capacity within ten years"
+		return fc
+	}
+	fc.DaysToFull = &days
+	fc.FullAt = fullAt(origin, capacity, meanX, meanY, slope, now)
+	fc.FullAtEarliest = fullAt(origin, capacity, meanX, meanY, slope+z95*slopeErr, now)
+	if upper := slope - z95*slopeErr; upper > 0 {
+		fc.FullAtLatest = fullAt(origin, capacity, meanX, meanY, upper, now)
+	}
+	return fc
+}
+
+// fullAt returns when the line through (meanX, meanY) with slope reaches
+// capacity, but never earlier than now. It returns nil beyond horizonDays
+// from now.
+func fullAt(origin time.Time, capacity, meanX, meanY, slope float64, now time.Time) *time.Time {
+	days := meanX + (capacity-meanY)/slope
+	if math.IsNaN(days) || days > now.Sub(origin).Hours()/24+horizonDays {
+		return nil
+	}
+	t := origin.Add(time.Duration(days * 24 * float64(time.Hour)))
+	if t.Before(now) {
+		t = now
+	}
+	return &t
+}
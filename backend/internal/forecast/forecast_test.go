@@ -0,0 +1,115 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+const gb = 1 << 30
+
+// series returns one sample per day ending at now, with used bytes given
+// per sample and a fixed usable capacity
+func series(now time.Time, usable int64, used ...int64) []models.FilesystemSample {
+	samples := make([]models.FilesystemSample, len(used))
+	for i, u := range used {
+		samples[i] = models.FilesystemSample{
+			Host:        "asuka01",
+			MountPoint:  "/data",
+			UsedBytes:   u,
+			UsableBytes: usable,
+			Time:        now.AddDate(0, 0, i-len(used)+1),
+		}
+	}
+	return samples
+}
+
+func TestFit(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		samples []models.FilesystemSample
+		days    float64 // Expected days to full; negative expects none
+		fullAt  time.Time
+		message string
+	}{
+		{name: "no samples", days: -1, message: "No history available"},
+		{name: "too few samples", samples: series(now, 100*gb, 10*gb, 11*gb), days: -1, message: "Not enough history to fit a trend"},
+		{name: "flat", samples: series(now, 100*gb, 10*gb, 10*gb, 10*gb), days: -1, message: "Usage is not growing"},
+		{name: "shrinking", samples: series(now, 100*gb, 30*gb, 20*gb, 10*gb), days: -1, message: "Usage is not growing"},
+		{name: "linear growth", samples: series(now, 100*gb, 10*gb, 20*gb, 30*gb, 40*gb), days: 6, fullAt: now.Add(6 * day)},
+		{name: "already full", samples: series(now, 100*gb, 80*gb, 90*gb, 110*gb), days: 0, fullAt: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := Fit(tt.samples, now)
+			if fc.Message != tt.message {
+				t.Errorf("message = %q, want %q", fc.Message, tt.message)
+			}
+			if tt.days < 0 {
+				if fc.DaysToFull != nil || fc.FullAt != nil {
+					t.Errorf("full in %v days at %v, want no projection", *fc.DaysToFull, fc.FullAt)
+				}
+				return
+			}
+			if fc.DaysToFull == nil || math.Abs(*fc.DaysToFull-tt.days) > 1e-6 {
+				t.Fatalf("days to full = %v, want %v", fc.DaysToFull, tt.days)
+			}
+			if fc.FullAt == nil || fc.FullAt.Sub(tt.fullAt).Abs() > time.Second {
+				t.Errorf("full at %v, want %v", fc.FullAt, tt.fullAt)
+			}
+		})
+	}
+}
+
+func TestFitExactTrend(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	fc := Fit(series(now, 100*gb, 10*gb, 20*gb, 30*gb, 40*gb), now)
+
+	if fc.GrowthBytesPerDay != 10*gb || fc.RSquared != 1 || fc.UsagePercent != 40 || fc.Samples != 4 {
+		t.Errorf("forecast = %+v", fc)
+	}
+	// Without scatter the bounds collapse onto the projection
+	if fc.FullAtEarliest == nil || fc.FullAtLatest == nil || !fc.FullAtEarliest.Equal(*fc.FullAt) || !fc.FullAtLatest.Equal(*fc.FullAt) {
+		t.Errorf("bounds = %v, %v, want %v", fc.FullAtEarliest, fc.FullAtLatest, fc.FullAt)
+	}
+}
+
+func TestFitConfidenceBand(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	fc := Fit(series(now, 1000*gb, 100*gb, 112*gb, 118*gb, 133*gb, 139*gb, 152*gb, 158*gb, 171*gb), now)
+
+	if fc.FullAt == nil || fc.FullAtEarliest == nil || fc.FullAtLatest == nil {
+		t.Fatalf("forecast = %+v, want a bounded projection", fc)
+	}
+	if !fc.FullAtEarliest.Before(*fc.FullAt) || !fc.FullAt.Before(*fc.FullAtLatest) {
+		t.Errorf("band %v .. %v does not enclose %v", fc.FullAtEarliest, fc.FullAtLatest, fc.FullAt)
+	}
+	// The band pivots on the samples, so it is about as wide either side
+	early, late := fc.FullAt.Sub(*fc.FullAtEarliest), fc.FullAtLatest.Sub(*fc.FullAt)
+	if late < early || late > 2*early {
+		t.Errorf("band is %v early and %v late", early, late)
+	}
+
+	// Too much scatter leaves the latest bound open
+	fc = Fit(series(now, 1000*gb, 100*gb, 200*gb, 90*gb, 210*gb, 120*gb), now)
+	if fc.FullAt == nil || fc.FullAtLatest != nil {
+		t.Errorf("noisy forecast = %+v, want no latest bound", fc)
+	}
+}
+
+func TestFitHorizon(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	// A byte a day on a large mount takes far longer than the horizon
+	fc := Fit(series(now, 1000*gb, 10*gb, 10*gb+1, 10*gb+2), now)
+	if fc.DaysToFull != nil || fc.FullAt != nil || fc.FullAtEarliest != nil || fc.FullAtLatest != nil {
+		t.Errorf("forecast = %+v, want no projection beyond the horizon", fc)
+	}
+	if fc.GrowthBytesPerDay <= 0 || fc.Message == "" {
+		t.Errorf("forecast = %+v, want the growth and a message", fc)
+	}
+}
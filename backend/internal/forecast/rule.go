@@ -0,0 +1,78 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+)
+
+// RuleType identifies disk fill alerts
+const RuleType = "disk_fill"
+
+// DiskFillRule fires when a mount is projected to fill within the warning or
+// critical horizon. It replaces the fixed 90-99% thresholds of disk_total.sh.
+type DiskFillRule struct {
+	forecaster   *Forecaster
+	registry     *registry.Registry
+	Window       time.Duration // History used for the fit
+	WarningDays  float64
+	CriticalDays float64
+}
+
+// NewDiskFillRule creates a disk fill alert rule
+func NewDiskFillRule(forecaster *Forecaster, reg *registry.Registry, window time.Duration, warningDays, criticalDays float64) *DiskFillRule {
+	return &DiskFillRule{
+		forecaster:   forecaster,
+		registry:     reg,
+		Window:       window,
+		WarningDays:  warningDays,
+		CriticalDays: criticalDays,
+	}
+}
+
+// Name returns the rule name
+func (r *DiskFillRule) Name() string {
+	return RuleType
+}
+
+// Evaluate returns an alert for every mount expected to fill within the
+// warning horizon
+func (r *DiskFillRule) Evaluate(ctx context.Context) ([]models.Alert, error) {
+	clusters, err := r.registry.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []models.Alert
+	for _, cluster := range clusters {
+		forecasts, err := r.forecaster.Cluster(cluster, r.Window)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fc := range forecasts {
+			if fc.DaysToFull == nil || *fc.DaysToFull > r.WarningDays {
+				continue
+			}
+
+			severity := models.SeverityWarning
+			if *fc.DaysToFull <= r.CriticalDays {
+				severity = models.SeverityCritical
+			}
+			alerts = append(alerts, models.Alert{
+				Type:     RuleType,
+				Severity: severity,
+				Cluster:  cluster,
+				Node:     fc.Host,
+				Target:   fc.Host + ":" + fc.MountPoint,
+				Summary: fmt.Sprintf("%s on %s fills in ~%.1f days (%.1f%% used)",
+					fc.MountPoint, fc.Host, *fc.DaysToFull, fc.UsagePercent),
+				Value: *fc.DaysToFull,
+			})
+		}
+	}
+	return alerts, nil
+}
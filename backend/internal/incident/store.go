@@ -0,0 +1,486 @@
+package incident
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// storageKey is the storage key holding the incidents
+const storageKey = "incidents"
+
+var (
+	// ErrIncidentNotFound is returned for unknown incident IDs
+	ErrIncidentNotFound = errors.New("incident not found")
+	// ErrInvalidIncident is returned for incidents and updates that fail validation
+	ErrInvalidIncident = errors.New("invalid incident")
+	// ErrAlertNotFiring is returned when opening an incident from an alert
+	// that is not firing
+	ErrAlertNotFiring = errors.New("alert is not firing")
+)
+
+// state is the persisted incident list
+type state struct {
+	NextID    int               `json:"next_id"`
+	Incidents []models.Incident `json:"incidents"`
+	Firing    []string          `json:"firing"`          // Alerts firing at the last sync
+	Resolved  []string          `json:"resolved_firing"` // Firing alerts whose incident was resolved; not reopened until they clear
+}
+
+// Filter selects incidents; empty fields match every incident
+type Filter struct {
+	Status  string
+	Cluster string
+	Open    bool // Only incidents that are not resolved
+}
+
+// Store keeps incidents with their timelines. Incidents are opened through
+// the API, from a firing alert, or automatically by Sync for alerts of at
+// least AutoOpenSeverity.
+type Store struct {
+	storage          storage.Storage
+	alerts           *alert.Engine
+	AutoOpenSeverity string // Lowest severity opened automatically; empty disables
+	mu               sync.Mutex
+}
+
+// NewStore creates an incident store
+func NewStore(store storage.Storage, alerts *alert.Engine) *Store {
+	return &Store{storage: store, alerts: alerts, AutoOpenSeverity: models.SeverityCritical}
+}
+
+// List returns the incidents matching the filter, newest first
+func (s *Store) List(f Filter) ([]models.Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.Incident{}
+	for i := len(st.Incidents) - 1; i >= 0; i-- {
+		inc := st.Incidents[i]
+		if f.Status != "" && inc.Status != f.Status {
+			continue
+		}
+		if f.Open && inc.Status == models.IncidentStatusResolved {
+			continue
+		}
+		if f.Cluster != "" && !contains(inc.Clusters, f.Cluster) {
+			continue
+		}
+		result = append(result, inc)
+	}
+	return result, nil
+}
+
+// Get returns one incident
+func (s *Store) Get(id int) (*models.Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	i := index(st, id)
+	if i < 0 {
+		return nil, ErrIncidentNotFound
+	}
+	return &st.Incidents[i], nil
+}
+
+// Create opens an incident. The message, if any, starts the timeline.
+func (s *Store) Create(inc models.Incident, message, author string) (*models.Incident, error) {
+	inc.Title = strings.TrimSpace(inc.Title)
+	if inc.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidIncident)
+	}
+	if inc.Status == "" {
+		inc.Status = models.IncidentStatusInvestigating
+	}
+	if inc.Severity == "" {
+		inc.Severity = models.SeverityWarning
+	}
+	if inc.Source == "" {
+		inc.Source = models.IncidentSourceManual
+	}
+	if err := validate(inc); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	inc.Clusters = normalize(inc.Clusters)
+	inc.Nodes = normalize(inc.Nodes)
+	inc.Alerts = normalize(inc.Alerts)
+	inc.CreatedAt = now
+	inc.UpdatedAt = now
+	inc.ResolvedAt = nil
+	if message == "" {
+		message = "Incident opened"
+	}
+	inc.Timeline = []models.IncidentUpdate{{At: now, Status: inc.Status, Message: message, Author: author}}
+	if inc.Status == models.IncidentStatusResolved {
+		inc.ResolvedAt = &now
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	st.NextID++
+	inc.ID = st.NextID
+	st.Incidents = append(st.Incidents, inc)
+
+	if err := storage.SetData(s.storage, storageKey, st); err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+// CreateFromAlert opens an incident for a firing alert, taking the title,
+// severity and affected cluster and node from the alert
+func (s *Store) CreateFromAlert(alertID, title, message, author string) (*models.Incident, error) {
+	a, err := s.alerts.Get(alertID)
+	if err != nil {
+		return nil, err
+	}
+	if a.State != models.AlertStateFiring {
+		return nil, ErrAlertNotFiring
+	}
+	return s.Create(fromAlert(*a, title, models.IncidentSourceManual), message, author)
+}
+
+// Edit replaces the title, severity, affected clusters and nodes, linked
+// alerts and resolution of an incident. Status changes go through AddUpdate.
+func (s *Store) Edit(id int, edit models.Incident) (*models.Incident, error) {
+	edit.Title = strings.TrimSpace(edit.Title)
+	if edit.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidIncident)
+	}
+
+	return s.modify(id, func(inc *models.Incident) error {
+		edit.Status = inc.Status
+		if edit.Severity == "" {
+			edit.Severity = inc.Severity
+		}
+		if err := validate(edit); err != nil {
+			return err
+		}
+		inc.Title = edit.Title
+		inc.Severity = edit.Severity
+		inc.Clusters = normalize(edit.Clusters)
+		inc.Nodes = normalize(edit.Nodes)
+		inc.Alerts = normalize(edit.Alerts)
+		inc.Resolution = edit.Resolution
+		return nil
+	})
+}
+
+// AddUpdate appends an entry to the timeline of an incident. An update with
+// a status moves the incident to it; resolving sets the resolution notes
+// when given.
+func (s *Store) AddUpdate(id int, update models.IncidentUpdate, resolution string) (*models.Incident, error) {
+	update.Message = strings.TrimSpace(update.Message)
+	if update.Message == "" {
+		return nil, fmt.Errorf("%w: message is required", ErrInvalidIncident)
+	}
+
+	return s.modify(id, func(inc *models.Incident) error {
+		if update.Status == "" {
+			update.Status = inc.Status
+		}
+		if !validStatus(update.Status) {
+			return fmt.Errorf("%w: invalid status %q", ErrInvalidIncident, update.Status)
+		}
+		update.At = time.Now().UTC()
+
+		switch {
+		case update.Status == models.IncidentStatusResolved && inc.Status != models.IncidentStatusResolved:
+			at := update.At
+			inc.ResolvedAt = &at
+		case update.Status != models.IncidentStatusResolved:
+			inc.ResolvedAt = nil
+		}
+		if update.Status == models.IncidentStatusResolved && resolution != "" {
+			inc.Resolution = resolution
+		}
+		inc.Status = update.Status
+		inc.Timeline = append(inc.Timeline, update)
+		return nil
+	})
+}
+
+// Delete removes an incident
+func (s *Store) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	i := index(st, id)
+	if i < 0 {
+		return ErrIncidentNotFound
+	}
+	st.Incidents = append(st.Incidents[:i], st.Incidents[i+1:]...)
+	return storage.SetData(s.storage, storageKey, st)
+}
+
+// Sync opens an incident for every firing alert of at least
+// AutoOpenSeverity that is neither silenced nor linked to an open incident,
+// and notes in the timeline when a linked alert resolves. An alert whose
+// incident was resolved while it kept firing opens no new incident until it
+// clears.
+func (s *Store) Sync(ctx context.Context) error {
+	firing, err := s.alerts.Firing()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	current := make(map[string]models.Alert, len(firing))
+	for _, a := range firing {
+		current[a.ID] = a
+	}
+
+	wasFiring := make(map[string]bool, len(st.Firing))
+	for _, id := range st.Firing {
+		wasFiring[id] = true
+	}
+
+	// Alerts that fired at the last sync and still fire have not cleared
+	linked := make(map[string]bool)
+	for _, id := range st.Resolved {
+		if _, ok := current[id]; ok {
+			linked[id] = true
+		}
+	}
+	for _, inc := range st.Incidents {
+		if inc.Status != models.IncidentStatusResolved {
+			continue
+		}
+		for _, id := range inc.Alerts {
+			if _, ok := current[id]; ok && wasFiring[id] {
+				linked[id] = true
+			}
+		}
+	}
+	resolved := make([]string, 0, len(linked))
+	for id := range linked {
+		resolved = append(resolved, id)
+	}
+	sort.Strings(resolved)
+
+	changed := false
+	for i := range st.Incidents {
+		inc := &st.Incidents[i]
+		if inc.Status == models.IncidentStatusResolved {
+			continue
+		}
+		for _, id := range inc.Alerts {
+			linked[id] = true
+			if _, ok := current[id]; ok || !wasFiring[id] {
+				continue
+			}
+			message := "Alert resolved: " + id
+			if a, err := s.alerts.Get(id); err == nil {
+				message = "Alert resolved: " + a.Summary
+			}
+			inc.Timeline = append(inc.Timeline, models.IncidentUpdate{At: now, Status: inc.Status, Message: message})
+			inc.UpdatedAt = now
+			changed = true
+		}
+	}
+
+	if rank(s.AutoOpenSeverity) > 0 {
+		for _, a := range firing {
+			if linked[a.ID] || a.Silenced || rank(a.Severity) < rank(s.AutoOpenSeverity) {
+				continue
+			}
+			inc := fromAlert(a, "", models.IncidentSourceAlert)
+			inc.Status = models.IncidentStatusInvestigating
+			inc.CreatedAt = now
+			inc.UpdatedAt = now
+			inc.Timeline = []models.IncidentUpdate{{At: now, Status: inc.Status, Message: "Opened from alert: " + a.Summary}}
+			st.NextID++
+			inc.ID = st.NextID
+			st.Incidents = append(st.Incidents, inc)
+			changed = true
+			log.Printf("Incident %d opened from alert %s %s", inc.ID, a.Rule, a.Target)
+		}
+	}
+
+	ids := make([]string, 0, len(firing))
+	for _, a := range firing {
+		ids = append(ids, a.ID)
+	}
+	sort.Strings(ids)
+	if !changed && strings.Join(ids, ",") == strings.Join(st.Firing, ",") && strings.Join(resolved, ",") == strings.Join(st.Resolved, ",") {
+		return nil
+	}
+	st.Firing = ids
+	st.Resolved = resolved
+	return storage.SetData(s.storage, storageKey, st)
+}
+
+// SetAutoOpenSeverity changes AutoOpenSeverity while incidents are synced
+func (s *Store) SetAutoOpenSeverity(severity string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.AutoOpenSeverity = severity
+}
+
+// Run syncs incidents with the alerts at the given interval until ctx is done
+func (s *Store) Run(ctx context.Context, interval *schedule.Interval) {
+	collector.RunEvery(ctx, interval, "Incident", s.Sync)
+}
+
+// modify applies fn to an incident and saves it
+func (s *Store) modify(id int, fn func(inc *models.Incident) error) (*models.Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	i := index(st, id)
+	if i < 0 {
+		return nil, ErrIncidentNotFound
+	}
+
+	inc := st.Incidents[i]
+	inc.Timeline = append([]models.IncidentUpdate(nil), inc.Timeline...)
+	if err := fn(&inc); err != nil {
+		return nil, err
+	}
+	inc.UpdatedAt = time.Now().UTC()
+	st.Incidents[i] = inc
+
+	if err := storage.SetData(s.storage, storageKey, st); err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+// load reads the incidents from storage
+func (s *Store) load() (*state, error) {
+	st := &state{}
+	err := storage.GetData(s.storage, storageKey, st)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load incidents: %w", err)
+	}
+	if st.Incidents == nil {
+		st.Incidents = []models.Incident{}
+	}
+	return st, nil
+}
+
+// fromAlert builds an incident affecting the cluster and node of an alert
+func fromAlert(a models.Alert, title, source string) models.Incident {
+	if title == "" {
+		title = a.Summary
+	}
+	inc := models.Incident{
+		Title:    title,
+		Severity: a.Severity,
+		Source:   source,
+		Clusters: normalize([]string{a.Cluster}),
+		Nodes:    normalize([]string{a.Node}),
+		Alerts:   []string{a.ID},
+	}
+	return inc
+}
+
+// validate checks the status and severity of an incident
+func validate(inc models.Incident) error {
+	if !validStatus(inc.Status) {
+		return fmt.Errorf("%w: invalid status %q", ErrInvalidIncident, inc.Status)
+	}
+	if inc.Severity != models.SeverityWarning && inc.Severity != models.SeverityCritical {
+		return fmt.Errorf("%w: invalid severity %q", ErrInvalidIncident, inc.Severity)
+	}
+	return nil
+}
+
+// validStatus reports whether status is an incident status
+func validStatus(status string) bool {
+	switch status {
+	case models.IncidentStatusInvestigating, models.IncidentStatusIdentified, models.IncidentStatusResolved:
+		return true
+	}
+	return false
+}
+
+// rank orders severities; unknown severities rank 0
+func rank(severity string) int {
+	switch severity {
+	case models.SeverityWarning:
+		return 1
+	case models.SeverityCritical:
+		return 2
+	}
+	return 0
+}
+
+// index returns the position of an incident, or -1
+func index(st *state, id int) int {
+	for i := range st.Incidents {
+		if st.Incidents[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalize trims, sorts and deduplicates names, dropping empty ones
+func normalize(names []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n != "" && !seen[n] {
+			seen[n] = true
+			result = append(result, n)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package incident
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/alert"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// fakeRule reports the alerts it holds
+type fakeRule struct {
+	alerts []models.Alert
+}
+
+func (r *fakeRule) Name() string { return "fake" }
+
+func (r *fakeRule) Evaluate(ctx context.Context) ([]models.Alert, error) {
+	return r.alerts, nil
+}
+
+// fixture is an incident store over an alert engine evaluating rule
+type fixture struct {
+	store  *Store
+	engine *alert.Engine
+	rule   *fakeRule
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	rule := &fakeRule{}
+	engine := alert.NewEngine(store, []alert.Rule{rule})
+	return &fixture{store: NewStore(store, engine), engine: engine, rule: rule}
+}
+
+// sync reports alerts, evaluates them and syncs the incidents
+func (f *fixture) sync(t *testing.T, alerts ...models.Alert) []models.Incident {
+	t.Helper()
+	f.rule.alerts = alerts
+	if err := f.engine.Evaluate(context.Background()); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if err := f.store.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	incidents, err := f.store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return incidents
+}
+
+// firingID returns the ID the engine gave a firing alert
+func (f *fixture) firingID(t *testing.T, target string) string {
+	t.Helper()
+	firing, err := f.engine.Firing()
+	if err != nil {
+		t.Fatalf("Firing: %v", err)
+	}
+	for _, a := range firing {
+		if a.Target == target {
+			return a.ID
+		}
+	}
+	t.Fatalf("alert %s is not firing", target)
+	return ""
+}
+
+var (
+	nodeDown = models.Alert{Type: "node_down", Severity: models.SeverityCritical, Cluster: "asuka", Node: "asuka01", Target: "asuka01", Summary: "asuka01 is down"}
+	diskFull = models.Alert{Type: "disk_fill", Severity: models.SeverityWarning, Cluster: "asuka", Target: "asuka00:/home", Summary: "/home fills in 5 days"}
+)
+
+func TestSyncOpensIncidents(t *testing.T) {
+	f := newFixture(t)
+
+	// Only critical alerts open incidents by default
+	incidents := f.sync(t, nodeDown, diskFull)
+	if len(incidents) != 1 {
+		t.Fatalf("incidents = %+v, want one for the critical alert", incidents)
+	}
+	inc := incidents[0]
+	id := f.firingID(t, "asuka01")
+	if inc.Source != models.IncidentSourceAlert || inc.Status != models.IncidentStatusInvestigating || inc.Title != nodeDown.Summary ||
+		strings.Join(inc.Alerts, ",") != id || strings.Join(inc.Nodes, ",") != "asuka01" || strings.Join(inc.Clusters, ",") != "asuka" {
+		t.Errorf("incident = %+v", inc)
+	}
+	if len(inc.Timeline) != 1 || inc.Timeline[0].Message != "Opened from alert: asuka01 is down" {
+		t.Errorf("timeline = %+v", inc.Timeline)
+	}
+
+	// A linked alert that keeps firing opens no more incidents
+	if incidents = f.sync(t, nodeDown, diskFull); len(incidents) != 1 || len(incidents[0].Timeline) != 1 {
+		t.Errorf("incidents after another sync = %+v", incidents)
+	}
+
+	// Resolving the alert is noted in the timeline once
+	f.sync(t, diskFull)
+	incidents = f.sync(t, diskFull)
+	if len(incidents) != 1 {
+		t.Fatalf("incidents = %+v", incidents)
+	}
+	timeline := incidents[0].Timeline
+	if len(timeline) != 2 || timeline[1].Message != "Alert resolved: asuka01 is down" || timeline[1].Status != models.IncidentStatusInvestigating {
+		t.Errorf("timeline = %+v", timeline)
+	}
+}
+
+func TestSyncResolvedWhileFiring(t *testing.T) {
+	f := newFixture(t)
+
+	incidents := f.sync(t, nodeDown)
+	if len(incidents) != 1 {
+		t.Fatalf("incidents = %+v", incidents)
+	}
+	update := models.IncidentUpdate{Status: models.IncidentStatusResolved, Message: "Node replaced, alert is stale"}
+	if _, err := f.store.AddUpdate(incidents[0].ID, update, ""); err != nil {
+		t.Fatalf("AddUpdate: %v", err)
+	}
+
+	// The alert still fires, but its incident was closed on purpose
+	for i := 0; i < 2; i++ {
+		if incidents = f.sync(t, nodeDown); len(incidents) != 1 {
+			t.Fatalf("sync %d reopened the alert: %+v", i, incidents)
+		}
+	}
+
+	// Once the alert clears, firing again opens a new incident
+	f.sync(t)
+	incidents = f.sync(t, nodeDown)
+	if len(incidents) != 2 || incidents[0].Status != models.IncidentStatusInvestigating {
+		t.Errorf("incidents after the alert fired again = %+v", incidents)
+	}
+}
+
+func TestSyncAutoOpenSeverity(t *testing.T) {
+	f := newFixture(t)
+
+	f.store.SetAutoOpenSeverity(models.SeverityWarning)
+	silenced := nodeDown
+	silenced.Silenced = true
+	if incidents := f.sync(t, silenced, diskFull); len(incidents) != 1 || incidents[0].Title != diskFull.Summary {
+		t.Errorf("incidents = %+v, want one for the warning and none for the silenced alert", incidents)
+	}
+
+	f2 := newFixture(t)
+	f2.store.SetAutoOpenSeverity("")
+	if incidents := f2.sync(t, nodeDown); len(incidents) != 0 {
+		t.Errorf("incidents with auto-open disabled = %+v", incidents)
+	}
+}
+
+func TestCreateFromAlert(t *testing.T) {
+	f := newFixture(t)
+	f.store.SetAutoOpenSeverity("")
+	f.sync(t, diskFull)
+	id := f.firingID(t, "asuka00:/home")
+
+	inc, err := f.store.CreateFromAlert(id, "", "Looking into it", "ada")
+	if err != nil {
+		t.Fatalf("CreateFromAlert: %v", err)
+	}
+	if inc.Title != diskFull.Summary || inc.Severity != models.SeverityWarning || inc.Source != models.IncidentSourceManual || inc.Timeline[0].Author != "ada" {
+		t.Errorf("incident = %+v", inc)
+	}
+	// Syncing does not open another incident for the linked alert
+	f.store.SetAutoOpenSeverity(models.SeverityWarning)
+	if incidents := f.sync(t, diskFull); len(incidents) != 1 {
+		t.Errorf("incidents = %+v", incidents)
+	}
+
+	f.sync(t)
+	if _, err := f.store.CreateFromAlert(id, "", "", "ada"); !errors.Is(err, ErrAlertNotFiring) {
+		t.Errorf("CreateFromAlert of a resolved alert: error = %v", err)
+	}
+}
+
+func TestAddUpdate(t *testing.T) {
+	f := newFixture(t)
+	inc, err := f.store.Create(models.Incident{Title: " Scratch slow ", Clusters: []string{"asuka", " asuka"}}, "", "ada")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if inc.ID != 1 || inc.Title != "Scratch slow" || inc.Status != models.IncidentStatusInvestigating || strings.Join(inc.Clusters, ",") != "asuka" {
+		t.Errorf("incident = %+v", inc)
+	}
+
+	inc, err = f.store.AddUpdate(inc.ID, models.IncidentUpdate{Status: models.IncidentStatusResolved, Message: "Fixed"}, "Replaced a disk")
+	if err != nil {
+		t.Fatalf("AddUpdate: %v", err)
+	}
+	if inc.ResolvedAt == nil || inc.Resolution != "Replaced a disk" || len(inc.Timeline) != 2 {
+		t.Errorf("resolved incident = %+v", inc)
+	}
+	// Reopening clears the resolution time
+	if inc, err = f.store.AddUpdate(inc.ID, models.IncidentUpdate{Status: models.IncidentStatusIdentified, Message: "Again"}, ""); err != nil || inc.ResolvedAt != nil {
+		t.Errorf("reopened incident = %+v, error %v", inc, err)
+	}
+
+	for _, update := range []models.IncidentUpdate{{Message: " "}, {Status: "closed", Message: "Done"}} {
+		if _, err := f.store.AddUpdate(inc.ID, update, ""); !errors.Is(err, ErrInvalidIncident) {
+			t.Errorf("AddUpdate(%+v): error = %v", update, err)
+		}
+	}
+	if _, err := f.store.AddUpdate(99, models.IncidentUpdate{Message: "x"}, ""); !errors.Is(err, ErrIncidentNotFound) {
+		t.Errorf("AddUpdate of an unknown incident: error = %v", err)
+	}
+
+	if open, _ := f.store.List(Filter{Open: true, Cluster: "asuka"}); len(open) != 1 {
+		t.Errorf("open incidents = %+v", open)
+	}
+	if others, _ := f.store.List(Filter{Cluster: "naruko"}); len(others) != 0 {
+		t.Errorf("incidents of naruko = %+v", others)
+	}
+}
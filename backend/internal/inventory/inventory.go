@@ -0,0 +1,144 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// storageKey is the storage key holding the node inventory
+const storageKey = "node_inventory"
+
+// Source lists the nodes currently known to the scheduler
+type Source interface {
+	Nodes(ctx context.Context) ([]models.Node, error)
+}
+
+// Inventory keeps the list of compute nodes and their state in storage
+type Inventory struct {
+	storage storage.Storage
+	source  Source
+	// OnRefresh, when set, is called with the nodes reported by the source
+	// before they are saved, and may change their state
+	OnRefresh func(nodes []models.Node, at time.Time) error
+	mu        sync.Mutex
+}
+
+// New creates a node inventory backed by the given storage
+func New(store storage.Storage, source Source) *Inventory {
+	return &Inventory{storage: store, source: source}
+}
+
+// List returns all known nodes sorted by cluster and name
+func (i *Inventory) List() ([]models.Node, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.load()
+}
+
+// ByCluster returns the nodes of one cluster
+func (i *Inventory) ByCluster(cluster string) ([]models.Node, error) {
+	nodes, err := i.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.Node{}
+	for _, n := range nodes {
+		if n.Cluster == cluster {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+// Refresh replaces the inventory with the nodes reported by the source.
+// LastSeen is carried over for nodes that are not online, and state changes
+// are recorded as transitions.
+func (i *Inventory) Refresh(ctx context.Context) ([]models.Node, error) {
+	current, err := i.source.Nodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	previous, err := i.load()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]models.Node, len(previous))
+	for _, n := range previous {
+		known[n.Name] = n
+	}
+
+	now := time.Now().UTC()
+	for idx := range current {
+		n := &current[idx]
+		n.UpdatedAt = now
+		if n.State == models.NodeStateOnline {
+			n.LastSeen = now
+		} else if prev, ok := known[n.Name]; ok {
+			n.LastSeen = prev.LastSeen
+		}
+	}
+	if i.OnRefresh != nil {
+		if err := i.OnRefresh(current, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.recordTransitions(current, now); err != nil {
+		return nil, err
+	}
+	if err := i.save(current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// Run refreshes the inventory at the given interval until ctx is done
+func (i *Inventory) Run(ctx context.Context, interval *schedule.Interval) {
+	for {
+		start := time.Now()
+		if _, err := i.Refresh(ctx); err != nil {
+			log.Printf("Node inventory refresh failed: %v", err)
+		}
+
+		if !interval.Wait(ctx, start) {
+			return
+		}
+	}
+}
+
+// load reads the inventory from storage
+func (i *Inventory) load() ([]models.Node, error) {
+	nodes := []models.Node{}
+	err := storage.GetData(i.storage, storageKey, &nodes)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load node inventory: %w", err)
+	}
+	return nodes, nil
+}
+
+// save writes the inventory to storage
+func (i *Inventory) save(nodes []models.Node) error {
+	sort.Slice(nodes, func(a, b int) bool {
+		if nodes[a].Cluster != nodes[b].Cluster {
+			return nodes[a].Cluster < nodes[b].Cluster
+		}
+		return nodes[a].Name < nodes[b].Name
+	})
+
+	return storage.SetData(i.storage, storageKey, nodes)
+}
@@ -0,0 +1,103 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// PBSSource lists nodes from `pbsnodes -a`. The node's partition names its
+// cluster, as in disk_node.sh.
+type PBSSource struct {
+	PbsnodesPath string
+}
+
+// NewPBSSource creates a node source using the given pbsnodes binary
+func NewPBSSource(pbsnodesPath string) *PBSSource {
+	return &PBSSource{PbsnodesPath: pbsnodesPath}
+}
+
+// Nodes runs `pbsnodes -a` and returns the nodes assigned to a partition
+func (s *PBSSource) Nodes(ctx context.Context) ([]models.Node, error) {
+	out, err := exec.CommandContext(ctx, s.PbsnodesPath, "-a").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s -a: %w", s.PbsnodesPath, err)
+	}
+
+	return ParsePbsnodes(out), nil
+}
+
+// ParsePbsnodes parses `pbsnodes -a` output. Each node starts with its name on
+// an unindented line followed by indented "key = value" attributes.
+func ParsePbsnodes(out []byte) []models.Node {
+	var nodes []models.Node
+	var current *models.Node
+
+	flush := func() {
+		if current != nil && current.Cluster != "" {
+			if current.Host == "" {
+				current.Host = current.Name
+			}
+			if current.State == "" {
+				current.State = models.NodeStateOffline
+			}
+			nodes = append(nodes, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			flush()
+			current = &models.Node{Name: strings.TrimSpace(line)}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Mom":
+			current.Host = value
+		case "partition":
+			current.Cluster = value
+		case "state":
+			current.PBSState = value
+			current.State = nodeState(value)
+		case "resources_available.ncpus":
+			current.NCPUs, _ = strconv.Atoi(value)
+		}
+	}
+	flush()
+
+	return nodes
+}
+
+// nodeState maps a PBS node state list such as "job-busy" or "down,offline"
+// to an inventory state
+func nodeState(pbsState string) string {
+	for _, s := range strings.Split(pbsState, ",") {
+		switch strings.TrimSpace(s) {
+		case "down", "offline", "unknown", "stale", "state-unknown":
+			return models.NodeStateOffline
+		}
+	}
+	return models.NodeStateOnline
+}
@@ -0,0 +1,61 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+func TestParsePbsnodes(t *testing.T) {
+	out := `asuka01
+     Mom = asuka01.cluster.local
+     ntype = PBS
+     state = job-busy
+     partition = asuka
+     resources_available.ncpus = 64
+
+asuka02
+     state = down,offline
+     partition = asuka
+     resources_available.ncpus = 64
+
+login01
+     Mom = login01
+     state = free
+
+naruko01
+	partition = naruko
+	comment = no state reported
+`
+	want := []models.Node{
+		{Name: "asuka01", Host: "asuka01.cluster.local", Cluster: "asuka", PBSState: "job-busy", State: models.NodeStateOnline, NCPUs: 64},
+		{Name: "asuka02", Host: "asuka02", Cluster: "asuka", PBSState: "down,offline", State: models.NodeStateOffline, NCPUs: 64},
+		{Name: "naruko01", Host: "naruko01", Cluster: "naruko", State: models.NodeStateOffline},
+	}
+	if got := ParsePbsnodes([]byte(out)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePbsnodes =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestNodeState(t *testing.T) {
+	tests := []struct {
+		pbs  string
+		want string
+	}{
+		{"free", models.NodeStateOnline},
+		{"job-busy", models.NodeStateOnline},
+		{"job-exclusive,busy", models.NodeStateOnline},
+		{"down", models.NodeStateOffline},
+		{"free, offline", models.NodeStateOffline},
+		{"state-unknown,down", models.NodeStateOffline},
+		{"stale", models.NodeStateOffline},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pbs, func(t *testing.T) {
+			if got := nodeState(tt.pbs); got != tt.want {
+				t.Errorf("nodeState(%q) = %q, want %q", tt.pbs, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// RuleType identifies node outage alerts
+const RuleType = "node_down"
+
+// NodeDownRule fires for every node the inventory reports as offline. Nodes
+// in maintenance do not fire.
+type NodeDownRule struct {
+	inventory     *Inventory
+	CriticalAfter time.Duration // Outages longer than this are critical
+}
+
+// NewNodeDownRule creates a node outage alert rule
+func NewNodeDownRule(inv *Inventory, criticalAfter time.Duration) *NodeDownRule {
+	return &NodeDownRule{inventory: inv, CriticalAfter: criticalAfter}
+}
+
+// Name returns the rule name
+func (r *NodeDownRule) Name() string {
+	return RuleType
+}
+
+// Evaluate returns an alert for every offline node
+func (r *NodeDownRule) Evaluate(ctx context.Context) ([]models.Alert, error) {
+	nodes, err := r.inventory.List()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var alerts []models.Alert
+	for _, n := range nodes {
+		if n.State != models.NodeStateOffline {
+			continue
+		}
+
+		severity := models.SeverityWarning
+		summary := fmt.Sprintf("Node %s of %s is %s", n.Name, n.Cluster, n.PBSState)
+		var down time.Duration
+		if !n.LastSeen.IsZero() {
+			down = now.Sub(n.LastSeen)
+			summary += fmt.Sprintf(", last seen %s", n.LastSeen.Local().Format("2006-01-02 15:04"))
+		}
+		if n.LastSeen.IsZero() || down >= r.CriticalAfter {
+			severity = models.SeverityCritical
+		}
+
+		alerts = append(alerts, models.Alert{
+			Type:     RuleType,
+			Severity: severity,
+			Cluster:  n.Cluster,
+			Node:     n.Name,
+			Target:   n.Name,
+			Summary:  summary,
+			Value:    down.Seconds(),
+		})
+	}
+	return alerts, nil
+}
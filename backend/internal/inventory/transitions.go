@@ -0,0 +1,125 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// statesKey holds the state of each node as of its last recorded transition
+const statesKey = "node_transition_states"
+
+// recordTransitions stores the state changes since the last refresh. The
+// states are tracked apart from the inventory so that nodes already in the
+// inventory get a first transition as well.
+func (i *Inventory) recordTransitions(current []models.Node, now time.Time) error {
+	known := make(map[string]string)
+	if err := storage.GetData(i.storage, statesKey, &known); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to load node states: %w", err)
+	}
+
+	var changes []models.NodeTransition
+	for _, n := range current {
+		from, ok := known[n.Name]
+		if ok && from == n.State {
+			continue
+		}
+		changes = append(changes, models.NodeTransition{
+			Node:     n.Name,
+			Cluster:  n.Cluster,
+			From:     from,
+			To:       n.State,
+			PBSState: n.PBSState,
+			At:       now,
+		})
+		known[n.Name] = n.State
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	period := now.Format("2006-01")
+	stored, err := i.loadTransitions(period)
+	if err != nil {
+		return err
+	}
+	if err := storage.SetData(i.storage, transitionsKey(period), append(stored, changes...)); err != nil {
+		return err
+	}
+	return storage.SetData(i.storage, statesKey, known)
+}
+
+// Transitions returns the state changes in [start, end), oldest first. The
+// last change of each node before start is included as well, so the state
+// of every node at start is known; lookback limits how far back it is
+// searched.
+func (i *Inventory) Transitions(start, end time.Time, lookback time.Duration) ([]models.NodeTransition, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	start, end = start.UTC(), end.UTC()
+	var result []models.NodeTransition
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for ; month.Before(end); month = month.AddDate(0, 1, 0) {
+		stored, err := i.loadTransitions(month.Format("2006-01"))
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range stored {
+			if !t.At.Before(start) && t.At.Before(end) {
+				result = append(result, t)
+			}
+		}
+	}
+
+	// Search earlier months for the state of each node at start
+	nodes, err := i.load()
+	if err != nil {
+		return nil, err
+	}
+	missing := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		missing[n.Name] = true
+	}
+	var initial []models.NodeTransition
+	limit := start.Add(-lookback)
+	for month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); len(missing) > 0 && !month.Add(31*24*time.Hour).Before(limit); month = month.AddDate(0, -1, 0) {
+		stored, err := i.loadTransitions(month.Format("2006-01"))
+		if err != nil {
+			return nil, err
+		}
+		for idx := len(stored) - 1; idx >= 0; idx-- {
+			t := stored[idx]
+			if t.At.Before(start) && !t.At.Before(limit) && missing[t.Node] {
+				initial = append(initial, t)
+				delete(missing, t.Node)
+			}
+		}
+	}
+
+	result = append(initial, result...)
+	sort.SliceStable(result, func(a, b int) bool { return result[a].At.Before(result[b].At) })
+	if result == nil {
+		result = []models.NodeTransition{}
+	}
+	return result, nil
+}
+
+// loadTransitions reads the state changes of a month
+func (i *Inventory) loadTransitions(period string) ([]models.NodeTransition, error) {
+	transitions := []models.NodeTransition{}
+	err := storage.GetData(i.storage, transitionsKey(period), &transitions)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load node transitions for %s: %w", period, err)
+	}
+	return transitions, nil
+}
+
+// transitionsKey returns the storage key for the state changes of a month
+func transitionsKey(period string) string {
+	return "node_transitions_" + period
+}
@@ -0,0 +1,176 @@
+package inventory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// fakeSource reports a fixed list of nodes
+type fakeSource []models.Node
+
+func (s fakeSource) Nodes(ctx context.Context) ([]models.Node, error) {
+	nodes := make([]models.Node, len(s))
+	copy(nodes, s)
+	return nodes, nil
+}
+
+func newTestInventory(t *testing.T, source Source) *Inventory {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	return New(store, source)
+}
+
+// transition is the change of a node of asuka at a time
+func transition(node, from, to string, at time.Time) models.NodeTransition {
+	return models.NodeTransition{Node: node, Cluster: "asuka", From: from, To: to, At: at}
+}
+
+func TestRecordTransitions(t *testing.T) {
+	inv := newTestInventory(t, nil)
+	jan := time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 1, 0, 0, 0, time.UTC)
+	online, offline := models.NodeStateOnline, models.NodeStateOffline
+
+	steps := []struct {
+		at    time.Time
+		nodes []models.Node
+	}{
+		{jan, []models.Node{{Name: "asuka01", Cluster: "asuka", State: online}, {Name: "asuka02", Cluster: "asuka", State: online}}},
+		{jan.Add(30 * time.Minute), []models.Node{{Name: "asuka01", Cluster: "asuka", State: online}, {Name: "asuka02", Cluster: "asuka", State: online}}},
+		{feb, []models.Node{{Name: "asuka01", Cluster: "asuka", State: offline}, {Name: "asuka02", Cluster: "asuka", State: online}}},
+	}
+	for _, step := range steps {
+		if err := inv.recordTransitions(step.nodes, step.at); err != nil {
+			t.Fatalf("recordTransitions: %v", err)
+		}
+	}
+
+	// A first sighting is a transition from no state; unchanged nodes are
+	// not recorded, and each month has its own key
+	january, err := inv.loadTransitions("2024-01")
+	if err != nil {
+		t.Fatalf("loadTransitions: %v", err)
+	}
+	if want := []models.NodeTransition{transition("asuka01", "", online, jan), transition("asuka02", "", online, jan)}; !reflect.DeepEqual(january, want) {
+		t.Errorf("January = %+v, want %+v", january, want)
+	}
+	february, err := inv.loadTransitions("2024-02")
+	if err != nil {
+		t.Fatalf("loadTransitions: %v", err)
+	}
+	if want := []models.NodeTransition{transition("asuka01", online, offline, feb)}; !reflect.DeepEqual(february, want) {
+		t.Errorf("February = %+v, want %+v", february, want)
+	}
+}
+
+func TestTransitionsLookback(t *testing.T) {
+	inv := newTestInventory(t, nil)
+	online, offline := models.NodeStateOnline, models.NodeStateOffline
+	nodes := []models.Node{{Name: "asuka01", Cluster: "asuka"}, {Name: "asuka02", Cluster: "asuka"}, {Name: "asuka03", Cluster: "asuka"}}
+	if err := inv.save(nodes); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	nov := time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC)
+	dec := time.Date(2023, 12, 30, 0, 0, 0, 0, time.UTC)
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
+	stored := map[string][]models.NodeTransition{
+		"2023-11": {transition("asuka01", "", online, nov), transition("asuka02", "", online, nov.Add(time.Hour)), transition("asuka03", "", online, nov.Add(2*time.Hour))},
+		"2023-12": {transition("asuka02", online, offline, dec)},
+		"2024-01": {transition("asuka01", online, offline, jan), transition("asuka01", offline, online, jan.Add(time.Hour))},
+		"2024-02": {transition("asuka03", online, offline, feb)},
+	}
+	for period, ts := range stored {
+		if err := storage.SetData(inv.storage, transitionsKey(period), ts); err != nil {
+			t.Fatalf("SetData: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		lookback   time.Duration
+		want       []models.NodeTransition
+	}{
+		{
+			// The window spans two months; the state of each node at the
+			// start is the latest change before it, months earlier
+			name:     "across months",
+			start:    time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC),
+			lookback: 90 * 24 * time.Hour,
+			want: []models.NodeTransition{
+				transition("asuka01", "", online, nov),
+				transition("asuka03", "", online, nov.Add(2*time.Hour)),
+				transition("asuka02", online, offline, dec),
+				transition("asuka01", online, offline, jan),
+				transition("asuka01", offline, online, jan.Add(time.Hour)),
+				transition("asuka03", online, offline, feb),
+			},
+		},
+		{
+			name:     "lookback limits the search",
+			start:    time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC),
+			lookback: 30 * 24 * time.Hour,
+			want:     []models.NodeTransition{transition("asuka02", online, offline, dec)},
+		},
+		{
+			name:     "end is exclusive",
+			start:    time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			end:      feb,
+			lookback: 40 * 24 * time.Hour,
+			want: []models.NodeTransition{
+				transition("asuka02", online, offline, dec),
+				transition("asuka01", offline, online, jan.Add(time.Hour)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inv.Transitions(tt.start, tt.end, tt.lookback)
+			if err != nil {
+				t.Fatalf("Transitions: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transitions =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshRecordsTransitions(t *testing.T) {
+	source := fakeSource{{Name: "asuka01", Cluster: "asuka", State: models.NodeStateOnline}}
+	inv := newTestInventory(t, source)
+
+	if _, err := inv.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	source[0].State = models.NodeStateOffline
+	nodes, err := inv.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	// LastSeen is when the node was last online
+	if nodes[0].LastSeen.IsZero() || !nodes[0].LastSeen.Before(nodes[0].UpdatedAt) {
+		t.Errorf("node = %+v", nodes[0])
+	}
+
+	now := time.Now()
+	transitions, err := inv.Transitions(now.Add(-time.Hour), now.Add(time.Minute), time.Hour)
+	if err != nil {
+		t.Fatalf("Transitions: %v", err)
+	}
+	if len(transitions) != 2 || transitions[0].To != models.NodeStateOnline || transitions[1].From != models.NodeStateOnline || transitions[1].To != models.NodeStateOffline {
+		t.Errorf("transitions = %+v", transitions)
+	}
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// qstatJob holds the fields of `qstat -f -F json` used by the tracker
+type qstatJob struct {
+	Name          string                 `json:"Job_Name"`
+	Owner         string                 `json:"Job_Owner"`
+	State         string                 `json:"job_state"`
+	Queue         string                 `json:"queue"`
+	STime         string                 `json:"stime"`
+	ResourcesUsed map[string]interface{} `json:"resources_used"`
+	ResourceList  map[string]interface{} `json:"Resource_List"`
+}
+
+// ParseQstatJSON parses `qstat -f -F json` and returns the running jobs.
+// The cluster of a job is its queue name without queuePrefix, following the
+// "work_<cluster>" queue layout used for discovery.
+func ParseQstatJSON(out []byte, queuePrefix string, now time.Time) ([]models.Job, error) {
+	var doc struct {
+		Jobs map[string]qstatJob `json:"Jobs"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		return nil, fmt.Errorf("invalid qstat output: %w", err)
+	}
+
+	jobs := make([]models.Job, 0, len(doc.Jobs))
+	for id, q := range doc.Jobs {
+		if q.State != "R" {
+			continue
+		}
+
+		job := models.Job{
+			ID:              id,
+			Name:            q.Name,
+			User:            strings.SplitN(q.Owner, "@", 2)[0],
+			Queue:           q.Queue,
+			Cluster:         strings.TrimPrefix(q.Queue, queuePrefix),
+			State:           models.JobStateRunning,
+			NCPUs:           int(number(q.ResourceList["ncpus"])),
+			MemoryBytes:     size(q.ResourceList["mem"]),
+			WalltimeSeconds: duration(q.ResourcesUsed["walltime"]),
+			CPUSeconds:      duration(q.ResourcesUsed["cput"]),
+			StartedAt:       now,
+			LastSeen:        now,
+		}
+		if job.NCPUs == 0 {
+			job.NCPUs = int(number(q.ResourcesUsed["ncpus"]))
+		}
+		if t, err := time.ParseInLocation(time.ANSIC, q.STime, time.Local); err == nil {
+			job.StartedAt = t.UTC()
+		}
+		if capacity := job.WalltimeSeconds * float64(job.NCPUs); capacity > 0 {
+			job.Efficiency = job.CPUSeconds / capacity
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+// number converts a JSON number or numeric string to a float
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+// size converts a PBS size such as "16gb" or "512mb" to bytes
+func size(v interface{}) int64 {
+	s, ok := v.(string)
+	if !ok {
+		return int64(number(v))
+	}
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		bytes  int64
+	}{{"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"b", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSuffix(s, unit.suffix)
+			multiplier = unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n * multiplier
+}
+
+// duration converts a PBS "HH:MM:SS" time, or a number of seconds, to seconds
+func duration(v interface{}) float64 {
+	s, ok := v.(string)
+	if !ok {
+		return number(v)
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// runningKey maps the IDs of jobs running at the last collection to the
+// month they are stored under
+const runningKey = "jobs_running"
+
+// ErrInvalidPeriod is returned for periods that are not formatted as YYYY-MM
+var ErrInvalidPeriod = errors.New("invalid period, expected YYYY-MM")
+
+// Tracker records running PBS jobs, replacing the job table of occrate.sh.
+// Each collection updates the walltime and CPU time of running jobs; a job
+// that is no longer running is marked finished with the values last seen.
+// Jobs are stored by the month they started.
+type Tracker struct {
+	storage     storage.Storage
+	QstatPath   string // Path to qstat
+	QueuePrefix string // Queue name prefix, e.g. "work_"
+
+	// OnCollect, when set, is called with the running jobs of every collection
+	OnCollect func(at time.Time, running []models.Job) error
+
+	mu sync.Mutex
+}
+
+// NewTracker creates a job tracker
+func NewTracker(store storage.Storage, qstatPath string) *Tracker {
+	return &Tracker{
+		storage:     store,
+		QstatPath:   qstatPath,
+		QueuePrefix: "work_",
+	}
+}
+
+// Collect runs `qstat -f -F json` and records the running jobs
+func (t *Tracker) Collect(ctx context.Context) error {
+	out, err := exec.CommandContext(ctx, t.QstatPath, "-f", "-F", "json").Output()
+	if err != nil {
+		return fmt.Errorf("failed to run %s -f -F json: %w", t.QstatPath, err)
+	}
+
+	now := time.Now().UTC()
+	jobs, err := ParseQstatJSON(out, t.QueuePrefix, now)
+	if err != nil {
+		return err
+	}
+	if err := t.Record(jobs, now); err != nil {
+		return err
+	}
+	if t.OnCollect != nil {
+		return t.OnCollect(now, jobs)
+	}
+	return nil
+}
+
+// Run collects at the given interval until ctx is done
+func (t *Tracker) Run(ctx context.Context, interval *schedule.Interval) {
+	collector.RunEvery(ctx, interval, "PBS job", t.Collect)
+}
+
+// Record stores the jobs running at now and marks jobs that were running at
+// the previous call as finished
+func (t *Tracker) Record(running []models.Job, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := make(map[string]string)
+	if err := storage.GetData(t.storage, runningKey, &previous); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to load running jobs: %w", err)
+	}
+
+	updates := make(map[string][]models.Job)
+	current := make(map[string]string, len(running))
+	for _, job := range running {
+		period := job.StartedAt.Format("2006-01")
+		if p, ok := previous[job.ID]; ok {
+			// Keep the month of the first sighting if stime was unavailable then
+			period = p
+		}
+		updates[period] = append(updates[period], job)
+		current[job.ID] = period
+	}
+	for id, period := range previous {
+		if _, ok := current[id]; !ok {
+			if _, ok := updates[period]; !ok {
+				updates[period] = nil
+			}
+		}
+	}
+
+	for period, jobs := range updates {
+		if err := t.updateMonth(period, jobs, current, now); err != nil {
+			return err
+		}
+	}
+	return storage.SetData(t.storage, runningKey, current)
+}
+
+// updateMonth upserts the running jobs of a month and finishes the jobs of
+// that month that are no longer running
+func (t *Tracker) updateMonth(period string, running []models.Job, current map[string]string, now time.Time) error {
+	stored, err := t.loadMonth(period)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]int, len(stored))
+	for i, job := range stored {
+		byID[job.ID] = i
+	}
+	for _, job := range running {
+		if i, ok := byID[job.ID]; ok {
+			job.StartedAt = stored[i].StartedAt
+			stored[i] = job
+			continue
+		}
+		byID[job.ID] = len(stored)
+		stored = append(stored, job)
+	}
+	for i, job := range stored {
+		if _, ok := current[job.ID]; ok || job.State != models.JobStateRunning {
+			continue
+		}
+		ended := job.LastSeen
+		stored[i].State = models.JobStateFinished
+		stored[i].EndedAt = &ended
+	}
+
+	sort.Slice(stored, func(i, j int) bool {
+		if !stored[i].StartedAt.Equal(stored[j].StartedAt) {
+			return stored[i].StartedAt.Before(stored[j].StartedAt)
+		}
+		return stored[i].ID < stored[j].ID
+	})
+	return storage.SetData(t.storage, monthKey(period), stored)
+}
+
+// Month returns the jobs that started in a month, oldest first
+func (t *Tracker) Month(period string) ([]models.Job, error) {
+	if _, err := time.Parse("2006-01", period); err != nil {
+		return nil, ErrInvalidPeriod
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.loadMonth(period)
+}
+
+// LowEfficiency returns the jobs that ran for at least minWalltime with an
+// efficiency below threshold, least efficient first. occrate.sh reported
+// jobs below 40% for 12 hours.
+func LowEfficiency(jobs []models.Job, threshold float64, minWalltime time.Duration) []models.Job {
+	result := []models.Job{}
+	for _, job := range jobs {
+		if job.WalltimeSeconds >= minWalltime.Seconds() && job.Efficiency < threshold {
+			result = append(result, job)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Efficiency < result[j].Efficiency })
+	return result
+}
+
+// loadMonth reads the jobs of a month
+func (t *Tracker) loadMonth(period string) ([]models.Job, error) {
+	jobs := []models.Job{}
+	err := storage.GetData(t.storage, monthKey(period), &jobs)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load jobs for %s: %w", period, err)
+	}
+	return jobs, nil
+}
+
+// monthKey returns the storage key for the jobs started in a month
+func monthKey(period string) string {
+	return "jobs_" + period
+}
@@ -0,0 +1,87 @@
+package maintenance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// icalTime is the UTC date-time format of iCalendar
+const icalTime = "20060102T150405Z"
+
+// WriteICS writes windows as an iCalendar (RFC 5545) feed with one event
+// per window, for subscribing from calendar applications
+func WriteICS(w io.Writer, name string, windows []models.MaintenanceWindow) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//cluster-status-monitor//maintenance//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escapeText(name))
+	for _, win := range windows {
+		summary := "Maintenance: " + win.Cluster
+		description := win.Description
+		if len(win.Nodes) > 0 {
+			summary += " (" + strings.Join(win.Nodes, ", ") + ")"
+			description = strings.TrimSpace(description + "\n\nNodes: " + strings.Join(win.Nodes, ", "))
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("maintenance-%d@cluster-status-monitor", win.ID))
+		line("DTSTAMP", win.UpdatedAt.UTC().Format(icalTime))
+		line("LAST-MODIFIED", win.UpdatedAt.UTC().Format(icalTime))
+		line("DTSTART", win.Start.UTC().Format(icalTime))
+		line("DTEND", win.End.UTC().Format(icalTime))
+		line("SUMMARY", escapeText(summary))
+		if description != "" {
+			line("DESCRIPTION", escapeText(description))
+		}
+		line("CATEGORIES", "MAINTENANCE")
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText escapes a TEXT property value
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// writeFolded writes a content line ending in CRLF, folding it into lines
+// of at most 75 octets without splitting UTF-8 sequences
+func writeFolded(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8Start(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// utf8Start reports whether b starts a UTF-8 sequence
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,261 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// storageKey is the storage key holding the maintenance windows
+const storageKey = "maintenance_windows"
+
+var (
+	// ErrWindowNotFound is returned for unknown maintenance window IDs
+	ErrWindowNotFound = errors.New("maintenance window not found")
+	// ErrInvalidWindow is returned for windows that fail validation
+	ErrInvalidWindow = errors.New("invalid maintenance window")
+)
+
+// state is the persisted window list
+type state struct {
+	NextID  int                        `json:"next_id"`
+	Windows []models.MaintenanceWindow `json:"windows"`
+}
+
+// Filter selects maintenance windows; empty fields match every window
+type Filter struct {
+	Cluster  string
+	Upcoming bool // Only windows that have not ended
+}
+
+// Store keeps the scheduled maintenance windows. ApplyToNodes and
+// SilenceAlerts are set as hooks of the node inventory and the alert engine.
+type Store struct {
+	storage storage.Storage
+	mu      sync.Mutex
+}
+
+// NewStore creates a maintenance window store
+func NewStore(store storage.Storage) *Store {
+	return &Store{storage: store}
+}
+
+// List returns the windows matching the filter ordered by start time
+func (s *Store) List(f Filter) ([]models.MaintenanceWindow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := []models.MaintenanceWindow{}
+	for _, w := range st.Windows {
+		if f.Cluster != "" && w.Cluster != f.Cluster {
+			continue
+		}
+		if f.Upcoming && !w.End.After(now) {
+			continue
+		}
+		result = append(result, w)
+	}
+	return result, nil
+}
+
+// Active returns the windows in effect at t
+func (s *Store) Active(t time.Time) ([]models.MaintenanceWindow, error) {
+	windows, err := s.List(Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.MaintenanceWindow{}
+	for _, w := range windows {
+		if w.Active(t) {
+			result = append(result, w)
+		}
+	}
+	return result, nil
+}
+
+// Get returns one window
+func (s *Store) Get(id int) (*models.MaintenanceWindow, error) {
+	windows, err := s.List(Filter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range windows {
+		if w.ID == id {
+			return &w, nil
+		}
+	}
+	return nil, ErrWindowNotFound
+}
+
+// Create schedules a window
+func (s *Store) Create(w models.MaintenanceWindow) (*models.MaintenanceWindow, error) {
+	if err := prepare(&w); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	st.NextID++
+	w.ID = st.NextID
+	w.CreatedAt = now
+	w.UpdatedAt = now
+	st.Windows = append(st.Windows, w)
+
+	if err := s.save(st); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Put replaces the cluster, nodes, schedule and description of a window
+func (s *Store) Put(id int, w models.MaintenanceWindow) (*models.MaintenanceWindow, error) {
+	if err := prepare(&w); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range st.Windows {
+		if st.Windows[i].ID != id {
+			continue
+		}
+		w.ID = id
+		w.CreatedAt = st.Windows[i].CreatedAt
+		w.UpdatedAt = time.Now().UTC()
+		st.Windows[i] = w
+		if err := s.save(st); err != nil {
+			return nil, err
+		}
+		return &w, nil
+	}
+	return nil, ErrWindowNotFound
+}
+
+// Delete removes a window
+func (s *Store) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range st.Windows {
+		if st.Windows[i].ID == id {
+			st.Windows = append(st.Windows[:i], st.Windows[i+1:]...)
+			return s.save(st)
+		}
+	}
+	return ErrWindowNotFound
+}
+
+// ApplyToNodes reports the nodes covered by a window in effect at t as in
+// maintenance. It is the OnRefresh hook of the node inventory.
+func (s *Store) ApplyToNodes(nodes []models.Node, t time.Time) error {
+	active, err := s.Active(t)
+	if err != nil {
+		return err
+	}
+	for i := range nodes {
+		for _, w := range active {
+			if w.Covers(nodes[i].Cluster, nodes[i].Name) {
+				nodes[i].State = models.NodeStateMaintenance
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// SilenceAlerts marks the alerts of clusters and nodes covered by a window
+// in effect at t as silenced. It is the Silence hook of the alert engine.
+func (s *Store) SilenceAlerts(alerts []models.Alert, t time.Time) error {
+	active, err := s.Active(t)
+	if err != nil {
+		return err
+	}
+	for i := range alerts {
+		for _, w := range active {
+			if w.Covers(alerts[i].Cluster, alerts[i].Node) {
+				alerts[i].Silenced = true
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// prepare validates a window and normalizes its fields
+func prepare(w *models.MaintenanceWindow) error {
+	w.Cluster = strings.TrimSpace(w.Cluster)
+	if w.Cluster == "" {
+		return fmt.Errorf("%w: cluster is required", ErrInvalidWindow)
+	}
+	if w.Start.IsZero() || w.End.IsZero() {
+		return fmt.Errorf("%w: start and end are required", ErrInvalidWindow)
+	}
+	if !w.End.After(w.Start) {
+		return fmt.Errorf("%w: end must be after start", ErrInvalidWindow)
+	}
+	w.Start = w.Start.UTC()
+	w.End = w.End.UTC()
+	w.Description = strings.TrimSpace(w.Description)
+
+	nodes := []string{}
+	seen := make(map[string]bool)
+	for _, n := range w.Nodes {
+		n = strings.TrimSpace(n)
+		if n != "" && !seen[n] {
+			seen[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Strings(nodes)
+	w.Nodes = nodes
+	return nil
+}
+
+// load reads the windows from storage
+func (s *Store) load() (*state, error) {
+	st := &state{}
+	err := storage.GetData(s.storage, storageKey, st)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load maintenance windows: %w", err)
+	}
+	if st.Windows == nil {
+		st.Windows = []models.MaintenanceWindow{}
+	}
+	return st, nil
+}
+
+// save writes the windows ordered by start time
+func (s *Store) save(st *state) error {
+	sort.SliceStable(st.Windows, func(i, j int) bool {
+		return st.Windows[i].Start.Before(st.Windows[j].Start)
+	})
+	return storage.SetData(s.storage, storageKey, st)
+}
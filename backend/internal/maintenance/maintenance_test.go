@@ -0,0 +1,160 @@
+package maintenance
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Maintenance"},
+		{"exactly 75 octets", "DESCRIPTION:" + strings.Repeat("a", 63)},
+		{"ascii", "DESCRIPTION:" + strings.Repeat("a", 200)},
+		{"multibyte", "DESCRIPTION:" + strings.Repeat("計画停電のため停止します。", 12)},
+		{"multibyte at the boundary", "DESCRIPTION:" + strings.Repeat("a", 62) + strings.Repeat("é", 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeFolded(w, tt.line)
+			w.Flush()
+
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end in CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			var unfolded strings.Builder
+			for i, l := range lines {
+				if len(l) > 75 {
+					t.Errorf("line %d is %d octets", i, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, l)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Errorf("continuation line %d does not start with a space", i)
+					}
+					l = l[1:]
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolded %q, want %q", unfolded.String(), tt.line)
+			}
+			if len(tt.line) <= 75 && len(lines) != 1 {
+				t.Errorf("a %d octet line was folded", len(tt.line))
+			}
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	got := escapeText("a,b;c\\d\r\ne\nf")
+	if want := `a\,b\;c\\d\ne\nf`; got != want {
+		t.Errorf("escapeText = %q, want %q", got, want)
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	windows := []models.MaintenanceWindow{
+		{ID: 7, Cluster: "asuka", Nodes: []string{"asuka01", "asuka02"}, Start: start, End: start.Add(8 * time.Hour), Description: "Firmware; reboot", UpdatedAt: start.Add(-time.Hour)},
+		{ID: 8, Cluster: "naruko", Start: start, End: start.Add(time.Hour)},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, "Cluster maintenance", windows); err != nil {
+		t.Fatalf("WriteICS: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Cluster maintenance\r\n",
+		"UID:maintenance-7@cluster-status-monitor\r\n",
+		"DTSTART:20240601T000000Z\r\nDTEND:20240601T080000Z\r\n",
+		"SUMMARY:Maintenance: asuka (asuka01\\, asuka02)\r\n",
+		"DESCRIPTION:Firmware\\; reboot\\n\\nNodes: asuka01\\, asuka02\r\n",
+		"UID:maintenance-8@cluster-status-monitor\r\n",
+		"SUMMARY:Maintenance: naruko\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VEVENT") != 2 || strings.Count(out, "DESCRIPTION:") != 1 {
+		t.Errorf("feed has the wrong events:\n%s", out)
+	}
+}
+
+func TestStoreWindows(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	s := NewStore(store)
+	now := time.Now().UTC()
+
+	for _, w := range []models.MaintenanceWindow{
+		{Start: now, End: now.Add(time.Hour)},
+		{Cluster: "asuka"},
+		{Cluster: "asuka", Start: now, End: now},
+	} {
+		if _, err := s.Create(w); !errors.Is(err, ErrInvalidWindow) {
+			t.Errorf("Create(%+v) error = %v, want ErrInvalidWindow", w, err)
+		}
+	}
+
+	w, err := s.Create(models.MaintenanceWindow{Cluster: " asuka ", Nodes: []string{"asuka02", " asuka01", "asuka02", ""}, Start: now.Add(-time.Hour), End: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if w.ID != 1 || w.Cluster != "asuka" || strings.Join(w.Nodes, ",") != "asuka01,asuka02" {
+		t.Errorf("window = %+v", w)
+	}
+	if _, err := s.Create(models.MaintenanceWindow{Cluster: "naruko", Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	nodes := []models.Node{
+		{Name: "asuka01", Cluster: "asuka", State: models.NodeStateOnline},
+		{Name: "asuka03", Cluster: "asuka", State: models.NodeStateOnline},
+		{Name: "naruko01", Cluster: "naruko", State: models.NodeStateOffline},
+	}
+	if err := s.ApplyToNodes(nodes, now); err != nil {
+		t.Fatalf("ApplyToNodes: %v", err)
+	}
+	if nodes[0].State != models.NodeStateMaintenance || nodes[1].State != models.NodeStateOnline || nodes[2].State != models.NodeStateOffline {
+		t.Errorf("nodes = %+v", nodes)
+	}
+
+	alerts := []models.Alert{{Cluster: "asuka", Node: "asuka02"}, {Cluster: "asuka"}, {Cluster: "naruko", Node: "naruko01"}}
+	if err := s.SilenceAlerts(alerts, now); err != nil {
+		t.Fatalf("SilenceAlerts: %v", err)
+	}
+	// A window for some nodes does not silence alerts of the whole cluster
+	if !alerts[0].Silenced || alerts[1].Silenced || alerts[2].Silenced {
+		t.Errorf("alerts = %+v", alerts)
+	}
+
+	if err := s.Delete(w.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(w.ID); !errors.Is(err, ErrWindowNotFound) {
+		t.Errorf("Get of a deleted window: error = %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+// UserCPUDay is the CPU time a user consumed on one day, summed over hosts
+type UserCPUDay struct {
+	Date       string  `json:"date"` // YYYY-MM-DD
+	User       string  `json:"user"`
+	CPUMinutes float64 `json:"cpu_minutes"`
+	PBSMinutes float64 `json:"pbs_minutes,omitempty"` // Part of CPUMinutes on nodes scheduled by PBS
+}
+
+// UserCPUPeriod is the CPU time of a user rolled up over an accounting period
+type UserCPUPeriod struct {
+	Period     string  `json:"period"` // YYYY-MM
+	User       string  `json:"user"`
+	CPUMinutes float64 `json:"cpu_minutes"`
+	CPUHours   float64 `json:"cpu_hours"`
+	PBSMinutes float64 `json:"pbs_minutes"` // Part of CPUMinutes on nodes scheduled by PBS
+	ActiveDays int     `json:"active_days"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Alert severities
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert states
+const (
+	AlertStateFiring   = "firing"
+	AlertStateResolved = "resolved"
+)
+
+// Alert is a condition reported by an alert rule
+type Alert struct {
+	ID         string     `json:"id"`
+	Rule       string     `json:"rule"`
+	Type       string     `json:"type"`
+	Severity   string     `json:"severity"`
+	State      string     `json:"state"`
+	Cluster    string     `json:"cluster"`
+	Node       string     `json:"node,omitempty"`
+	Target     string     `json:"target"` // What the alert is about, e.g. "asuka00:/home"
+	Summary    string     `json:"summary"`
+	Value      float64    `json:"value"`
+	Silenced   bool       `json:"silenced,omitempty"` // Muted by a maintenance window
+	StartsAt   time.Time  `json:"starts_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
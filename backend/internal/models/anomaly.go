@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AnomalyEvent is a sample that deviated from its series baseline
+type AnomalyEvent struct {
+	Series   string    `json:"series"`
+	Cluster  string    `json:"cluster"`
+	Node     string    `json:"node,omitempty"`
+	Metric   string    `json:"metric"`
+	Value    float64   `json:"value"`
+	Expected float64   `json:"expected"`
+	StdDev   float64   `json:"stddev"`
+	Score    float64   `json:"score"`    // Deviation from the baseline in standard deviations
+	Baseline string    `json:"baseline"` // "hour_of_week" or "overall"
+	Time     time.Time `json:"time"`
+}
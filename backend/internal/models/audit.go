@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// AuditEntry records one administrative or data-changing API request
+type AuditEntry struct {
+	ID        string                 `json:"id"`
+	At        time.Time              `json:"at"`
+	Actor     string                 `json:"actor"`
+	Source    string                 `json:"source,omitempty"`   // How the actor signed in
+	TokenID   string                 `json:"token_id,omitempty"` // API token the request was made with
+	Action    string                 `json:"action"`             // Such as "cluster.update"
+	Target    string                 `json:"target"`             // Such as "cluster:asuka"
+	RequestID string                 `json:"request_id,omitempty"`
+	IP        string                 `json:"ip,omitempty"`   // Client address; forwarded only by trusted proxies
+	Diff      map[string]AuditChange `json:"diff,omitempty"` // Changed fields of the target
+}
+
+// AuditChange is the old and new value of one field; From is absent for
+// created objects and To for deleted ones
+type AuditChange struct {
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
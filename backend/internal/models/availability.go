@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// NodeTransition records a node changing state in the inventory. From is
+// empty the first time a node is seen.
+type NodeTransition struct {
+	Node     string    `json:"node"`
+	Cluster  string    `json:"cluster"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	PBSState string    `json:"pbs_state,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Availability summarizes the uptime of a node, or of every node of a
+// cluster, over a window. Time spent in maintenance and time before a node
+// was first seen do not count towards availability.
+type Availability struct {
+	Cluster            string    `json:"cluster"`
+	Node               string    `json:"node,omitempty"`
+	Nodes              int       `json:"nodes,omitempty"` // Nodes in a cluster summary
+	Start              time.Time `json:"start"`
+	End                time.Time `json:"end"`
+	Availability       *float64  `json:"availability"` // Percent of the observed time online
+	UptimeSeconds      float64   `json:"uptime_seconds"`
+	DowntimeSeconds    float64   `json:"downtime_seconds"`
+	MaintenanceSeconds float64   `json:"maintenance_seconds"`
+	Outages            int       `json:"outages"` // Offline periods overlapping the window
+	MTBFSeconds        *float64  `json:"mtbf_seconds"`
+	MTTRSeconds        *float64  `json:"mttr_seconds"`
+}
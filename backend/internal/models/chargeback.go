@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Research group sources
+const (
+	GroupSourceManual   = "manual"
+	GroupSourceImported = "imported"
+)
+
+// ResearchGroup is a group that cluster costs are charged to
+type ResearchGroup struct {
+	Name      string    `json:"name"`
+	Members   []string  `json:"members"`
+	Source    string    `json:"source"` // "manual" or "imported"
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Invoice line sources
+const (
+	ChargeSourceJobs       = "jobs"       // Allocated cores × walltime of PBS jobs
+	ChargeSourceAccounting = "accounting" // CPU time from process accounting
+)
+
+// InvoiceLine is the usage of one user on one cluster, or of one user in
+// process accounting, priced at the rate of the cluster
+type InvoiceLine struct {
+	User      string  `json:"user"`
+	Cluster   string  `json:"cluster,omitempty"`
+	Source    string  `json:"source"`
+	Jobs      int     `json:"jobs,omitempty"`
+	CoreHours float64 `json:"core_hours"`
+	Rate      float64 `json:"rate"`
+	Amount    float64 `json:"amount"`
+}
+
+// Invoice is the usage charged to a research group in a period
+type Invoice struct {
+	Period      string        `json:"period"` // YYYY-MM
+	Group       string        `json:"group"`
+	Currency    string        `json:"currency"`
+	Lines       []InvoiceLine `json:"lines"`
+	CoreHours   float64       `json:"core_hours"`
+	Amount      float64       `json:"amount"`
+	GeneratedAt time.Time     `json:"generated_at"`
+}
@@ -33,3 +33,60 @@ type ClusterData struct {
 	Cluster string                 `json:"cluster"`
 	Data    map[string]interface{} `json:"data"`
 }
+
+// ClusterType distinguishes compute clusters from file servers
+type ClusterType string
+
+const (
+	ClusterTypeCompute    ClusterType = "compute"
+	ClusterTypeFileServer ClusterType = "fileserver"
+)
+
+// Cluster sources recorded in the registry
+const (
+	ClusterSourceDiscovered = "discovered"
+	ClusterSourceStatic     = "static"
+	ClusterSourceManual     = "manual"
+)
+
+// ClusterInfo represents a cluster known to the registry
+type ClusterInfo struct {
+	Name         string      `json:"name"`
+	Type         ClusterType `json:"type"`
+	MasterHost   string      `json:"master_host"`
+	Description  string      `json:"description,omitempty"`
+	Queue        string      `json:"queue,omitempty"`
+	UserRoots    []string    `json:"user_roots,omitempty"`    // Directories holding per-user data, e.g. /home
+	MountInclude []string    `json:"mount_include,omitempty"` // Mount point patterns to collect (default: all)
+	MountExclude []string    `json:"mount_exclude,omitempty"` // Mount point patterns to skip
+	Source       string      `json:"source"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// Node states, matching the nodes table and the frontend NodeStatus type
+const (
+	NodeStateOnline      = "online"
+	NodeStateOffline     = "offline"
+	NodeStateMaintenance = "maintenance"
+)
+
+// Node represents a compute node known to the node inventory
+type Node struct {
+	Name      string    `json:"name"`
+	Cluster   string    `json:"cluster"`
+	Host      string    `json:"host"`
+	State     string    `json:"state"`
+	PBSState  string    `json:"pbs_state,omitempty"`
+	NCPUs     int       `json:"ncpus,omitempty"` // resources_available.ncpus
+	LastSeen  time.Time `json:"last_seen"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NodeLoad is the load average of a node read from /proc/loadavg
+type NodeLoad struct {
+	Node        string    `json:"node"`
+	Load1       float64   `json:"load1"`
+	Load5       float64   `json:"load5"`
+	Load15      float64   `json:"load15"`
+	CollectedAt time.Time `json:"collected_at"`
+}
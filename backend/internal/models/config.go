@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// What triggered a configuration reload
+const (
+	ConfigReloadSignal = "signal" // SIGHUP
+	ConfigReloadWatch  = "watch"  // The configuration file changed
+	ConfigReloadAPI    = "api"
+)
+
+// ConfigReload is the outcome of reloading the configuration. Keys are
+// those of the configuration file, such as "alerts.interval".
+type ConfigReload struct {
+	At              time.Time `json:"at"`
+	Trigger         string    `json:"trigger"`
+	Actor           string    `json:"actor,omitempty"` // User who requested an API reload
+	Success         bool      `json:"success"`
+	Error           string    `json:"error,omitempty"`
+	Errors          []string  `json:"errors,omitempty"` // One per invalid key
+	Applied         []string  `json:"applied"`          // Changed keys now in effect
+	RestartRequired []string  `json:"restart_required"` // Changed keys that take effect on the next start
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DigestSubscription selects the clusters covered by one recipient's weekly
+// digest. An empty cluster list covers every cluster.
+type DigestSubscription struct {
+	Recipient string    `json:"recipient"`
+	Clusters  []string  `json:"clusters"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Digest is the weekly summary sent to subscribers
+type Digest struct {
+	Start     time.Time            `json:"start"`
+	End       time.Time            `json:"end"`
+	Clusters  []ClusterUtilization `json:"clusters"` // Busiest first
+	DiskUsers []DiskUserReport     `json:"disk_users"`
+	DownNodes []Node               `json:"down_nodes"`
+	Alerts    []Alert              `json:"alerts"` // Firing at the end of the week
+}
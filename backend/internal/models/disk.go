@@ -0,0 +1,103 @@
+package models
+
+import "time"
+
+// DiskUserSnapshot is one collection of per-user directory sizes on a filesystem
+type DiskUserSnapshot struct {
+	Filesystem  string           `json:"filesystem"`
+	CollectedAt time.Time        `json:"collected_at"`
+	Users       map[string]int64 `json:"users"` // Bytes per user directory
+}
+
+// UserDiskUsage represents the disk usage of one user with its growth
+type UserDiskUsage struct {
+	User         string  `json:"user"`
+	Bytes        int64   `json:"bytes"`
+	UsedGB       float64 `json:"used_gb"`
+	DeltaBytes   int64   `json:"delta_bytes"`
+	DeltaPercent float64 `json:"delta_percent"`
+}
+
+// DiskUserReport is the per-user disk usage of one cluster filesystem
+type DiskUserReport struct {
+	Cluster     string          `json:"cluster"`
+	Filesystem  string          `json:"filesystem"`
+	CollectedAt time.Time       `json:"collected_at"`
+	ComparedTo  *time.Time      `json:"compared_to,omitempty"`
+	TotalBytes  int64           `json:"total_bytes"`
+	Users       []UserDiskUsage `json:"users"`
+	HasData     bool            `json:"has_data"`
+	Message     string          `json:"message,omitempty"`
+}
+
+// Filesystem is the capacity of one mounted filesystem on a host
+type Filesystem struct {
+	Host           string    `json:"host"`
+	MountPoint     string    `json:"mount_point"`
+	Device         string    `json:"device"`
+	FSType         string    `json:"fs_type"`
+	SizeBytes      int64     `json:"size_bytes"`
+	UsedBytes      int64     `json:"used_bytes"`
+	AvailableBytes int64     `json:"available_bytes"`
+	InodesUsed     int64     `json:"inodes_used"`
+	InodesTotal    int64     `json:"inodes_total"`
+	CollectedAt    time.Time `json:"collected_at"`
+}
+
+// UsagePercent returns the used share of the space available to users, as
+// reported by df (reserved blocks are excluded)
+func (f Filesystem) UsagePercent() float64 {
+	usable := f.UsedBytes + f.AvailableBytes
+	if usable == 0 {
+		return 0
+	}
+	return float64(f.UsedBytes) / float64(usable) * 100
+}
+
+// DiskUsage converts the filesystem to the shape used by the frontend
+// DiskHeatmapChart
+func (f Filesystem) DiskUsage() DiskUsage {
+	return DiskUsage{
+		Node:         f.Host,
+		MountPoint:   f.MountPoint,
+		UsedGB:       float64(f.UsedBytes) / (1 << 30),
+		TotalGB:      float64(f.SizeBytes) / (1 << 30),
+		UsagePercent: f.UsagePercent(),
+	}
+}
+
+// DiskUsage represents the disk usage of one mount on a node
+type DiskUsage struct {
+	Node         string  `json:"node"`
+	MountPoint   string  `json:"mount_point"`
+	UsedGB       float64 `json:"used_gb"`
+	TotalGB      float64 `json:"total_gb"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+// FilesystemSample is one historical capacity reading of a mount
+type FilesystemSample struct {
+	Host        string    `json:"host"`
+	MountPoint  string    `json:"mount_point"`
+	UsedBytes   int64     `json:"used_bytes"`
+	UsableBytes int64     `json:"usable_bytes"` // Used plus available
+	Time        time.Time `json:"time"`
+}
+
+// FilesystemForecast is the projected time until a mount is full
+type FilesystemForecast struct {
+	Cluster           string     `json:"cluster"`
+	Host              string     `json:"host"`
+	MountPoint        string     `json:"mount_point"`
+	UsedBytes         int64      `json:"used_bytes"`
+	UsableBytes       int64      `json:"usable_bytes"`
+	UsagePercent      float64    `json:"usage_percent"`
+	GrowthBytesPerDay float64    `json:"growth_bytes_per_day"`
+	DaysToFull        *float64   `json:"days_to_full,omitempty"`
+	FullAt            *time.Time `json:"full_at,omitempty"`
+	FullAtEarliest    *time.Time `json:"full_at_earliest,omitempty"` // Lower confidence bound
+	FullAtLatest      *time.Time `json:"full_at_latest,omitempty"`   // Upper confidence bound; omitted when unbounded
+	RSquared          float64    `json:"r_squared"`
+	Samples           int        `json:"samples"`
+	Message           string     `json:"message,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Incident statuses
+const (
+	IncidentStatusInvestigating = "investigating"
+	IncidentStatusIdentified    = "identified"
+	IncidentStatusResolved      = "resolved"
+)
+
+// Incident sources
+const (
+	IncidentSourceManual = "manual"
+	IncidentSourceAlert  = "alert" // Opened automatically from a firing alert
+)
+
+// IncidentUpdate is one entry of an incident timeline
+type IncidentUpdate struct {
+	At      time.Time `json:"at"`
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
+	Author  string    `json:"author,omitempty"`
+}
+
+// Incident tracks an outage or problem from detection to resolution
+type Incident struct {
+	ID         int              `json:"id"`
+	Title      string           `json:"title"`
+	Status     string           `json:"status"`
+	Severity   string           `json:"severity"`
+	Source     string           `json:"source"`
+	Clusters   []string         `json:"clusters"`
+	Nodes      []string         `json:"nodes"`
+	Alerts     []string         `json:"alerts"` // IDs of the linked alerts
+	Timeline   []IncidentUpdate `json:"timeline"`
+	Resolution string           `json:"resolution,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	ResolvedAt *time.Time       `json:"resolved_at,omitempty"`
+}
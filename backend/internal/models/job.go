@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Job states tracked by the job collector
+const (
+	JobStateRunning  = "running"
+	JobStateFinished = "finished"
+)
+
+// Job is a PBS job as observed while it was running
+type Job struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	User            string     `json:"user"`
+	Queue           string     `json:"queue"`
+	Cluster         string     `json:"cluster"`
+	State           string     `json:"state"`
+	NCPUs           int        `json:"ncpus"`
+	MemoryBytes     int64      `json:"memory_bytes"` // Requested memory
+	WalltimeSeconds float64    `json:"walltime_seconds"`
+	CPUSeconds      float64    `json:"cpu_seconds"`
+	Efficiency      float64    `json:"efficiency"` // CPU time / (walltime × ncpus), 0-1
+	StartedAt       time.Time  `json:"started_at"`
+	LastSeen        time.Time  `json:"last_seen"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"` // Last time seen running
+}
+
+// CoreHours returns the cores allocated to the job multiplied by its walltime
+func (j Job) CoreHours() float64 {
+	return float64(j.NCPUs) * j.WalltimeSeconds / 3600
+}
+
+// UserUsage is the PBS resources held by one user's running jobs on a
+// cluster at one time. It matches the user_usage table and the frontend
+// UserUsage type.
+type UserUsage struct {
+	Cluster   string    `json:"cluster"`
+	Username  string    `json:"username"`
+	CPUCores  int       `json:"cpu_cores"`
+	MemoryGB  float64   `json:"memory_gb"`
+	Jobs      int       `json:"jobs"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// UserUsageSummary ranks one user's usage over a window
+type UserUsageSummary struct {
+	Rank             int      `json:"rank"`
+	Username         string   `json:"username"`
+	Clusters         []string `json:"clusters"`
+	CoreHours        float64  `json:"core_hours"`
+	AvgCPUCores      float64  `json:"avg_cpu_cores"`
+	PeakCPUCores     int      `json:"peak_cpu_cores"`
+	AvgMemoryGB      float64  `json:"avg_memory_gb"`
+	AvgJobs          float64  `json:"avg_jobs"`
+	PercentOfCluster *float64 `json:"percent_of_cluster,omitempty"` // Average cores as a percent of the cores available
+	Share            float64  `json:"share"`                        // Percent of all core hours used in the window
+	FairShare        float64  `json:"fair_share"`                   // Target share in percent
+	FairShareRatio   float64  `json:"fair_share_ratio"`             // Share / FairShare; above 1 is over the target
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// MaintenanceWindow is scheduled maintenance of a cluster or some of its
+// nodes. While it is in effect the nodes are reported in maintenance and
+// their alerts are silenced.
+type MaintenanceWindow struct {
+	ID          int       `json:"id"`
+	Cluster     string    `json:"cluster"`
+	Nodes       []string  `json:"nodes"` // Empty for every node of the cluster
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Active reports whether the window is in effect at t
+func (w MaintenanceWindow) Active(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Covers reports whether the window applies to a node of a cluster. An
+// empty node matches only windows of the whole cluster.
+func (w MaintenanceWindow) Covers(cluster, node string) bool {
+	if cluster != w.Cluster {
+		return false
+	}
+	if len(w.Nodes) == 0 {
+		return true
+	}
+	for _, n := range w.Nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
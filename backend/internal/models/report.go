@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// ClusterUtilization is the mean utilization of a cluster over a period,
+// averaged from daily means
+type ClusterUtilization struct {
+	Cluster      string   `json:"cluster"`
+	Days         int      `json:"days"` // Days with samples
+	LoadAverage  float64  `json:"load_average"`
+	PBSUsage     float64  `json:"pbs_usage"`              // Percent of cores assigned by PBS
+	CPUUsage     *float64 `json:"cpu_usage,omitempty"`    // Percent of cores in use
+	Availability *float64 `json:"availability,omitempty"` // Percent of nodes online
+}
+
+// MountGrowth is the change in used space of a mount over a period
+type MountGrowth struct {
+	Host         string  `json:"host"`
+	MountPoint   string  `json:"mount_point"`
+	StartBytes   int64   `json:"start_bytes"`
+	EndBytes     int64   `json:"end_bytes"`
+	GrowthBytes  int64   `json:"growth_bytes"`
+	UsagePercent float64 `json:"usage_percent"` // At the end of the period
+}
+
+// ClusterReport is the section of a monthly report about one cluster
+type ClusterReport struct {
+	Cluster     string             `json:"cluster"`
+	Type        ClusterType        `json:"type"`
+	Utilization ClusterUtilization `json:"utilization"`
+	Jobs        int                `json:"jobs"`
+	CoreHours   float64            `json:"core_hours"`
+	DiskGrowth  []MountGrowth      `json:"disk_growth"`
+}
+
+// MonthlyReport summarizes cluster usage over one month
+type MonthlyReport struct {
+	Period            string          `json:"period"` // YYYY-MM
+	GeneratedAt       time.Time       `json:"generated_at"`
+	Clusters          []ClusterReport `json:"clusters"`
+	TopUsers          []UserCPUPeriod `json:"top_users"`
+	LowEfficiencyJobs []Job           `json:"low_efficiency_jobs"`
+}
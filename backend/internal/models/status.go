@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Cluster states on the public status page, from best to worst
+const (
+	ClusterStateOperational   = "operational"
+	ClusterStateMaintenance   = "maintenance"
+	ClusterStateDegraded      = "degraded"
+	ClusterStatePartialOutage = "partial_outage"
+	ClusterStateMajorOutage   = "major_outage"
+)
+
+// Announcement is scheduled maintenance or another notice shown on the
+// public status page between its start and end
+type Announcement struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Clusters  []string  `json:"clusters"` // Empty for every cluster
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ClusterState is the operational state of one cluster
+type ClusterState struct {
+	Cluster          string `json:"cluster"`
+	Type             string `json:"type"`
+	Description      string `json:"description,omitempty"`
+	State            string `json:"state"`
+	NodesOnline      int    `json:"nodes_online"`
+	NodesOffline     int    `json:"nodes_offline"`
+	NodesMaintenance int    `json:"nodes_maintenance"`
+	Incidents        []int  `json:"incidents"` // IDs of the open incidents
+}
+
+// StatusPage is the public view of the clusters, incidents and announcements
+type StatusPage struct {
+	Title         string              `json:"title"`
+	State         string              `json:"state"` // Worst cluster state
+	Clusters      []ClusterState      `json:"clusters"`
+	Incidents     []Incident          `json:"incidents"`     // Open and recently resolved
+	Announcements []Announcement      `json:"announcements"` // Current and upcoming
+	Maintenance   []MaintenanceWindow `json:"maintenance"`   // Current and upcoming
+	GeneratedAt   time.Time           `json:"generated_at"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// API token kinds
+const (
+	TokenKindPersonal = "personal" // Acts as its owner
+	TokenKindService  = "service"  // Acts as itself, for collectors and integrations
+)
+
+// API token scopes
+const (
+	ScopeRead   = "read"   // GET requests, with at most the viewer role
+	ScopeIngest = "ingest" // Pushing collector data
+	ScopeAdmin  = "admin"  // Every request the owner's roles allow
+)
+
+// APIToken is a bearer token for non-interactive API access. The secret is
+// only shown when the token is created.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"`
+	Owner      string     `json:"owner"` // User who created the token; personal tokens act as this user
+	Scopes     []string   `json:"scopes"`
+	Prefix     string     `json:"prefix"` // Start of the secret, to recognise the token
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the token was granted the scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the token can be used at t
+func (t *APIToken) Active(at time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || at.Before(*t.ExpiresAt))
+}
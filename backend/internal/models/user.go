@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// User sources
+const (
+	UserSourceLocal = "local" // Password stored by the server
+	UserSourceLDAP  = "ldap"  // Bound against the LDAP directory
+	UserSourceOIDC  = "oidc"  // Signed in with the OpenID Connect provider
+	UserSourceToken = "token" // Service API token
+	UserSourceCert  = "cert"  // TLS client certificate of a collector
+)
+
+// Roles, from least to most privileged
+const (
+	RoleViewer       = "viewer"
+	RoleClusterAdmin = "cluster-admin"
+	RoleAdmin        = "admin"
+)
+
+// RoleGrant gives a user a role on one cluster, or on every cluster when
+// Cluster is empty
+type RoleGrant struct {
+	Role    string `json:"role"`
+	Cluster string `json:"cluster,omitempty"`
+}
+
+// roleRank orders the roles by privilege; unknown roles rank 0
+func roleRank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleClusterAdmin:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// User is a person or service that can sign in to the API
+type User struct {
+	Username  string      `json:"username"`
+	Name      string      `json:"name,omitempty"`
+	Email     string      `json:"email,omitempty"`
+	Source    string      `json:"source"`
+	Groups    []string    `json:"groups,omitempty"` // Directory groups at the last login
+	Roles     []RoleGrant `json:"roles"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	LastLogin *time.Time  `json:"last_login,omitempty"`
+}
+
+// HasRole reports whether the user holds role, or a more privileged one, on
+// the cluster. An empty cluster asks for a grant covering every cluster.
+func (u *User) HasRole(role, cluster string) bool {
+	for _, g := range u.Roles {
+		if roleRank(g.Role) >= roleRank(role) && (g.Cluster == "" || g.Cluster == cluster) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAnyRole reports whether the user holds role, or a more privileged one,
+// on at least one cluster
+func (u *User) HasAnyRole(role string) bool {
+	for _, g := range u.Roles {
+		if roleRank(g.Role) >= roleRank(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Session is a signed-in user. The session cookie carries its ID.
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
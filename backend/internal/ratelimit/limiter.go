@@ -0,0 +1,208 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/auth"
+)
+
+// vars holds the counters of every limiter, served by Counters
+var vars = expvar.NewMap("ratelimit")
+
+// sweepInterval is how often buckets that have refilled are dropped
+const sweepInterval = time.Minute
+
+// Limit is a token bucket refilled at Rate requests per second and holding
+// at most Burst requests. A zero Rate disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit such as "10/s", "600/m,100" or "off". The
+// optional number after the comma is the burst, which defaults to the
+// number of requests per unit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" || s == "0" {
+		return Limit{}, nil
+	}
+
+	spec, burstText, hasBurst := strings.Cut(s, ",")
+	countText, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/unit[,burst]", s)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(countText))
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: request count must be a positive integer", s)
+	}
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", s)
+	}
+
+	limit := Limit{Rate: float64(count) / per.Seconds(), Burst: count}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(strings.TrimSpace(burstText))
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	return limit, nil
+}
+
+// bucket is the token bucket of one client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter limits the request rate of each client of a route group. Clients
+// are told apart by API token, then user, then IP address.
+type Limiter struct {
+	name    string
+	limit   Limit
+	buckets map[string]*bucket
+	swept   time.Time
+	allowed expvar.Int
+	limited expvar.Int
+	mu      sync.Mutex
+}
+
+// New creates a limiter and publishes its counters under name
+func New(name string, limit Limit) *Limiter {
+	l := &Limiter{name: name, limit: limit, buckets: make(map[string]*bucket), swept: time.Now()}
+
+	counters := new(expvar.Map)
+	counters.Set("allowed", &l.allowed)
+	counters.Set("limited", &l.limited)
+	counters.Set("clients", expvar.Func(func() interface{} {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.buckets)
+	}))
+	counters.Set("rate", expvar.Func(func() interface{} {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.limit.Rate
+	}))
+	vars.Set(name, counters)
+	return l
+}
+
+// Counters serves the counters of every limiter as JSON under "ratelimit",
+// in the expvar format. Unlike expvar.Handler it leaves out the command line
+// and memory statistics of the process.
+func Counters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\"ratelimit\": %s}\n", vars.String())
+}
+
+// SetLimit changes the limit. Buckets are kept, holding at most the new
+// burst, so clients do not get a fresh burst from a reload.
+func (l *Limiter) SetLimit(limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	if limit.Rate <= 0 {
+		l.buckets = make(map[string]*bucket)
+		return
+	}
+	for _, b := range l.buckets {
+		b.tokens = math.Min(b.tokens, float64(limit.Burst))
+	}
+}
+
+// Allow takes a token from the bucket of key. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit.Rate <= 0 {
+		l.allowed.Add(1)
+		return true, 0
+	}
+
+	now := time.Now()
+	if now.Sub(l.swept) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		l.limited.Add(1)
+		return false, time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	l.allowed.Add(1)
+	return true, 0
+}
+
+// sweep drops the buckets that have refilled, which behave like new ones
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// Handler rejects requests of clients over the limit with 429 and a
+// Retry-After header. After authentication tokens and users get their own
+// buckets wherever they connect from; before it every client is counted by
+// the IP address that TrustedProxies resolved.
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.Allow(clientKey(r))
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		seconds := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "Too many requests",
+			"message": fmt.Sprintf("Rate limit of %s exceeded; retry in %d seconds", l.name, seconds),
+		})
+	})
+}
+
+// clientKey identifies the client of a request
+func clientKey(r *http.Request) string {
+	if token, ok := auth.TokenFromContext(r.Context()); ok {
+		return "token:" + token.ID
+	}
+	if user, ok := auth.UserFromContext(r.Context()); ok && user.Username != auth.Anonymous.Username {
+		return "user:" + user.Username
+	}
+	return "ip:" + auth.ClientIP(r)
+}
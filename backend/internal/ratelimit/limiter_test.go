@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "", want: Limit{}},
+		{in: "off", want: Limit{}},
+		{in: "0", want: Limit{}},
+		{in: "10/s", want: Limit{Rate: 10, Burst: 10}},
+		{in: "600/m", want: Limit{Rate: 10, Burst: 600}},
+		{in: " 600/m, 100 ", want: Limit{Rate: 10, Burst: 100}},
+		{in: "3600/h,5", want: Limit{Rate: 1, Burst: 5}},
+		{in: "10", wantErr: true},
+		{in: "-1/s", wantErr: true},
+		{in: "0/s", wantErr: true},
+		{in: "10/d", wantErr: true},
+		{in: "10/s,0", wantErr: true},
+		{in: "10/s,x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseLimit(%q) = %+v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLimit(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	l := New(t.Name(), Limit{Rate: 1, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within the burst was limited", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("request over the burst was allowed")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("wait = %v, want up to a second", wait)
+	}
+	// Every client has its own bucket
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("another client was limited")
+	}
+
+	// The bucket refills at the rate
+	l.buckets["a"].last = l.buckets["a"].last.Add(-2 * time.Second)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d after refilling was limited", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("refill exceeded the elapsed time")
+	}
+	if l.allowed.Value() != 6 || l.limited.Value() != 2 {
+		t.Errorf("counters allowed %d, limited %d", l.allowed.Value(), l.limited.Value())
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	l := New(t.Name(), Limit{Rate: 1, Burst: 10})
+	l.Allow("a")
+
+	// A smaller burst caps the tokens left
+	l.SetLimit(Limit{Rate: 1, Burst: 2})
+	if got := l.buckets["a"].tokens; got != 2 {
+		t.Errorf("tokens after lowering the burst = %v, want 2", got)
+	}
+
+	l.SetLimit(Limit{})
+	if len(l.buckets) != 0 {
+		t.Errorf("buckets kept after disabling: %d", len(l.buckets))
+	}
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatal("disabled limiter limited a request")
+		}
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l := New(t.Name(), Limit{Rate: 1, Burst: 2})
+	l.Allow("idle")
+	l.Allow("busy")
+	l.Allow("busy")
+
+	now := l.buckets["idle"].last.Add(time.Second)
+	l.sweep(now)
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("refilled bucket was kept")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("bucket still refilling was dropped")
+	}
+}
+
+func TestLimiterHandler(t *testing.T) {
+	l := New(t.Name(), Limit{Rate: 0.1, Burst: 1})
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	do := func(remote string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("192.0.2.1:1234"); w.Code != http.StatusNoContent {
+		t.Fatalf("first request: status %d", w.Code)
+	}
+	w := do("192.0.2.1:1235")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q, want 10", got)
+	}
+	if w := do("192.0.2.2:1234"); w.Code != http.StatusNoContent {
+		t.Errorf("request from another address: status %d", w.Code)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	l := New(t.Name(), Limit{Rate: 1, Burst: 1})
+	l.Allow("a")
+	l.Allow("a")
+
+	w := httptest.NewRecorder()
+	Counters(w, httptest.NewRequest(http.MethodGet, "/api/v1/debug/vars", nil))
+	var body map[string]map[string]struct {
+		Allowed int     `json:"allowed"`
+		Limited int     `json:"limited"`
+		Clients int     `json:"clients"`
+		Rate    float64 `json:"rate"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// Only the limiters are served, not memstats or cmdline
+	if len(body) != 1 {
+		t.Errorf("variables = %v, want ratelimit only", body)
+	}
+	got, ok := body["ratelimit"][t.Name()]
+	if !ok || got.Allowed != 1 || got.Limited != 1 || got.Clients != 1 || got.Rate != 1 {
+		t.Errorf("counters = %+v", got)
+	}
+}
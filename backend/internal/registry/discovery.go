@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// PBSDiscoverer discovers compute clusters from the PBS queue list.
+// Every execution queue named "<QueuePrefix><cluster>" is one cluster,
+// mirroring `qstat -Q | grep work | cut -c 6-` in cluschk.sh.
+type PBSDiscoverer struct {
+	QstatPath    string // Path to qstat
+	QueuePrefix  string // Queue name prefix, e.g. "work_"
+	MasterSuffix string // Appended to the cluster name to get the master host
+}
+
+// NewPBSDiscoverer creates a discoverer with the conventional queue layout
+func NewPBSDiscoverer(qstatPath string) *PBSDiscoverer {
+	return &PBSDiscoverer{
+		QstatPath:    qstatPath,
+		QueuePrefix:  "work_",
+		MasterSuffix: "00",
+	}
+}
+
+// Discover runs `qstat -Q` and returns one compute cluster per queue
+func (d *PBSDiscoverer) Discover(ctx context.Context) ([]models.ClusterInfo, error) {
+	out, err := exec.CommandContext(ctx, d.QstatPath, "-Q").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s -Q: %w", d.QstatPath, err)
+	}
+
+	return d.parse(out), nil
+}
+
+// parse extracts clusters from `qstat -Q` output
+func (d *PBSDiscoverer) parse(out []byte) []models.ClusterInfo {
+	var clusters []models.ClusterInfo
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		queue := fields[0]
+		name := strings.TrimPrefix(queue, d.QueuePrefix)
+		if name == queue || name == "" || seen[name] || !validName.MatchString(name) {
+			continue
+		}
+		seen[name] = true
+
+		clusters = append(clusters, models.ClusterInfo{
+			Name:       name,
+			Type:       models.ClusterTypeCompute,
+			MasterHost: name + d.MasterSuffix,
+			Queue:      queue,
+		})
+	}
+
+	return clusters
+}
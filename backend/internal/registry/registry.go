@@ -0,0 +1,301 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// storageKey is the storage key holding the registered clusters
+const storageKey = "cluster_registry"
+
+var (
+	ErrClusterNotFound  = errors.New("cluster not found")
+	ErrInvalidCluster   = errors.New("invalid cluster definition")
+	ErrClusterNotManual = errors.New("cluster is not manually registered")
+)
+
+// validName matches the cluster names accepted by the registry. Names are
+// embedded in storage keys, so they are restricted to the characters the
+// JSON storage keeps as-is.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Discoverer lists the clusters currently known to the scheduler
+type Discoverer interface {
+	Discover(ctx context.Context) ([]models.ClusterInfo, error)
+}
+
+// Registry keeps the list of known clusters in storage
+type Registry struct {
+	storage    storage.Storage
+	discoverer Discoverer
+	static     []models.ClusterInfo
+	mu         sync.Mutex
+}
+
+// New creates a registry backed by the given storage. Static clusters (such
+// as file servers that have no scheduler queue) are always kept registered.
+func New(store storage.Storage, discoverer Discoverer, static []models.ClusterInfo) *Registry {
+	return &Registry{
+		storage:    store,
+		discoverer: discoverer,
+		static:     static,
+	}
+}
+
+// DefaultFileServers returns the file servers that cluschk.sh registered
+// alongside the PBS queues
+func DefaultFileServers() []models.ClusterInfo {
+	return []models.ClusterInfo{
+		{Name: "nagara", Type: models.ClusterTypeFileServer, MasterHost: "nagara", Description: "File server"},
+		{Name: "asuka_data", Type: models.ClusterTypeFileServer, MasterHost: "asuka00", Description: "Asuka data server", UserRoots: []string{"/data"}, MountInclude: []string{"/data"}},
+		{Name: "naruko_data", Type: models.ClusterTypeFileServer, MasterHost: "naruko00", Description: "Naruko data server", UserRoots: []string{"/data"}, MountInclude: []string{"/data"}},
+	}
+}
+
+// List returns all registered clusters sorted by name
+func (r *Registry) List() ([]models.ClusterInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.load()
+}
+
+// Names returns the names of all registered clusters
+func (r *Registry) Names() ([]string, error) {
+	clusters, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(clusters))
+	for _, c := range clusters {
+		names = append(names, c.Name)
+	}
+	return names, nil
+}
+
+// Get returns a registered cluster by name
+func (r *Registry) Get(name string) (*models.ClusterInfo, error) {
+	clusters, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range clusters {
+		if clusters[i].Name == name {
+			return &clusters[i], nil
+		}
+	}
+	return nil, ErrClusterNotFound
+}
+
+// Put creates or replaces a cluster. Clusters edited through Put are marked
+// as manual so that discovery no longer overwrites or removes them.
+func (r *Registry) Put(cluster models.ClusterInfo) (*models.ClusterInfo, error) {
+	if err := Validate(cluster); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clusters, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	cluster.Source = models.ClusterSourceManual
+	cluster.UpdatedAt = time.Now().UTC()
+	clusters = upsert(clusters, cluster)
+
+	if err := r.save(clusters); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// Delete removes a manually registered cluster from the registry.
+// Discovered and static clusters would come back with the next Sync, so
+// deleting them fails with ErrClusterNotManual.
+func (r *Registry) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clusters, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	for i := range clusters {
+		if clusters[i].Name == name {
+			if clusters[i].Source != models.ClusterSourceManual {
+				return fmt.Errorf("%w: %s clusters are registered again by the next sync", ErrClusterNotManual, clusters[i].Source)
+			}
+			clusters = append(clusters[:i], clusters[i+1:]...)
+			return r.save(clusters)
+		}
+	}
+	return ErrClusterNotFound
+}
+
+// Sync merges the scheduler's clusters into the registry. Discovered clusters
+// that disappeared from the scheduler are removed; manual entries are kept.
+// When discovery fails, static clusters are still registered and previously
+// discovered clusters are left untouched.
+func (r *Registry) Sync(ctx context.Context) ([]models.ClusterInfo, error) {
+	var discovered []models.ClusterInfo
+	var discoverErr error
+	if r.discoverer != nil {
+		discovered, discoverErr = r.discoverer.Discover(ctx)
+		if discoverErr != nil {
+			discoverErr = fmt.Errorf("failed to discover clusters: %w", discoverErr)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clusters, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	seen := make(map[string]bool)
+	for _, c := range discovered {
+		c.Source = models.ClusterSourceDiscovered
+		seen[c.Name] = true
+		clusters = r.merge(clusters, c, now)
+	}
+	for _, c := range r.static {
+		c.Source = models.ClusterSourceStatic
+		seen[c.Name] = true
+		clusters = r.merge(clusters, c, now)
+	}
+
+	kept := clusters[:0]
+	for _, c := range clusters {
+		switch {
+		case c.Source == models.ClusterSourceManual || seen[c.Name]:
+			kept = append(kept, c)
+		case c.Source == models.ClusterSourceDiscovered && discoverErr != nil:
+			kept = append(kept, c)
+		default:
+			log.Printf("Cluster %s is no longer known to the scheduler, removing it from the registry", c.Name)
+		}
+	}
+
+	if err := r.save(kept); err != nil {
+		return nil, err
+	}
+	return kept, discoverErr
+}
+
+// SetStatic replaces the static clusters. They are registered, and static
+// clusters no longer in the list removed, on the next Sync.
+func (r *Registry) SetStatic(static []models.ClusterInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.static = static
+}
+
+// Run synchronizes the registry at the given interval until ctx is done.
+// The first synchronization happens after one interval; callers run Sync
+// themselves when they need the registry populated at startup.
+func (r *Registry) Run(ctx context.Context, interval *schedule.Interval) {
+	last := time.Now()
+	for {
+		if !interval.Wait(ctx, last) {
+			return
+		}
+		last = time.Now()
+
+		if clusters, err := r.Sync(ctx); err != nil {
+			log.Printf("Cluster registry sync failed: %v", err)
+		} else {
+			log.Printf("Cluster registry synchronized: %d clusters", len(clusters))
+		}
+	}
+}
+
+// merge adds or refreshes a non-manual cluster
+func (r *Registry) merge(clusters []models.ClusterInfo, c models.ClusterInfo, now time.Time) []models.ClusterInfo {
+	for i := range clusters {
+		if clusters[i].Name != c.Name {
+			continue
+		}
+		if clusters[i].Source == models.ClusterSourceManual {
+			return clusters
+		}
+		if c.Description == "" {
+			c.Description = clusters[i].Description
+		}
+		c.UpdatedAt = clusters[i].UpdatedAt
+		if !reflect.DeepEqual(clusters[i], c) {
+			c.UpdatedAt = now
+		}
+		clusters[i] = c
+		return clusters
+	}
+
+	log.Printf("Registering new cluster %s (%s)", c.Name, c.Type)
+	c.UpdatedAt = now
+	return append(clusters, c)
+}
+
+// load reads the registered clusters from storage
+func (r *Registry) load() ([]models.ClusterInfo, error) {
+	clusters := []models.ClusterInfo{}
+	err := storage.GetData(r.storage, storageKey, &clusters)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load cluster registry: %w", err)
+	}
+	return clusters, nil
+}
+
+// save writes the registered clusters to storage
+func (r *Registry) save(clusters []models.ClusterInfo) error {
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	return storage.SetData(r.storage, storageKey, clusters)
+}
+
+// upsert replaces the cluster with the same name or appends it
+func upsert(clusters []models.ClusterInfo, c models.ClusterInfo) []models.ClusterInfo {
+	for i := range clusters {
+		if clusters[i].Name == c.Name {
+			clusters[i] = c
+			return clusters
+		}
+	}
+	return append(clusters, c)
+}
+
+// Validate checks a cluster definition submitted through the admin API or
+// configured as a static cluster
+func Validate(c models.ClusterInfo) error {
+	if !validName.MatchString(c.Name) {
+		return fmt.Errorf("%w: name must match %s", ErrInvalidCluster, validName.String())
+	}
+	switch c.Type {
+	case models.ClusterTypeCompute, models.ClusterTypeFileServer:
+	default:
+		return fmt.Errorf("%w: type must be %q or %q", ErrInvalidCluster, models.ClusterTypeCompute, models.ClusterTypeFileServer)
+	}
+	if c.MasterHost == "" {
+		return fmt.Errorf("%w: master_host is required", ErrInvalidCluster)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/config"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// Reloader reloads the configuration and swaps what changed into the running
+// server. Changed keys that cannot be swapped are reported as needing a
+// restart.
+type Reloader struct {
+	Load       func() (*config.Config, error) // Reads the configuration
+	History    int                            // Reloads kept for the API
+	apply      func(next *config.Config, changed []string)
+	reloadable []string
+	started    *config.Config // Configuration the server started with
+	current    *config.Config // Configuration last applied
+	results    []models.ConfigReload
+	mu         sync.Mutex
+}
+
+// New creates a reloader for a server started with cfg. apply swaps a new
+// configuration into the running subsystems; it is called with the changed
+// keys that match one of the reloadable patterns, such as "alerts.*".
+func New(cfg *config.Config, apply func(next *config.Config, changed []string), reloadable ...string) *Reloader {
+	return &Reloader{
+		Load:       config.Load,
+		History:    20,
+		apply:      apply,
+		reloadable: reloadable,
+		started:    cfg,
+		current:    cfg,
+	}
+}
+
+// Reload loads and validates the configuration and applies the reloadable
+// changes. An invalid configuration is not applied at all.
+func (r *Reloader) Reload(trigger, actor string) models.ConfigReload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := models.ConfigReload{
+		At:              time.Now().UTC(),
+		Trigger:         trigger,
+		Actor:           actor,
+		Applied:         []string{},
+		RestartRequired: []string{},
+	}
+
+	next, err := r.Load()
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		result.Error = err.Error()
+		var invalid config.ValidationError
+		if errors.As(err, &invalid) {
+			for _, fe := range invalid {
+				result.Errors = append(result.Errors, fe.Error())
+			}
+		}
+		log.Printf("Configuration reload (%s) failed, keeping the current configuration: %v", trigger, err)
+		r.record(result)
+		return result
+	}
+
+	for _, key := range config.Diff(r.current, next) {
+		if r.isReloadable(key) {
+			result.Applied = append(result.Applied, key)
+		}
+	}
+	for _, key := range config.Diff(r.started, next) {
+		if !r.isReloadable(key) {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+	if len(result.Applied) > 0 {
+		r.apply(next, result.Applied)
+	}
+	r.current = next
+	result.Success = true
+
+	if len(result.Applied) > 0 {
+		log.Printf("Configuration reloaded (%s): applied %s", trigger, strings.Join(result.Applied, ", "))
+	} else {
+		log.Printf("Configuration reloaded (%s): no changes to apply", trigger)
+	}
+	if len(result.RestartRequired) > 0 {
+		log.Printf("Configuration changes waiting for a restart: %s", strings.Join(result.RestartRequired, ", "))
+	}
+	r.record(result)
+	return result
+}
+
+// Results returns the latest reloads, newest first
+func (r *Reloader) Results() []models.ConfigReload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]models.ConfigReload, len(r.results))
+	copy(results, r.results)
+	return results
+}
+
+// Watch reloads the configuration whenever file changes, which is checked
+// at the given interval, until ctx is done
+func (r *Reloader) Watch(ctx context.Context, file string, interval time.Duration) {
+	last, lastErr := os.Stat(file)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			// Editors may replace the file; report it only once
+			if lastErr == nil {
+				log.Printf("Failed to check configuration file: %v", err)
+			}
+			last, lastErr = nil, err
+			continue
+		}
+		changed := last == nil || !info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size()
+		last, lastErr = info, nil
+		if changed {
+			r.Reload(models.ConfigReloadWatch, "")
+		}
+	}
+}
+
+// record keeps a result for the API
+func (r *Reloader) record(result models.ConfigReload) {
+	r.results = append([]models.ConfigReload{result}, r.results...)
+	if len(r.results) > r.History {
+		r.results = r.results[:r.History]
+	}
+}
+
+// isReloadable reports whether a key can change while the server runs
+func (r *Reloader) isReloadable(key string) bool {
+	for _, pattern := range r.reloadable {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/config"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// load returns the default configuration
+func load(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return cfg
+}
+
+func TestReload(t *testing.T) {
+	started := load(t)
+	var applied [][]string
+	r := New(started, func(next *config.Config, changed []string) {
+		applied = append(applied, changed)
+	}, "alerts.*", "rate_limits.*")
+
+	next := *started
+	next.Alerts.Interval = time.Minute
+	next.Server.Port = "9090"
+	r.Load = func() (*config.Config, error) {
+		cfg := next
+		return &cfg, nil
+	}
+
+	result := r.Reload(models.ConfigReloadSignal, "")
+	if !result.Success || !reflect.DeepEqual(result.Applied, []string{"alerts.interval"}) || !reflect.DeepEqual(result.RestartRequired, []string{"server.port"}) {
+		t.Errorf("first reload = %+v", result)
+	}
+
+	// Applied changes are not applied again, but a restart is still needed
+	next.RateLimits.Login = "5/m"
+	result = r.Reload(models.ConfigReloadAPI, "ada")
+	if !result.Success || !reflect.DeepEqual(result.Applied, []string{"rate_limits.login"}) || !reflect.DeepEqual(result.RestartRequired, []string{"server.port"}) {
+		t.Errorf("second reload = %+v", result)
+	}
+	if want := [][]string{{"alerts.interval"}, {"rate_limits.login"}}; !reflect.DeepEqual(applied, want) {
+		t.Errorf("applied = %v, want %v", applied, want)
+	}
+
+	// An invalid configuration is not applied at all
+	next.Alerts.Interval = 0
+	next.RateLimits.Login = "often"
+	result = r.Reload(models.ConfigReloadAPI, "ada")
+	if result.Success || len(result.Errors) != 2 || len(result.Applied) != 0 {
+		t.Errorf("invalid reload = %+v", result)
+	}
+	r.Load = func() (*config.Config, error) { return nil, errors.New("unreadable") }
+	if result = r.Reload(models.ConfigReloadAPI, "ada"); result.Success || result.Error != "unreadable" {
+		t.Errorf("failed load = %+v", result)
+	}
+	if len(applied) != 2 {
+		t.Errorf("failed reloads applied %v", applied[2:])
+	}
+
+	results := r.Results()
+	if len(results) != 4 || results[0].Error != "unreadable" || results[3].Trigger != models.ConfigReloadSignal {
+		t.Errorf("results = %+v, want the four reloads newest first", results)
+	}
+}
+
+func TestResultsHistory(t *testing.T) {
+	r := New(load(t), func(*config.Config, []string) {})
+	r.History = 2
+	r.Load = func() (*config.Config, error) { return nil, errors.New("unreadable") }
+	for i := 0; i < 3; i++ {
+		r.Reload(models.ConfigReloadAPI, "")
+	}
+	if n := len(r.Results()); n != 2 {
+		t.Errorf("results kept = %d, want 2", n)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	reloaded := make(chan string, 10)
+	r := New(load(t), func(*config.Config, []string) {})
+	r.Load = func() (*config.Config, error) {
+		reloaded <- "reload"
+		return nil, errors.New("stop")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, file, 10*time.Millisecond)
+
+	select {
+	case <-reloaded:
+		t.Fatal("reloaded an unchanged file")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := os.WriteFile(file, []byte("alerts: {}\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("changed file was not reloaded")
+	}
+	if results := r.Results(); len(results) != 1 || results[0].Trigger != models.ConfigReloadWatch {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,169 @@
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"text/template"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// Report output formats
+const (
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+)
+
+// funcs are the helpers shared by the report templates
+var funcs = map[string]interface{}{
+	"bytes":   formatBytes,
+	"percent": formatPercent,
+	"number":  func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"hours":   func(seconds float64) string { return fmt.Sprintf("%.1f", seconds/3600) },
+	"date":    func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+	"ratio":   func(v float64) string { return fmt.Sprintf("%.0f%%", v*100) },
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(funcs).Parse(`# Cluster report {{.Period}}
+
+Generated {{date .GeneratedAt}}
+
+## Utilization
+
+| Cluster | Days | Load average | PBS usage | CPU usage | Availability | Jobs | Core hours |
+|---------|-----:|-------------:|----------:|----------:|-------------:|-----:|-----------:|
+{{- range .Clusters}}{{if eq .Type "compute"}}
+| {{.Cluster}} | {{.Utilization.Days}} | {{number .Utilization.LoadAverage}} | {{percent .Utilization.PBSUsage}} | {{percent .Utilization.CPUUsage}} | {{percent .Utilization.Availability}} | {{.Jobs}} | {{number .CoreHours}} |
+{{- end}}{{end}}
+
+## Top users
+
+| User | CPU hours | Active days |
+|------|----------:|------------:|
+{{- range .TopUsers}}
+| {{.User}} | {{number .CPUHours}} | {{.ActiveDays}} |
+{{- else}}
+| - | - | - |
+{{- end}}
+
+## Disk growth
+{{range .Clusters}}{{if .DiskGrowth}}
+### {{.Cluster}}
+
+| Host | Mount | Start | End | Growth | Usage |
+|------|-------|------:|----:|-------:|------:|
+{{- range .DiskGrowth}}
+| {{.Host}} | {{.MountPoint}} | {{bytes .StartBytes}} | {{bytes .EndBytes}} | {{bytes .GrowthBytes}} | {{percent .UsagePercent}} |
+{{- end}}
+{{end}}{{end}}
+## Low-efficiency jobs
+
+| Job | User | Cluster | Cores | Walltime (h) | Efficiency |
+|-----|------|---------|------:|-------------:|-----------:|
+{{- range .LowEfficiencyJobs}}
+| {{.ID}} | {{.User}} | {{.Cluster}} | {{.NCPUs}} | {{hours .WalltimeSeconds}} | {{ratio .Efficiency}} |
+{{- else}}
+| - | - | - | - | - | - |
+{{- end}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Cluster report {{.Period}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Cluster report {{.Period}}</h1>
+<p>Generated {{date .GeneratedAt}}</p>
+
+<h2>Utilization</h2>
+<table>
+<tr><th>Cluster</th><th>Days</th><th>Load average</th><th>PBS usage</th><th>CPU usage</th><th>Availability</th><th>Jobs</th><th>Core hours</th></tr>
+{{- range .Clusters}}{{if eq .Type "compute"}}
+<tr><td>{{.Cluster}}</td><td class="num">{{.Utilization.Days}}</td><td class="num">{{number .Utilization.LoadAverage}}</td><td class="num">{{percent .Utilization.PBSUsage}}</td><td class="num">{{percent .Utilization.CPUUsage}}</td><td class="num">{{percent .Utilization.Availability}}</td><td class="num">{{.Jobs}}</td><td class="num">{{number .CoreHours}}</td></tr>
+{{- end}}{{end}}
+</table>
+
+<h2>Top users</h2>
+<table>
+<tr><th>User</th><th>CPU hours</th><th>Active days</th></tr>
+{{- range .TopUsers}}
+<tr><td>{{.User}}</td><td class="num">{{number .CPUHours}}</td><td class="num">{{.ActiveDays}}</td></tr>
+{{- else}}
+<tr><td colspan="3">No accounting data</td></tr>
+{{- end}}
+</table>
+
+<h2>Disk growth</h2>
+{{- range .Clusters}}{{if .DiskGrowth}}
+<h3>{{.Cluster}}</h3>
+<table>
+<tr><th>Host</th><th>Mount</th><th>Start</th><th>End</th><th>Growth</th><th>Usage</th></tr>
+{{- range .DiskGrowth}}
+<tr><td>{{.Host}}</td><td>{{.MountPoint}}</td><td class="num">{{bytes .StartBytes}}</td><td class="num">{{bytes .EndBytes}}</td><td class="num">{{bytes .GrowthBytes}}</td><td class="num">{{percent .UsagePercent}}</td></tr>
+{{- end}}
+</table>
+{{- end}}{{end}}
+
+<h2>Low-efficiency jobs</h2>
+<table>
+<tr><th>Job</th><th>User</th><th>Cluster</th><th>Cores</th><th>Walltime (h)</th><th>Efficiency</th></tr>
+{{- range .LowEfficiencyJobs}}
+<tr><td>{{.ID}}</td><td>{{.User}}</td><td>{{.Cluster}}</td><td class="num">{{.NCPUs}}</td><td class="num">{{hours .WalltimeSeconds}}</td><td class="num">{{ratio .Efficiency}}</td></tr>
+{{- else}}
+<tr><td colspan="6">None</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// RenderMarkdown writes a report as Markdown
+func RenderMarkdown(w io.Writer, report *models.MonthlyReport) error {
+	return markdownTemplate.Execute(w, report)
+}
+
+// RenderHTML writes a report as a standalone HTML page
+func RenderHTML(w io.Writer, report *models.MonthlyReport) error {
+	return htmlTemplate.Execute(w, report)
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(b int64) string {
+	v := float64(b)
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := 0
+	for math.Abs(v) >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", b)
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
+
+// formatPercent formats a percentage, or "-" for a missing value
+func formatPercent(v interface{}) string {
+	switch p := v.(type) {
+	case float64:
+		return fmt.Sprintf("%.1f%%", p)
+	case *float64:
+		if p != nil {
+			return fmt.Sprintf("%.1f%%", *p)
+		}
+	}
+	return "-"
+}
@@ -0,0 +1,44 @@
+package report
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+		{-2 << 20, "-2.0 MiB"},
+		{3 << 50, "3.0 PiB"},
+		{1 << 62, "4096.0 PiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.in); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	p := 12.345
+	var missing *float64
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{99.96, "100.0%"},
+		{&p, "12.3%"},
+		{missing, "-"},
+		{nil, "-"},
+		{"12", "-"},
+	}
+	for _, tt := range tests {
+		if got := formatPercent(tt.in); got != tt.want {
+			t.Errorf("formatPercent(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
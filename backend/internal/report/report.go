@@ -0,0 +1,155 @@
+package report
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/accounting"
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/jobs"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/utilization"
+)
+
+// ErrInvalidPeriod is returned for periods that are not formatted as YYYY-MM
+var ErrInvalidPeriod = errors.New("invalid period, expected YYYY-MM")
+
+// Generator compiles monthly reports from the stored utilization, job,
+// accounting and filesystem history
+type Generator struct {
+	registry             *registry.Registry
+	utilization          *utilization.Recorder
+	jobs                 *jobs.Tracker
+	accounting           *accounting.Store
+	filesystems          *collector.FilesystemStore
+	TopUsers             int           // Users listed in the report
+	LowEfficiency        float64       // Jobs below this efficiency are listed
+	LowEfficiencyMinTime time.Duration // Minimum walltime of a listed job
+}
+
+// NewGenerator creates a report generator
+func NewGenerator(reg *registry.Registry, util *utilization.Recorder, tracker *jobs.Tracker, acct *accounting.Store, filesystems *collector.FilesystemStore) *Generator {
+	return &Generator{
+		registry:             reg,
+		utilization:          util,
+		jobs:                 tracker,
+		accounting:           acct,
+		filesystems:          filesystems,
+		TopUsers:             10,
+		LowEfficiency:        0.4,
+		LowEfficiencyMinTime: 12 * time.Hour,
+	}
+}
+
+// Generate compiles the report of a month
+func (g *Generator) Generate(period string) (*models.MonthlyReport, error) {
+	start, err := time.Parse("2006-01", period)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	end := start.AddDate(0, 1, 0)
+
+	clusters, err := g.registry.List()
+	if err != nil {
+		return nil, err
+	}
+	utilizations, err := g.utilization.Month(period)
+	if err != nil {
+		return nil, err
+	}
+	monthJobs, err := g.jobs.Month(period)
+	if err != nil {
+		return nil, err
+	}
+	users, err := g.accounting.Period(period)
+	if err != nil {
+		return nil, err
+	}
+
+	byCluster := make(map[string]models.ClusterUtilization, len(utilizations))
+	for _, u := range utilizations {
+		byCluster[u.Cluster] = u
+	}
+
+	report := &models.MonthlyReport{
+		Period:            period,
+		GeneratedAt:       time.Now().UTC(),
+		Clusters:          make([]models.ClusterReport, 0, len(clusters)),
+		TopUsers:          users,
+		LowEfficiencyJobs: jobs.LowEfficiency(monthJobs, g.LowEfficiency, g.LowEfficiencyMinTime),
+	}
+	if g.TopUsers > 0 && len(report.TopUsers) > g.TopUsers {
+		report.TopUsers = report.TopUsers[:g.TopUsers]
+	}
+
+	for _, c := range clusters {
+		section := models.ClusterReport{Cluster: c.Name, Type: c.Type, Utilization: byCluster[c.Name]}
+		section.Utilization.Cluster = c.Name
+		for _, job := range monthJobs {
+			if job.Cluster == c.Name {
+				section.Jobs++
+				section.CoreHours += job.CoreHours()
+			}
+		}
+
+		section.DiskGrowth, err = g.diskGrowth(c.Name, start, end)
+		if err != nil {
+			return nil, err
+		}
+		report.Clusters = append(report.Clusters, section)
+	}
+	return report, nil
+}
+
+// diskGrowth compares the first and last capacity sample of each mount
+// within [start, end), largest growth first
+func (g *Generator) diskGrowth(cluster string, start, end time.Time) ([]models.MountGrowth, error) {
+	history, err := g.filesystems.History(cluster, start)
+	if err != nil {
+		return nil, err
+	}
+
+	type mount struct{ host, path string }
+	first := make(map[mount]models.FilesystemSample)
+	last := make(map[mount]models.FilesystemSample)
+	for _, s := range history {
+		if !s.Time.Before(end) {
+			continue
+		}
+		key := mount{s.Host, s.MountPoint}
+		if f, ok := first[key]; !ok || s.Time.Before(f.Time) {
+			first[key] = s
+		}
+		if l, ok := last[key]; !ok || s.Time.After(l.Time) {
+			last[key] = s
+		}
+	}
+
+	growth := make([]models.MountGrowth, 0, len(first))
+	for key, f := range first {
+		l := last[key]
+		m := models.MountGrowth{
+			Host:        key.host,
+			MountPoint:  key.path,
+			StartBytes:  f.UsedBytes,
+			EndBytes:    l.UsedBytes,
+			GrowthBytes: l.UsedBytes - f.UsedBytes,
+		}
+		if l.UsableBytes > 0 {
+			m.UsagePercent = float64(l.UsedBytes) / float64(l.UsableBytes) * 100
+		}
+		growth = append(growth, m)
+	}
+	sort.Slice(growth, func(i, j int) bool {
+		if growth[i].GrowthBytes != growth[j].GrowthBytes {
+			return growth[i].GrowthBytes > growth[j].GrowthBytes
+		}
+		if growth[i].Host != growth[j].Host {
+			return growth[i].Host < growth[j].Host
+		}
+		return growth[i].MountPoint < growth[j].MountPoint
+	})
+	return growth, nil
+}
@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Interval is how often a background job runs. It can be changed while the
+// job runs, for example by a configuration reload; a job waiting for its
+// next run then waits for the new interval instead.
+type Interval struct {
+	d       time.Duration
+	changed chan struct{}
+	mu      sync.Mutex
+}
+
+// New creates an interval
+func New(d time.Duration) *Interval {
+	return &Interval{d: d, changed: make(chan struct{})}
+}
+
+// Get returns the current interval
+func (i *Interval) Get() time.Duration {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.d
+}
+
+// Set changes the interval, waking up the jobs waiting for their next run
+func (i *Interval) Set(d time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if d == i.d {
+		return
+	}
+	i.d = d
+	close(i.changed)
+	i.changed = make(chan struct{})
+}
+
+// Wait waits until one interval has passed since last and reports whether
+// it has. It returns false when ctx is done first.
+func (i *Interval) Wait(ctx context.Context, last time.Time) bool {
+	for {
+		i.mu.Lock()
+		d, changed := i.d, i.changed
+		i.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(last.Add(d)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+			return true
+		case <-changed:
+			timer.Stop()
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package statuspage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// announcementsKey is the storage key holding the announcements
+const announcementsKey = "status_announcements"
+
+var (
+	// ErrAnnouncementNotFound is returned for unknown announcement IDs
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+	// ErrInvalidAnnouncement is returned for announcements that fail validation
+	ErrInvalidAnnouncement = errors.New("invalid announcement")
+)
+
+// announcementState is the persisted announcement list
+type announcementState struct {
+	NextID        int                   `json:"next_id"`
+	Announcements []models.Announcement `json:"announcements"`
+}
+
+// Announcements stores the maintenance announcements and notices shown on
+// the status page
+type Announcements struct {
+	storage storage.Storage
+	mu      sync.Mutex
+}
+
+// NewAnnouncements creates an announcement store
+func NewAnnouncements(store storage.Storage) *Announcements {
+	return &Announcements{storage: store}
+}
+
+// List returns every announcement ordered by start time
+func (a *Announcements) List() ([]models.Announcement, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	st, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+	return st.Announcements, nil
+}
+
+// Upcoming returns the announcements that have not ended at now, ordered by
+// start time
+func (a *Announcements) Upcoming(now time.Time) ([]models.Announcement, error) {
+	all, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.Announcement{}
+	for _, ann := range all {
+		if ann.EndsAt.After(now) {
+			result = append(result, ann)
+		}
+	}
+	return result, nil
+}
+
+// Get returns one announcement
+func (a *Announcements) Get(id int) (*models.Announcement, error) {
+	all, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, ann := range all {
+		if ann.ID == id {
+			return &ann, nil
+		}
+	}
+	return nil, ErrAnnouncementNotFound
+}
+
+// Create adds an announcement
+func (a *Announcements) Create(ann models.Announcement) (*models.Announcement, error) {
+	if err := prepare(&ann); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	st, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	st.NextID++
+	ann.ID = st.NextID
+	ann.CreatedAt = now
+	ann.UpdatedAt = now
+	st.Announcements = append(st.Announcements, ann)
+
+	if err := a.save(st); err != nil {
+		return nil, err
+	}
+	return &ann, nil
+}
+
+// Put replaces the title, message, clusters and schedule of an announcement
+func (a *Announcements) Put(id int, ann models.Announcement) (*models.Announcement, error) {
+	if err := prepare(&ann); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	st, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range st.Announcements {
+		if st.Announcements[i].ID != id {
+			continue
+		}
+		ann.ID = id
+		ann.CreatedAt = st.Announcements[i].CreatedAt
+		ann.UpdatedAt = time.Now().UTC()
+		st.Announcements[i] = ann
+		if err := a.save(st); err != nil {
+			return nil, err
+		}
+		return &ann, nil
+	}
+	return nil, ErrAnnouncementNotFound
+}
+
+// Delete removes an announcement
+func (a *Announcements) Delete(id int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	st, err := a.load()
+	if err != nil {
+		return err
+	}
+	for i := range st.Announcements {
+		if st.Announcements[i].ID == id {
+			st.Announcements = append(st.Announcements[:i], st.Announcements[i+1:]...)
+			return a.save(st)
+		}
+	}
+	return ErrAnnouncementNotFound
+}
+
+// prepare validates an announcement and normalizes its fields
+func prepare(ann *models.Announcement) error {
+	ann.Title = strings.TrimSpace(ann.Title)
+	if ann.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidAnnouncement)
+	}
+	if ann.StartsAt.IsZero() || ann.EndsAt.IsZero() {
+		return fmt.Errorf("%w: starts_at and ends_at are required", ErrInvalidAnnouncement)
+	}
+	if !ann.EndsAt.After(ann.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidAnnouncement)
+	}
+	ann.StartsAt = ann.StartsAt.UTC()
+	ann.EndsAt = ann.EndsAt.UTC()
+	ann.Message = strings.TrimSpace(ann.Message)
+
+	clusters := []string{}
+	seen := make(map[string]bool)
+	for _, c := range ann.Clusters {
+		c = strings.TrimSpace(c)
+		if c != "" && !seen[c] {
+			seen[c] = true
+			clusters = append(clusters, c)
+		}
+	}
+	sort.Strings(clusters)
+	ann.Clusters = clusters
+	return nil
+}
+
+// load reads the announcements from storage
+func (a *Announcements) load() (*announcementState, error) {
+	st := &announcementState{}
+	err := storage.GetData(a.storage, announcementsKey, st)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load announcements: %w", err)
+	}
+	if st.Announcements == nil {
+		st.Announcements = []models.Announcement{}
+	}
+	return st, nil
+}
+
+// save writes the announcements ordered by start time
+func (a *Announcements) save(st *announcementState) error {
+	sort.SliceStable(st.Announcements, func(i, j int) bool {
+		return st.Announcements[i].StartsAt.Before(st.Announcements[j].StartsAt)
+	})
+	return storage.SetData(a.storage, announcementsKey, st)
+}
@@ -0,0 +1,204 @@
+package statuspage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/incident"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/maintenance"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+)
+
+// Page builds the public status page. The state of a cluster is the worst of
+// what its nodes and open incidents imply:
+//
+//   - maintenance while an announcement or a maintenance window of the whole
+//     cluster is in effect, or every node is in maintenance
+//   - degraded with any node offline or an open warning incident
+//   - partial outage with at least half the nodes offline or an open
+//     critical incident
+//   - major outage with every node offline
+type Page struct {
+	registry       *registry.Registry
+	inventory      *inventory.Inventory
+	incidents      *incident.Store
+	announcements  *Announcements
+	maintenance    *maintenance.Store
+	Title          string
+	ResolvedWindow time.Duration // How long resolved incidents stay on the page
+	FeedWindow     time.Duration // How far back the feed goes
+}
+
+// NewPage creates a status page builder
+func NewPage(reg *registry.Registry, inv *inventory.Inventory, incidents *incident.Store, announcements *Announcements, windows *maintenance.Store) *Page {
+	return &Page{
+		registry:       reg,
+		inventory:      inv,
+		incidents:      incidents,
+		announcements:  announcements,
+		maintenance:    windows,
+		Title:          "Cluster status",
+		ResolvedWindow: 7 * 24 * time.Hour,
+		FeedWindow:     30 * 24 * time.Hour,
+	}
+}
+
+// Build returns the current status page
+func (p *Page) Build() (*models.StatusPage, error) {
+	now := time.Now().UTC()
+
+	clusters, err := p.registry.List()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := p.inventory.List()
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := p.incidents.List(incident.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	announcements, err := p.announcements.Upcoming(now)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := p.maintenance.List(maintenance.Filter{Upcoming: true})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.StatusPage{
+		Title:         p.Title,
+		State:         models.ClusterStateOperational,
+		Clusters:      []models.ClusterState{},
+		Incidents:     []models.Incident{},
+		Announcements: announcements,
+		Maintenance:   windows,
+		GeneratedAt:   now,
+	}
+
+	var open []models.Incident
+	for _, inc := range incidents {
+		if inc.Status != models.IncidentStatusResolved {
+			open = append(open, inc)
+			page.Incidents = append(page.Incidents, inc)
+		} else if inc.ResolvedAt != nil && now.Sub(*inc.ResolvedAt) <= p.ResolvedWindow {
+			page.Incidents = append(page.Incidents, inc)
+		}
+	}
+
+	for _, c := range clusters {
+		cs := models.ClusterState{
+			Cluster:     c.Name,
+			Type:        string(c.Type),
+			Description: c.Description,
+			Incidents:   []int{},
+		}
+		for _, n := range nodes {
+			if n.Cluster != c.Name {
+				continue
+			}
+			switch n.State {
+			case models.NodeStateOnline:
+				cs.NodesOnline++
+			case models.NodeStateOffline:
+				cs.NodesOffline++
+			case models.NodeStateMaintenance:
+				cs.NodesMaintenance++
+			}
+		}
+		for _, inc := range open {
+			if contains(inc.Clusters, c.Name) {
+				cs.Incidents = append(cs.Incidents, inc.ID)
+			}
+		}
+		cs.State = clusterState(cs, open, announcements, windows, now)
+		if stateRank(cs.State) > stateRank(page.State) {
+			page.State = cs.State
+		}
+		page.Clusters = append(page.Clusters, cs)
+	}
+	sort.Slice(page.Clusters, func(i, j int) bool { return page.Clusters[i].Cluster < page.Clusters[j].Cluster })
+
+	return page, nil
+}
+
+// clusterState derives the state of a cluster from its node counts, the
+// open incidents, the announcements and the maintenance windows
+func clusterState(cs models.ClusterState, open []models.Incident, announcements []models.Announcement, windows []models.MaintenanceWindow, now time.Time) string {
+	state := models.ClusterStateOperational
+	worsen := func(s string) {
+		if stateRank(s) > stateRank(state) {
+			state = s
+		}
+	}
+
+	for _, ann := range announcements {
+		if !ann.StartsAt.After(now) && covers(ann, cs.Cluster) {
+			worsen(models.ClusterStateMaintenance)
+		}
+	}
+	for _, w := range windows {
+		if w.Active(now) && len(w.Nodes) == 0 && w.Cluster == cs.Cluster {
+			worsen(models.ClusterStateMaintenance)
+		}
+	}
+	if cs.NodesMaintenance > 0 && cs.NodesOnline == 0 && cs.NodesOffline == 0 {
+		worsen(models.ClusterStateMaintenance)
+	}
+
+	if counted := cs.NodesOnline + cs.NodesOffline; cs.NodesOffline > 0 {
+		worsen(models.ClusterStateDegraded)
+		if cs.NodesOffline*2 >= counted {
+			worsen(models.ClusterStatePartialOutage)
+		}
+		if cs.NodesOnline == 0 {
+			worsen(models.ClusterStateMajorOutage)
+		}
+	}
+
+	for _, inc := range open {
+		if !contains(inc.Clusters, cs.Cluster) {
+			continue
+		}
+		if inc.Severity == models.SeverityCritical {
+			worsen(models.ClusterStatePartialOutage)
+		} else {
+			worsen(models.ClusterStateDegraded)
+		}
+	}
+	return state
+}
+
+// stateRank orders cluster states from operational to major outage
+func stateRank(state string) int {
+	switch state {
+	case models.ClusterStateMaintenance:
+		return 1
+	case models.ClusterStateDegraded:
+		return 2
+	case models.ClusterStatePartialOutage:
+		return 3
+	case models.ClusterStateMajorOutage:
+		return 4
+	}
+	return 0
+}
+
+// covers reports whether an announcement applies to a cluster
+func covers(ann models.Announcement, cluster string) bool {
+	return len(ann.Clusters) == 0 || contains(ann.Clusters, cluster)
+}
+
+// contains reports whether s holds v
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
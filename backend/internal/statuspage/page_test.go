@@ -0,0 +1,105 @@
+package statuspage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+func TestClusterState(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	nodes := func(online, offline, maintenance int) models.ClusterState {
+		return models.ClusterState{Cluster: "asuka", NodesOnline: online, NodesOffline: offline, NodesMaintenance: maintenance}
+	}
+	incident := func(severity string, clusters ...string) models.Incident {
+		return models.Incident{Severity: severity, Clusters: clusters}
+	}
+
+	tests := []struct {
+		name          string
+		cs            models.ClusterState
+		open          []models.Incident
+		announcements []models.Announcement
+		windows       []models.MaintenanceWindow
+		want          string
+	}{
+		{name: "all online", cs: nodes(4, 0, 0), want: models.ClusterStateOperational},
+		{name: "one node down", cs: nodes(3, 1, 0), want: models.ClusterStateDegraded},
+		{name: "half down", cs: nodes(2, 2, 0), want: models.ClusterStatePartialOutage},
+		{name: "all down", cs: nodes(0, 4, 0), want: models.ClusterStateMajorOutage},
+		// Nodes in maintenance count neither way
+		{name: "some nodes in maintenance", cs: nodes(3, 0, 1), want: models.ClusterStateOperational},
+		{name: "every node in maintenance", cs: nodes(0, 0, 4), want: models.ClusterStateMaintenance},
+		{name: "warning incident", cs: nodes(4, 0, 0), open: []models.Incident{incident(models.SeverityWarning, "asuka")}, want: models.ClusterStateDegraded},
+		{name: "critical incident", cs: nodes(4, 0, 0), open: []models.Incident{incident(models.SeverityCritical, "asuka")}, want: models.ClusterStatePartialOutage},
+		{name: "incident of another cluster", cs: nodes(4, 0, 0), open: []models.Incident{incident(models.SeverityCritical, "naruko")}, want: models.ClusterStateOperational},
+		{
+			name:          "started announcement for every cluster",
+			cs:            nodes(4, 0, 0),
+			announcements: []models.Announcement{{StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}},
+			want:          models.ClusterStateMaintenance,
+		},
+		{
+			name:          "upcoming announcement",
+			cs:            nodes(4, 0, 0),
+			announcements: []models.Announcement{{StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}},
+			want:          models.ClusterStateOperational,
+		},
+		{
+			name:          "announcement of another cluster",
+			cs:            nodes(4, 0, 0),
+			announcements: []models.Announcement{{Clusters: []string{"naruko"}, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}},
+			want:          models.ClusterStateOperational,
+		},
+		{
+			name:    "maintenance window of the cluster",
+			cs:      nodes(4, 0, 0),
+			windows: []models.MaintenanceWindow{{Cluster: "asuka", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}},
+			want:    models.ClusterStateMaintenance,
+		},
+		{
+			name:    "maintenance window of some nodes",
+			cs:      nodes(4, 0, 0),
+			windows: []models.MaintenanceWindow{{Cluster: "asuka", Nodes: []string{"asuka01"}, Start: now.Add(-time.Hour), End: now.Add(time.Hour)}},
+			want:    models.ClusterStateOperational,
+		},
+		{
+			// An outage during maintenance is still shown
+			name:          "outage during an announcement",
+			cs:            nodes(0, 4, 0),
+			announcements: []models.Announcement{{StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}},
+			want:          models.ClusterStateMajorOutage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clusterState(tt.cs, tt.open, tt.announcements, tt.windows, now); got != tt.want {
+				t.Errorf("clusterState = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	start := time.Date(2024, 6, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*3600))
+	ann := models.Announcement{Title: " Power work ", Message: " Building B ", Clusters: []string{"naruko", " asuka", "naruko", ""}, StartsAt: start, EndsAt: start.Add(time.Hour)}
+	if err := prepare(&ann); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if ann.Title != "Power work" || ann.Message != "Building B" || strings.Join(ann.Clusters, ",") != "asuka,naruko" || ann.StartsAt.Location() != time.UTC {
+		t.Errorf("announcement = %+v", ann)
+	}
+
+	for _, bad := range []models.Announcement{
+		{Title: " ", StartsAt: start, EndsAt: start.Add(time.Hour)},
+		{Title: "No times"},
+		{Title: "Backwards", StartsAt: start, EndsAt: start},
+	} {
+		if err := prepare(&bad); !errors.Is(err, ErrInvalidAnnouncement) {
+			t.Errorf("prepare(%+v): error = %v, want ErrInvalidAnnouncement", bad, err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package statuspage
+
+import (
+	"encoding/xml"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/incident"
+	"github.com/taisei-ito/cluster-status-monitor/internal/maintenance"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+)
+
+// stateLabels are the human readable cluster states
+var stateLabels = map[string]string{
+	models.ClusterStateOperational:   "Operational",
+	models.ClusterStateMaintenance:   "Under maintenance",
+	models.ClusterStateDegraded:      "Degraded",
+	models.ClusterStatePartialOutage: "Partial outage",
+	models.ClusterStateMajorOutage:   "Major outage",
+}
+
+var htmlFuncs = map[string]interface{}{
+	"label": func(state string) string { return stateLabels[state] },
+	"date":  func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+	"title": capitalize,
+	"join":  func(s []string) string { return strings.Join(s, ", ") },
+}
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("status").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="60">
+<title>{{.Title}}</title>
+<link rel="alternate" type="application/atom+xml" title="{{.Title}}" href="/status/feed.atom">
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+.banner { padding: 1em; font-size: 1.2em; margin-bottom: 1.5em; }
+.operational { background: #e3f5e1; }
+.maintenance { background: #e1ecf7; }
+.degraded { background: #fdf6d8; }
+.partial_outage { background: #fde7d2; }
+.major_outage { background: #f9d9d9; }
+.timeline { margin: 0.5em 0 0 1em; padding: 0; list-style: none; }
+.muted { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="banner {{.State}}">{{if eq .State "operational"}}All clusters operational{{else}}{{label .State}}{{end}}</div>
+
+<h2>Clusters</h2>
+<table>
+<tr><th>Cluster</th><th>State</th><th>Online</th><th>Offline</th><th>Maintenance</th></tr>
+{{- range .Clusters}}
+<tr class="{{.State}}"><td>{{.Cluster}}{{if .Description}} <span class="muted">{{.Description}}</span>{{end}}</td><td>{{label .State}}</td><td class="num">{{.NodesOnline}}</td><td class="num">{{.NodesOffline}}</td><td class="num">{{.NodesMaintenance}}</td></tr>
+{{- else}}
+<tr><td colspan="5">No clusters registered</td></tr>
+{{- end}}
+</table>
+
+<h2>Scheduled maintenance</h2>
+{{- range .Maintenance}}
+<div id="maintenance-{{.ID}}">
+<h3>{{.Cluster}}{{if .Nodes}} <span class="muted">{{join .Nodes}}</span>{{end}}</h3>
+<p class="muted">{{date .Start}} - {{date .End}}</p>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+</div>
+{{- end}}
+{{- range .Announcements}}
+<div id="announcement-{{.ID}}">
+<h3>{{.Title}}</h3>
+<p class="muted">{{date .StartsAt}} - {{date .EndsAt}}{{if .Clusters}} · {{join .Clusters}}{{end}}</p>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+</div>
+{{- end}}
+{{- if and (not .Maintenance) (not .Announcements)}}
+<p>No maintenance scheduled.</p>
+{{- end}}
+<p class="muted"><a href="/status/maintenance.ics">Add maintenance to your calendar</a></p>
+
+<h2>Incidents</h2>
+{{- range .Incidents}}
+<div id="incident-{{.ID}}">
+<h3>{{.Title}} <span class="muted">({{title .Status}})</span></h3>
+<p class="muted">{{if .Clusters}}{{join .Clusters}} · {{end}}opened {{date .CreatedAt}}{{if .ResolvedAt}}, resolved {{date .ResolvedAt}}{{end}}</p>
+<ul class="timeline">
+{{- range .Timeline}}
+<li><strong>{{title .Status}}</strong> {{date .At}} - {{.Message}}</li>
+{{- end}}
+</ul>
+{{if .Resolution}}<p>Resolution: {{.Resolution}}</p>{{end}}
+</div>
+{{- else}}
+<p>No recent incidents.</p>
+{{- end}}
+
+<p class="muted">Updated {{date .GeneratedAt}} · <a href="/status.json">JSON</a> · <a href="/status/feed.atom">Atom feed</a></p>
+</body>
+</html>
+`))
+
+// RenderHTML writes the status page as a standalone HTML document
+func RenderHTML(w io.Writer, page *models.StatusPage) error {
+	return htmlTemplate.Execute(w, page)
+}
+
+// atomFeed is an Atom 1.0 feed document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string    `xml:"id"`
+	Title     string    `xml:"title"`
+	Updated   string    `xml:"updated"`
+	Published string    `xml:"published"`
+	Link      atomLink  `xml:"link"`
+	Category  atomTerm  `xml:"category"`
+	Content   atomText  `xml:"content"`
+	updated   time.Time // Sort key, formatted into Updated
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteAtom writes the incidents, announcements and maintenance windows
+// updated within FeedWindow as an Atom feed, newest first. baseURL is the public URL of
+// the server, used for the feed and entry links.
+func (p *Page) WriteAtom(w io.Writer, baseURL string) error {
+	now := time.Now().UTC()
+	since := now.Add(-p.FeedWindow)
+	baseURL = strings.TrimRight(baseURL, "/")
+	pageURL := baseURL + "/status"
+
+	incidents, err := p.incidents.List(incident.Filter{})
+	if err != nil {
+		return err
+	}
+	announcements, err := p.announcements.List()
+	if err != nil {
+		return err
+	}
+	windows, err := p.maintenance.List(maintenance.Filter{})
+	if err != nil {
+		return err
+	}
+
+	var entries []atomEntry
+	for _, inc := range incidents {
+		if inc.UpdatedAt.Before(since) {
+			continue
+		}
+		latest := inc.Timeline[len(inc.Timeline)-1]
+		content := fmt.Sprintf("%s: %s", capitalize(latest.Status), latest.Message)
+		if len(inc.Clusters) > 0 {
+			content += "\nAffected clusters: " + strings.Join(inc.Clusters, ", ")
+		}
+		if inc.Resolution != "" {
+			content += "\nResolution: " + inc.Resolution
+		}
+		entries = append(entries, atomEntry{
+			ID:        fmt.Sprintf("%s#incident-%d", pageURL, inc.ID),
+			Title:     fmt.Sprintf("%s (%s)", inc.Title, inc.Status),
+			Published: inc.CreatedAt.Format(time.RFC3339),
+			Link:      atomLink{Href: fmt.Sprintf("%s#incident-%d", pageURL, inc.ID)},
+			Category:  atomTerm{Term: "incident"},
+			Content:   atomText{Type: "text", Body: content},
+			updated:   inc.UpdatedAt,
+		})
+	}
+	for _, ann := range announcements {
+		if ann.UpdatedAt.Before(since) && ann.EndsAt.Before(now) {
+			continue
+		}
+		content := fmt.Sprintf("%s - %s", ann.StartsAt.Format(time.RFC3339), ann.EndsAt.Format(time.RFC3339))
+		if len(ann.Clusters) > 0 {
+			content += "\nAffected clusters: " + strings.Join(ann.Clusters, ", ")
+		}
+		if ann.Message != "" {
+			content += "\n" + ann.Message
+		}
+		entries = append(entries, atomEntry{
+			ID:        fmt.Sprintf("%s#announcement-%d", pageURL, ann.ID),
+			Title:     ann.Title,
+			Published: ann.CreatedAt.Format(time.RFC3339),
+			Link:      atomLink{Href: fmt.Sprintf("%s#announcement-%d", pageURL, ann.ID)},
+			Category:  atomTerm{Term: "announcement"},
+			Content:   atomText{Type: "text", Body: content},
+			updated:   ann.UpdatedAt,
+		})
+	}
+	for _, win := range windows {
+		if win.UpdatedAt.Before(since) && win.End.Before(now) {
+			continue
+		}
+		title := "Maintenance: " + win.Cluster
+		content := fmt.Sprintf("%s - %s", win.Start.Format(time.RFC3339), win.End.Format(time.RFC3339))
+		if len(win.Nodes) > 0 {
+			title += " (" + strings.Join(win.Nodes, ", ") + ")"
+			content += "\nNodes: " + strings.Join(win.Nodes, ", ")
+		}
+		if win.Description != "" {
+			content += "\n" + win.Description
+		}
+		entries = append(entries, atomEntry{
+			ID:        fmt.Sprintf("%s#maintenance-%d", pageURL, win.ID),
+			Title:     title,
+			Published: win.CreatedAt.Format(time.RFC3339),
+			Link:      atomLink{Href: fmt.Sprintf("%s#maintenance-%d", pageURL, win.ID)},
+			Category:  atomTerm{Term: "maintenance"},
+			Content:   atomText{Type: "text", Body: content},
+			updated:   win.UpdatedAt,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].updated.After(entries[j].updated) })
+	updated := now
+	if len(entries) > 0 {
+		updated = entries[0].updated
+	}
+	for i := range entries {
+		entries[i].Updated = entries[i].updated.Format(time.RFC3339)
+	}
+
+	feed := atomFeed{
+		ID:      pageURL,
+		Title:   p.Title,
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomPerson{Name: p.Title},
+		Links: []atomLink{
+			{Href: baseURL + "/status/feed.atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: pageURL, Rel: "alternate", Type: "text/html"},
+		},
+		Entries: entries,
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(feed)
+}
+
+// capitalize upper-cases the first letter of a status
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -4,17 +4,103 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// MySQL storage options, set in Config.Options
+const (
+	OptionMaxOpenConns    = "max_open_conns"     // Open connections; 0 is unlimited
+	OptionMaxIdleConns    = "max_idle_conns"     // Idle connections kept in the pool
+	OptionConnMaxLifetime = "conn_max_lifetime"  // Connections are closed after this duration; 0 keeps them
+	OptionConnMaxIdleTime = "conn_max_idle_time" // Idle connections are closed after this duration; 0 keeps them
+	OptionTimeout         = "timeout"            // Connection timeout
+	OptionReadTimeout     = "read_timeout"       // Timeout of reading a response
+	OptionWriteTimeout    = "write_timeout"      // Timeout of sending a query
+)
+
+// mysqlOptions are the parsed MySQL storage options
+type mysqlOptions struct {
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+	timeout         time.Duration
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+}
+
+// parseMySQLOptions parses the options of a MySQL storage. Options that are
+// not set keep the defaults of database/sql and the driver.
+func parseMySQLOptions(options map[string]string) (mysqlOptions, map[string]error) {
+	o := mysqlOptions{maxIdleConns: 2}
+	errs := map[string]error{}
+	for name, value := range options {
+		var err error
+		switch name {
+		case OptionMaxOpenConns:
+			o.maxOpenConns, err = parseCount(value)
+		case OptionMaxIdleConns:
+			o.maxIdleConns, err = parseCount(value)
+		case OptionConnMaxLifetime:
+			o.connMaxLifetime, err = parseTimeout(value)
+		case OptionConnMaxIdleTime:
+			o.connMaxIdleTime, err = parseTimeout(value)
+		case OptionTimeout:
+			o.timeout, err = parseTimeout(value)
+		case OptionReadTimeout:
+			o.readTimeout, err = parseTimeout(value)
+		case OptionWriteTimeout:
+			o.writeTimeout, err = parseTimeout(value)
+		default:
+			err = fmt.Errorf("unknown MySQL option")
+		}
+		if err != nil {
+			errs[name] = err
+		}
+	}
+	return o, errs
+}
+
+// parseCount parses a connection count option
+func parseCount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("must be a non-negative integer, got %q", value)
+	}
+	return n, nil
+}
+
+// parseTimeout parses a duration option
+func parseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("must be a non-negative duration such as 30s, got %q", value)
+	}
+	return d, nil
+}
+
 // MySQLStorage implements MySQL-based storage
 type MySQLStorage struct {
 	db *sql.DB
 }
 
-// NewMySQLStorage creates a new MySQL storage instance
-func NewMySQLStorage(config MySQLConfig) (*MySQLStorage, error) {
+// NewMySQLStorage creates a new MySQL storage instance with the given pool
+// and timeout options
+func NewMySQLStorage(config MySQLConfig, options map[string]string) (*MySQLStorage, error) {
+	opts, errs := parseMySQLOptions(options)
+	if len(errs) > 0 {
+		names := make([]string, 0, len(errs))
+		for name := range errs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("invalid MySQL option %s: %w", names[0], errs[names[0]])
+	}
+
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
 		config.User,
 		config.Password,
@@ -22,11 +108,24 @@ func NewMySQLStorage(config MySQLConfig) (*MySQLStorage, error) {
 		config.Port,
 		config.Database,
 	)
+	if opts.timeout > 0 {
+		dsn += "&timeout=" + opts.timeout.String()
+	}
+	if opts.readTimeout > 0 {
+		dsn += "&readTimeout=" + opts.readTimeout.String()
+	}
+	if opts.writeTimeout > 0 {
+		dsn += "&writeTimeout=" + opts.writeTimeout.String()
+	}
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(opts.maxOpenConns)
+	db.SetMaxIdleConns(opts.maxIdleConns)
+	db.SetConnMaxLifetime(opts.connMaxLifetime)
+	db.SetConnMaxIdleTime(opts.connMaxIdleTime)
 
 	// Test connection
 	if err := db.Ping(); err != nil {
@@ -151,6 +250,11 @@ func (s *MySQLStorage) List() ([]string, error) {
 	return keys, nil
 }
 
+// DB returns the underlying database for data kept in dedicated tables
+func (s *MySQLStorage) DB() *sql.DB {
+	return s.db
+}
+
 // Close closes the database connection
 func (s *MySQLStorage) Close() error {
 	return s.db.Close()
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -20,21 +23,27 @@ type Storage interface {
 	Close() error
 }
 
+// SQLStorage is implemented by storages backed by a SQL database. Data that
+// has its own table in init.sql is written there instead of as documents.
+type SQLStorage interface {
+	DB() *sql.DB
+}
+
 // Config holds storage configuration
 type Config struct {
-	Type     string            // "json" or "mysql"
-	JSONPath string            // Path for JSON storage
-	MySQL    *MySQLConfig      // MySQL configuration
-	Options  map[string]string // Additional options
+	Type     string            `yaml:"type"`      // "json" or "mysql"
+	JSONPath string            `yaml:"json_path"` // Path for JSON storage
+	MySQL    *MySQLConfig      `yaml:"mysql"`     // MySQL configuration
+	Options  map[string]string `yaml:"options"`   // Storage specific options, such as the MySQL Option* settings
 }
 
 // MySQLConfig holds MySQL-specific configuration
 type MySQLConfig struct {
-	Host     string
-	Port     string
-	Database string
-	User     string
-	Password string
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Database string `yaml:"database"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
 }
 
 // Factory creates a storage instance based on configuration
@@ -44,7 +53,7 @@ func Factory(config Config) (Storage, error) {
 		if config.MySQL == nil {
 			return nil, errors.New("MySQL configuration required")
 		}
-		return NewMySQLStorage(*config.MySQL)
+		return NewMySQLStorage(*config.MySQL, config.Options)
 	case "json":
 		fallthrough
 	default:
@@ -56,6 +65,20 @@ func Factory(config Config) (Storage, error) {
 	}
 }
 
+// CheckOptions returns the options that a storage type does not understand
+// or that have invalid values, by option name
+func CheckOptions(storageType string, options map[string]string) map[string]error {
+	if storageType == "mysql" {
+		_, errs := parseMySQLOptions(options)
+		return errs
+	}
+	errs := map[string]error{}
+	for name := range options {
+		errs[name] = fmt.Errorf("not supported by %s storage", storageType)
+	}
+	return errs
+}
+
 // Helper function to convert map to struct
 func UnmarshalData(data map[string]interface{}, v interface{}) error {
 	jsonData, err := json.Marshal(data)
@@ -64,3 +87,32 @@ func UnmarshalData(data map[string]interface{}, v interface{}) error {
 	}
 	return json.Unmarshal(jsonData, v)
 }
+
+// GetData decodes the "data" field of a stored document into v.
+// A missing key is reported as ErrNotFound and leaves v untouched.
+func GetData(s Storage, key string, v interface{}) error {
+	data, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+
+	raw, ok := data["data"]
+	if !ok || raw == nil {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// SetData stores v as the "data" field of a document together with its
+// update time, matching the layout written by the collection scripts
+func SetData(s Storage, key string, v interface{}) error {
+	return s.Set(key, map[string]interface{}{
+		"data":       v,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,179 @@
+package usage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// backend persists user usage rows
+type backend interface {
+	insert(rows []models.UserUsage) error
+	query(cluster string, since, until time.Time) ([]models.UserUsage, error)
+	prune(before time.Time) error
+}
+
+// sqlBackend writes rows to the user_usage table of init.sql
+type sqlBackend struct {
+	db *sql.DB
+}
+
+// newSQLBackend creates the user_usage table if init.sql was not run
+func newSQLBackend(db *sql.DB) (*sqlBackend, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_usage (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			cluster_name VARCHAR(100) NOT NULL,
+			username VARCHAR(100) NOT NULL,
+			cpu_cores INT DEFAULT 0,
+			memory_gb DECIMAL(10, 2) DEFAULT 0.00,
+			jobs INT DEFAULT 0,
+			timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_cluster_user (cluster_name, username),
+			INDEX idx_timestamp (timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_usage table: %w", err)
+	}
+	return &sqlBackend{db: db}, nil
+}
+
+func (b *sqlBackend) insert(rows []models.UserUsage) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(rows))
+	args := make([]interface{}, 0, len(rows)*6)
+	for _, r := range rows {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?)")
+		args = append(args, r.Cluster, r.Username, r.CPUCores, r.MemoryGB, r.Jobs, r.Timestamp)
+	}
+
+	query := "INSERT INTO user_usage (cluster_name, username, cpu_cores, memory_gb, jobs, timestamp) VALUES " +
+		strings.Join(placeholders, ", ")
+	if _, err := b.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to insert user usage: %w", err)
+	}
+	return nil
+}
+
+func (b *sqlBackend) query(cluster string, since, until time.Time) ([]models.UserUsage, error) {
+	query := `SELECT cluster_name, username, cpu_cores, memory_gb, jobs, timestamp
+		FROM user_usage WHERE timestamp >= ? AND timestamp < ?`
+	args := []interface{}{since, until}
+	if cluster != "" {
+		query += " AND cluster_name = ?"
+		args = append(args, cluster)
+	}
+	query += " ORDER BY timestamp"
+
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user usage: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.UserUsage{}
+	for rows.Next() {
+		var r models.UserUsage
+		if err := rows.Scan(&r.Cluster, &r.Username, &r.CPUCores, &r.MemoryGB, &r.Jobs, &r.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan user usage: %w", err)
+		}
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user usage: %w", err)
+	}
+	return result, nil
+}
+
+func (b *sqlBackend) prune(before time.Time) error {
+	if _, err := b.db.Exec("DELETE FROM user_usage WHERE timestamp < ?", before); err != nil {
+		return fmt.Errorf("failed to prune user usage: %w", err)
+	}
+	return nil
+}
+
+// documentBackend keeps one document of rows per day, for the JSON storage
+type documentBackend struct {
+	storage storage.Storage
+}
+
+func (b *documentBackend) insert(rows []models.UserUsage) error {
+	byDay := make(map[string][]models.UserUsage)
+	for _, r := range rows {
+		day := r.Timestamp.UTC().Format("2006-01-02")
+		byDay[day] = append(byDay[day], r)
+	}
+
+	for day, added := range byDay {
+		stored, err := b.load(day)
+		if err != nil {
+			return err
+		}
+		if err := storage.SetData(b.storage, dayKey(day), append(stored, added...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *documentBackend) query(cluster string, since, until time.Time) ([]models.UserUsage, error) {
+	result := []models.UserUsage{}
+	last := until.UTC().Format("2006-01-02")
+	for day := since.UTC().Truncate(24 * time.Hour); day.Format("2006-01-02") <= last; day = day.Add(24 * time.Hour) {
+		rows, err := b.load(day.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			if r.Timestamp.Before(since) || !r.Timestamp.Before(until) || (cluster != "" && r.Cluster != cluster) {
+				continue
+			}
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (b *documentBackend) prune(before time.Time) error {
+	keys, err := b.storage.List()
+	if err != nil {
+		return err
+	}
+
+	cutoff := dayKey(before.UTC().Format("2006-01-02"))
+	for _, key := range keys {
+		if strings.HasPrefix(key, dayKeyPrefix) && key < cutoff {
+			if err := b.storage.Delete(key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// load reads the rows of one day
+func (b *documentBackend) load(day string) ([]models.UserUsage, error) {
+	rows := []models.UserUsage{}
+	err := storage.GetData(b.storage, dayKey(day), &rows)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load user usage for %s: %w", day, err)
+	}
+	return rows, nil
+}
+
+// dayKeyPrefix prefixes the storage keys of the daily documents
+const dayKeyPrefix = "user_usage_"
+
+// dayKey returns the storage key for a day's rows
+func dayKey(day string) string {
+	return dayKeyPrefix + day
+}
@@ -0,0 +1,247 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// Store records per-user PBS usage sampled from the running jobs and ranks
+// users over a window. With MySQL storage the rows go to the user_usage
+// table of init.sql; otherwise they are kept as one document per day.
+type Store struct {
+	backend   backend
+	inventory *inventory.Inventory
+	Interval  time.Duration // Time covered by one sample, i.e. the job collection interval
+	Retention time.Duration // Rows older than this are deleted
+	FairShare float64       // Target share per user in percent; 0 splits evenly among active users
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+// NewStore creates a user usage store
+func NewStore(store storage.Storage, inv *inventory.Inventory) (*Store, error) {
+	var b backend = &documentBackend{storage: store}
+	if s, ok := store.(storage.SQLStorage); ok {
+		sqlBackend, err := newSQLBackend(s.DB())
+		if err != nil {
+			return nil, err
+		}
+		b = sqlBackend
+	}
+
+	return &Store{
+		backend:   b,
+		inventory: inv,
+		Interval:  5 * time.Minute,
+		Retention: 90 * 24 * time.Hour,
+	}, nil
+}
+
+// RecordJobs stores the usage of each user from the jobs running at a time.
+// It is set as the OnCollect hook of the job tracker.
+func (s *Store) RecordJobs(at time.Time, running []models.Job) error {
+	type key struct{ cluster, user string }
+	totals := make(map[key]*models.UserUsage)
+	for _, job := range running {
+		k := key{job.Cluster, job.User}
+		u, ok := totals[k]
+		if !ok {
+			u = &models.UserUsage{Cluster: job.Cluster, Username: job.User, Timestamp: at}
+			totals[k] = u
+		}
+		u.CPUCores += job.NCPUs
+		u.MemoryGB += float64(job.MemoryBytes) / (1 << 30)
+		u.Jobs++
+	}
+
+	rows := make([]models.UserUsage, 0, len(totals))
+	for _, u := range totals {
+		rows = append(rows, *u)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Cluster != rows[j].Cluster {
+			return rows[i].Cluster < rows[j].Cluster
+		}
+		return rows[i].Username < rows[j].Username
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.backend.insert(rows); err != nil {
+		return err
+	}
+	if s.Retention > 0 && at.Sub(s.lastPruned) > 24*time.Hour {
+		if err := s.backend.prune(at.Add(-s.Retention)); err != nil {
+			return err
+		}
+		s.lastPruned = at
+	}
+	return nil
+}
+
+// Latest returns the per-user rows of the most recent sample of a cluster,
+// heaviest users first. It is empty when no jobs ran in the last two
+// sample intervals.
+func (s *Store) Latest(cluster string) ([]models.UserUsage, error) {
+	now := time.Now().UTC()
+	rows, err := s.query(cluster, now.Add(-2*s.Interval), now.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	var last time.Time
+	for _, r := range rows {
+		if r.Timestamp.After(last) {
+			last = r.Timestamp
+		}
+	}
+	latest := []models.UserUsage{}
+	for _, r := range rows {
+		if r.Timestamp.Equal(last) {
+			latest = append(latest, r)
+		}
+	}
+	sort.SliceStable(latest, func(i, j int) bool { return latest[i].CPUCores > latest[j].CPUCores })
+	return latest, nil
+}
+
+// Leaderboard ranks the users of a cluster, or of every cluster when
+// cluster is empty, by the core hours used in the window ending now
+func (s *Store) Leaderboard(cluster string, window time.Duration) ([]models.UserUsageSummary, error) {
+	now := time.Now().UTC()
+	rows, err := s.query(cluster, now.Add(-window), now.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	// Averages are taken over the samples recorded for each cluster, so
+	// gaps in collection do not dilute them. Samples without running jobs
+	// store no rows and are not counted either.
+	samples := make(map[string]map[time.Time]bool)
+	for _, r := range rows {
+		if samples[r.Cluster] == nil {
+			samples[r.Cluster] = make(map[time.Time]bool)
+		}
+		samples[r.Cluster][r.Timestamp] = true
+	}
+
+	// totals sums the rows of one user on one cluster
+	type totals struct {
+		cores, memory, jobs float64
+	}
+
+	hours := s.Interval.Hours()
+	byUser := make(map[string]*models.UserUsageSummary)
+	perCluster := make(map[string]map[string]*totals)
+	var total float64
+	for _, r := range rows {
+		u, ok := byUser[r.Username]
+		if !ok {
+			u = &models.UserUsageSummary{Username: r.Username}
+			byUser[r.Username] = u
+			perCluster[r.Username] = make(map[string]*totals)
+		}
+		t, ok := perCluster[r.Username][r.Cluster]
+		if !ok {
+			t = &totals{}
+			perCluster[r.Username][r.Cluster] = t
+		}
+		t.cores += float64(r.CPUCores)
+		t.memory += r.MemoryGB
+		t.jobs += float64(r.Jobs)
+		if r.CPUCores > u.PeakCPUCores {
+			u.PeakCPUCores = r.CPUCores
+		}
+		u.CoreHours += float64(r.CPUCores) * hours
+		total += float64(r.CPUCores) * hours
+	}
+
+	capacity, err := s.capacity(cluster)
+	if err != nil {
+		return nil, err
+	}
+	fairShare := s.FairShare
+	if fairShare <= 0 && len(byUser) > 0 {
+		fairShare = 100 / float64(len(byUser))
+	}
+
+	result := make([]models.UserUsageSummary, 0, len(byUser))
+	for name, u := range byUser {
+		u.Clusters = make([]string, 0, len(perCluster[name]))
+		for c, t := range perCluster[name] {
+			n := float64(len(samples[c]))
+			u.AvgCPUCores += t.cores / n
+			u.AvgMemoryGB += t.memory / n
+			u.AvgJobs += t.jobs / n
+			u.Clusters = append(u.Clusters, c)
+		}
+		sort.Strings(u.Clusters)
+
+		if capacity > 0 {
+			percent := u.AvgCPUCores / float64(capacity) * 100
+			u.PercentOfCluster = &percent
+		}
+		if total > 0 {
+			u.Share = u.CoreHours / total * 100
+		}
+		u.FairShare = fairShare
+		if fairShare > 0 {
+			u.FairShareRatio = u.Share / fairShare
+		}
+		result = append(result, *u)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CoreHours != result[j].CoreHours {
+			return result[i].CoreHours > result[j].CoreHours
+		}
+		return result[i].Username < result[j].Username
+	})
+	for i := range result {
+		result[i].Rank = i + 1
+	}
+	return result, nil
+}
+
+// query reads the rows of [since, until)
+func (s *Store) query(cluster string, since, until time.Time) ([]models.UserUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.backend.query(cluster, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user usage: %w", err)
+	}
+	return rows, nil
+}
+
+// capacity returns the cores of the nodes in service on a cluster, or on
+// every cluster when cluster is empty
+func (s *Store) capacity(cluster string) (int, error) {
+	var nodes []models.Node
+	var err error
+	if cluster != "" {
+		nodes, err = s.inventory.ByCluster(cluster)
+	} else {
+		nodes, err = s.inventory.List()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cores := 0
+	for _, n := range nodes {
+		if n.State == models.NodeStateOnline {
+			cores += n.NCPUs
+		}
+	}
+	return cores, nil
+}
@@ -0,0 +1,126 @@
+package usage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	nodes := []models.Node{
+		{Name: "asuka01", Cluster: "asuka", State: models.NodeStateOnline, NCPUs: 16},
+		{Name: "asuka02", Cluster: "asuka", State: models.NodeStateOffline, NCPUs: 16},
+		{Name: "naruko01", Cluster: "naruko", State: models.NodeStateOnline, NCPUs: 8},
+	}
+	if err := storage.SetData(store, "node_inventory", nodes); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	s, err := NewStore(store, inventory.New(store, nil))
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	s.Interval = time.Hour
+	return s
+}
+
+// job is a running job of user with ncpus cores
+func job(user, cluster string, ncpus int) models.Job {
+	return models.Job{User: user, Cluster: cluster, NCPUs: ncpus, MemoryBytes: 2 << 30}
+}
+
+func TestLeaderboard(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().UTC()
+
+	samples := []struct {
+		at   time.Time
+		jobs []models.Job
+	}{
+		{now.Add(-3 * time.Hour), []models.Job{job("alice", "asuka", 4), job("alice", "asuka", 4), job("bob", "asuka", 2)}},
+		{now.Add(-2 * time.Hour), []models.Job{job("alice", "asuka", 4), job("bob", "naruko", 2)}},
+		{now.Add(-time.Hour), []models.Job{job("alice", "asuka", 6), job("carol", "asuka", 2)}},
+		// Outside the window
+		{now.Add(-48 * time.Hour), []models.Job{job("dave", "asuka", 16)}},
+	}
+	for _, sample := range samples {
+		if err := s.RecordJobs(sample.at, sample.jobs); err != nil {
+			t.Fatalf("RecordJobs: %v", err)
+		}
+	}
+
+	board, err := s.Leaderboard("", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Leaderboard: %v", err)
+	}
+	if len(board) != 3 {
+		t.Fatalf("leaderboard = %+v, want three users", board)
+	}
+
+	// 24 core hours of 30 in total; an even split gives each user a third
+	alice := board[0]
+	if alice.Rank != 1 || alice.Username != "alice" || alice.CoreHours != 18 || alice.PeakCPUCores != 8 || alice.AvgJobs != 4.0/3 {
+		t.Errorf("alice = %+v", alice)
+	}
+	if math.Abs(alice.Share-75) > 1e-9 || math.Abs(alice.FairShare-100.0/3) > 1e-9 || math.Abs(alice.FairShareRatio-2.25) > 1e-9 {
+		t.Errorf("alice share %v, fair share %v, ratio %v", alice.Share, alice.FairShare, alice.FairShareRatio)
+	}
+	// Averages are taken over the samples of each cluster: 6 cores over 3
+	// asuka samples, 16 online cores of asuka and naruko
+	if alice.AvgCPUCores != 6 || alice.PercentOfCluster == nil || *alice.PercentOfCluster != 25 {
+		t.Errorf("alice averages %v cores, %v percent", alice.AvgCPUCores, alice.PercentOfCluster)
+	}
+
+	bob := board[1]
+	if bob.Username != "bob" || len(bob.Clusters) != 2 || bob.Clusters[0] != "asuka" || bob.Clusters[1] != "naruko" || bob.CoreHours != 4 {
+		t.Errorf("bob = %+v", bob)
+	}
+	// One naruko sample of 2 cores and 2 cores over 3 asuka samples
+	if math.Abs(bob.AvgCPUCores-(2+2.0/3)) > 1e-9 {
+		t.Errorf("bob averages %v cores", bob.AvgCPUCores)
+	}
+	if board[2].Username != "carol" || board[2].Rank != 3 {
+		t.Errorf("third = %+v", board[2])
+	}
+
+	// A configured fair share replaces the even split
+	s.FairShare = 50
+	board, err = s.Leaderboard("asuka", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Leaderboard: %v", err)
+	}
+	if len(board) != 3 || board[0].FairShare != 50 || math.Abs(board[0].FairShareRatio-board[0].Share/50) > 1e-9 {
+		t.Errorf("asuka leaderboard = %+v", board)
+	}
+	if board[0].PercentOfCluster == nil || *board[0].PercentOfCluster != 100*6.0/16 {
+		t.Errorf("alice percent of asuka = %v", board[0].PercentOfCluster)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().UTC()
+
+	if err := s.RecordJobs(now.Add(-90*time.Minute), []models.Job{job("alice", "asuka", 4)}); err != nil {
+		t.Fatalf("RecordJobs: %v", err)
+	}
+	if err := s.RecordJobs(now.Add(-30*time.Minute), []models.Job{job("bob", "asuka", 2), job("carol", "asuka", 8), job("dave", "naruko", 1)}); err != nil {
+		t.Fatalf("RecordJobs: %v", err)
+	}
+
+	latest, err := s.Latest("asuka")
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if len(latest) != 2 || latest[0].Username != "carol" || latest[1].Username != "bob" || latest[0].MemoryGB != 2 {
+		t.Errorf("latest = %+v", latest)
+	}
+}
@@ -0,0 +1,266 @@
+package utilization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taisei-ito/cluster-status-monitor/internal/collector"
+	"github.com/taisei-ito/cluster-status-monitor/internal/inventory"
+	"github.com/taisei-ito/cluster-status-monitor/internal/models"
+	"github.com/taisei-ito/cluster-status-monitor/internal/registry"
+	"github.com/taisei-ito/cluster-status-monitor/internal/schedule"
+	"github.com/taisei-ito/cluster-status-monitor/internal/storage"
+)
+
+// ErrInvalidPeriod is returned for periods that are not formatted as YYYY-MM
+var ErrInvalidPeriod = errors.New("invalid period, expected YYYY-MM")
+
+// dayTotals accumulates the samples of one cluster on one day
+type dayTotals struct {
+	Samples     int     `json:"samples"`
+	Load        float64 `json:"load"`
+	PBS         float64 `json:"pbs"`
+	CPUSamples  int     `json:"cpu_samples"`
+	CPU         float64 `json:"cpu"`
+	NodeSamples int     `json:"node_samples"`
+	Online      float64 `json:"online"` // Sum of the percent of nodes online
+}
+
+// Recorder keeps daily means of the cluster metrics written by the
+// collection scripts and of node availability from the node inventory, so
+// that utilization can be reported per month. The scripts only keep the
+// latest values, which is what the _week PHP pages used to be compiled from.
+type Recorder struct {
+	storage   storage.Storage
+	registry  *registry.Registry
+	inventory *inventory.Inventory
+	mu        sync.Mutex
+}
+
+// NewRecorder creates a utilization recorder
+func NewRecorder(store storage.Storage, reg *registry.Registry, inv *inventory.Inventory) *Recorder {
+	return &Recorder{storage: store, registry: reg, inventory: inv}
+}
+
+// Sample adds the current metrics and node availability of every cluster to
+// today's totals
+func (r *Recorder) Sample(ctx context.Context) error {
+	clusters, err := r.registry.List()
+	if err != nil {
+		return err
+	}
+
+	load := r.metric("load_average")
+	pbs := r.metric("pbs_usage")
+	cpu := r.metric("cpu_usage")
+
+	now := time.Now().UTC()
+	date := now.Format("2006-01-02")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	days, err := r.loadMonth(date[:7])
+	if err != nil {
+		return err
+	}
+	totals, ok := days[date]
+	if !ok {
+		totals = make(map[string]*dayTotals)
+		days[date] = totals
+	}
+
+	for _, c := range clusters {
+		if c.Type != models.ClusterTypeCompute {
+			continue
+		}
+		t, ok := totals[c.Name]
+		if !ok {
+			t = &dayTotals{}
+			totals[c.Name] = t
+		}
+
+		if l, ok := load[c.Name]; ok {
+			t.Samples++
+			t.Load += l
+			t.PBS += pbs[c.Name]
+		}
+		if v, ok := cpu[c.Name]; ok {
+			t.CPUSamples++
+			t.CPU += v
+		}
+
+		nodes, err := r.inventory.ByCluster(c.Name)
+		if err != nil {
+			return err
+		}
+		var online, counted int
+		for _, n := range nodes {
+			if n.State == models.NodeStateMaintenance {
+				continue
+			}
+			counted++
+			if n.State == models.NodeStateOnline {
+				online++
+			}
+		}
+		if counted > 0 {
+			t.NodeSamples++
+			t.Online += float64(online) / float64(counted) * 100
+		}
+	}
+
+	return storage.SetData(r.storage, monthKey(date[:7]), days)
+}
+
+// Run samples at the given interval until ctx is done
+func (r *Recorder) Run(ctx context.Context, interval *schedule.Interval) {
+	collector.RunEvery(ctx, interval, "Utilization", r.Sample)
+}
+
+// Month returns the utilization of every cluster in a month, averaged over
+// the days with samples
+func (r *Recorder) Month(period string) ([]models.ClusterUtilization, error) {
+	start, err := time.Parse("2006-01", period)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	return r.Between(start, start.AddDate(0, 1, 0))
+}
+
+// Between returns the utilization of every cluster over the days touched by
+// [start, end), averaged over the days with samples
+func (r *Recorder) Between(start, end time.Time) ([]models.ClusterUtilization, error) {
+	start, end = start.UTC(), end.UTC()
+	first, last := start.Format("2006-01-02"), end.Add(-time.Nanosecond).Format("2006-01-02")
+
+	type sums struct {
+		days, cpuDays, nodeDays int
+		load, pbs, cpu, online  float64
+	}
+	byCluster := make(map[string]*sums)
+
+	for month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(end); month = month.AddDate(0, 1, 0) {
+		r.mu.Lock()
+		days, err := r.loadMonth(month.Format("2006-01"))
+		r.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		for date, totals := range days {
+			if date < first || date > last {
+				continue
+			}
+			for cluster, t := range totals {
+				s, ok := byCluster[cluster]
+				if !ok {
+					s = &sums{}
+					byCluster[cluster] = s
+				}
+				if t.Samples > 0 {
+					s.days++
+					s.load += t.Load / float64(t.Samples)
+					s.pbs += t.PBS / float64(t.Samples)
+				}
+				if t.CPUSamples > 0 {
+					s.cpuDays++
+					s.cpu += t.CPU / float64(t.CPUSamples)
+				}
+				if t.NodeSamples > 0 {
+					s.nodeDays++
+					s.online += t.Online / float64(t.NodeSamples)
+				}
+			}
+		}
+	}
+
+	result := make([]models.ClusterUtilization, 0, len(byCluster))
+	for cluster, s := range byCluster {
+		u := models.ClusterUtilization{Cluster: cluster, Days: max(s.days, s.nodeDays)}
+		if s.days > 0 {
+			u.LoadAverage = s.load / float64(s.days)
+			u.PBSUsage = s.pbs / float64(s.days)
+		}
+		if s.cpuDays > 0 {
+			v := s.cpu / float64(s.cpuDays)
+			u.CPUUsage = &v
+		}
+		if s.nodeDays > 0 {
+			v := s.online / float64(s.nodeDays)
+			u.Availability = &v
+		}
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Cluster < result[j].Cluster })
+	return result, nil
+}
+
+// metric returns the latest value of a script metric per cluster. Values
+// formatted as "used/total", as in cpu_usage, are converted to a percentage.
+func (r *Recorder) metric(key string) map[string]float64 {
+	values := make(map[string]float64)
+	data, err := r.storage.Get(key)
+	if err != nil {
+		return values
+	}
+	items, _ := data["data"].([]interface{})
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if dummy, _ := m["is_dummy"].(bool); dummy {
+			continue
+		}
+		cluster, _ := m["cluster"].(string)
+		if cluster == "" {
+			continue
+		}
+		if v, ok := parseValue(m["value"]); ok {
+			values[cluster] = v
+		}
+	}
+	return values
+}
+
+// parseValue converts a metric value to a float
+func parseValue(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case string:
+		if used, total, ok := strings.Cut(value, "/"); ok {
+			u, err1 := strconv.ParseFloat(strings.TrimSpace(used), 64)
+			t, err2 := strconv.ParseFloat(strings.TrimSpace(total), 64)
+			if err1 != nil || err2 != nil || t <= 0 {
+				return 0, false
+			}
+			return u / t * 100, true
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// loadMonth reads the daily totals of a month, keyed by date and cluster
+func (r *Recorder) loadMonth(period string) (map[string]map[string]*dayTotals, error) {
+	days := make(map[string]map[string]*dayTotals)
+	err := storage.GetData(r.storage, monthKey(period), &days)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load utilization for %s: %w", period, err)
+	}
+	return days, nil
+}
+
+// monthKey returns the storage key for a month's daily totals
+func monthKey(period string) string {
+	return "utilization_daily_" + period
+}
@@ -9,6 +9,8 @@ interface AuthContextType {
 
 interface User {
   username: string;
+  name?: string;
+  email?: string;
 }
 
 const AuthContext = createContext<AuthContextType | undefined>(undefined);
@@ -17,38 +19,51 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   const [isAuthenticated, setIsAuthenticated] = useState(false);
   const [user, setUser] = useState<User | null>(null);
 
-  // Check if user is already logged in (from localStorage)
+  // Restore the session from the cookie set by the backend
   useEffect(() => {
-    const storedUser = localStorage.getItem('user');
-    if (storedUser) {
+    const restoreSession = async () => {
       try {
-        const userData = JSON.parse(storedUser);
-        setUser(userData);
-        setIsAuthenticated(true);
+        const response = await fetch('/api/v1/auth/me');
+        if (response.ok) {
+          const userData: User = await response.json();
+          setUser(userData);
+          setIsAuthenticated(true);
+        }
       } catch (error) {
-        console.error('Failed to parse stored user data:', error);
-        localStorage.removeItem('user');
+        console.error('Failed to restore session:', error);
       }
-    }
+    };
+
+    restoreSession();
   }, []);
 
   const login = async (username: string, password: string): Promise<boolean> => {
-    // For now, simple client-side authentication
-    // TODO: Replace with actual API call to Go backend
-    if (username && password) {
-      const userData: User = { username };
-      setUser(userData);
+    try {
+      const response = await fetch('/api/v1/auth/login', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ username, password }),
+      });
+      if (!response.ok) {
+        return false;
+      }
+
+      const data: { user: User } = await response.json();
+      setUser(data.user);
       setIsAuthenticated(true);
-      localStorage.setItem('user', JSON.stringify(userData));
       return true;
+    } catch (error) {
+      console.error('Login request failed:', error);
+      return false;
     }
-    return false;
   };
 
   const logout = () => {
     setUser(null);
     setIsAuthenticated(false);
-    localStorage.removeItem('user');
+    fetch('/api/v1/auth/logout', { method: 'POST' }).catch((error) => {
+      console.error('Logout request failed:', error);
+    });
   };
 
   return (
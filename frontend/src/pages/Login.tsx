@@ -172,7 +172,7 @@ export function Login() {
 
     try {
       // Redirect to SSO provider
-      window.location.href = '/api/auth/sso';
+      window.location.href = '/api/v1/auth/oidc/login';
     } catch (err) {
       setError('SSO authentication failed. Please try again.');
       setLoading(false);